@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// AdminGetSessionAsGif describes the adminGetSessionAsGif command
+type AdminGetSessionAsGif struct{}
+
+func init() {
+	commands.RegisterCommand("admin session gif", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(AdminGetSessionAsGif), models.Auditor, helpers.Helper{
+				Header:      "get a recording of any account's SSH session as a gif",
+				Usage:       "admin session gif --session-id SESSION-ID [--repeat --speed SPEED --show-input]",
+				Description: "get a recording of any account's SSH session as a gif (restricted to sb owners and members of the auditors group)",
+				Aliases:     []string{"adminGetSessionAsGif"},
+			}, map[string]commands.Argument{
+				"session-id": {
+					Required:    true,
+					Description: "The session recording ID to convert as a GIF",
+				},
+				"repeat": {
+					Required:    false,
+					Description: "Specify if animation is repeated",
+					Type:        commands.BOOL,
+				},
+				"speed": {
+					Required:     false,
+					Description:  "Specify the play speed factor of the session (default is \"1.0\")",
+					DefaultValue: "1.0",
+				},
+				"show-input": {
+					Required:    false,
+					Description: "Show the keystrokes of the session, if they were recorded",
+					Type:        commands.BOOL,
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *AdminGetSessionAsGif) Checks(ct *commands.Context) error {
+
+	_, err := strconv.ParseFloat(ct.FormattedArguments["speed"], 64)
+	if err != nil {
+		return fmt.Errorf("argument speed is not a valid float")
+	}
+
+	return nil
+}
+
+// Execute executes the command
+func (c *AdminGetSessionAsGif) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	// The audited account's ttyrecs directory is not writable, so the GIF is generated in a temporary location
+	outputFile := filepath.Join(os.TempDir(), fmt.Sprintf("%s.ttyrec.gif", ct.FormattedArguments["session-id"]))
+
+	_, showInput := ct.FormattedArguments["show-input"]
+	localFilepath, cleanup, err := getAuditedSessionRecord(ct.FormattedArguments["session-id"], showInput)
+	if err != nil {
+		return
+	}
+
+	_, repeat := ct.FormattedArguments["repeat"]
+	speed, err := strconv.ParseFloat(ct.FormattedArguments["speed"], 64)
+	if err != nil {
+		cleanup()
+		return
+	}
+
+	err = writeSessionRecordAsGif(localFilepath, outputFile, speed, repeat)
+	if err != nil {
+		cleanup()
+		return
+	}
+
+	err = cleanup()
+
+	return
+}
+
+func (c *AdminGetSessionAsGif) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *AdminGetSessionAsGif) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
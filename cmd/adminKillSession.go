@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/sessionctl"
+	"github.com/pkg/errors"
+)
+
+// AdminKillSession describes the adminKillSession command
+type AdminKillSession struct{}
+
+func init() {
+	commands.RegisterCommand("admin session kill", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(AdminKillSession), models.SBOwner, helpers.Helper{
+				Header:      "terminate a running SSH session of any account",
+				Usage:       "admin session kill --session-id SESSION-ID",
+				Description: "terminate a running SSH session of any account, handled by the instance you're connected to (restricted to sb owners)",
+				Aliases:     []string{"adminKillSession"},
+			}, map[string]commands.Argument{
+				"session-id": {
+					Required:    true,
+					Description: "The ID of the running session to terminate",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *AdminKillSession) Checks(ct *commands.Context) error {
+	// No specific checks, rights are handled by the SBOwner right
+	return nil
+}
+
+// Execute executes the command
+func (c *AdminKillSession) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	session, _, err := getRunningSession(ct.FormattedArguments["session-id"])
+	if err != nil {
+		return
+	}
+
+	// Only the daemon can signal the sb process of the session, once it checked it belongs to the account
+	err = sessionctl.TerminateSession(config.GetSessionsControlSocket(), session.UniqID)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to terminate session %s", session.UniqID)
+		return
+	}
+
+	fmt.Printf("Session %s of %s to %s@%s:%s has been terminated\n", session.UniqID, session.UserFrom, session.UserTo, session.HostTo, session.PortTo)
+
+	return
+}
+
+func (c *AdminKillSession) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *AdminKillSession) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// AdminListSessions describes the adminListSessions command
+type AdminListSessions struct{}
+
+func init() {
+	commands.RegisterCommand("admin sessions list", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(AdminListSessions), models.Auditor, helpers.Helper{
+				Header:      "list the SSH sessions of all accounts",
+				Usage:       "admin sessions list [--account ACCOUNT --host HOST --since DATE --until DATE --limit LIMIT]",
+				Description: "list the SSH sessions of all accounts (restricted to sb owners and members of the auditors group)",
+				Aliases:     []string{"adminListSessions"},
+			}, map[string]commands.Argument{
+				"account": {
+					Required:    false,
+					Description: "Only list the sessions initiated by this account",
+				},
+				"host": {
+					Required:    false,
+					Description: "Only list the sessions to this distant host",
+				},
+				"since": {
+					Required:    false,
+					Description: "Only list the sessions started after this date (RFC3339, 2006-01-02 or a duration like 7d)",
+				},
+				"until": {
+					Required:    false,
+					Description: "Only list the sessions started before this date (RFC3339, 2006-01-02 or a duration like 7d)",
+				},
+				"limit": {
+					Required:     false,
+					Description:  "The maximum number of sessions to list (default is 50)",
+					DefaultValue: "50",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *AdminListSessions) Checks(ct *commands.Context) error {
+	_, err := c.buildFilter(ct.FormattedArguments)
+	return err
+}
+
+// Execute executes the command
+func (c *AdminListSessions) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// adminSessionsResult describes the result of the command
+type adminSessionsResult struct {
+	Sessions []*sessionResult `json:"sessions" yaml:"sessions"`
+}
+
+// Result returns the SSH sessions of every account matching the filters
+func (c *AdminListSessions) Result(ct *commands.Context) (result commands.Result, err error) {
+
+	filter, err := c.buildFilter(ct.FormattedArguments)
+	if err != nil {
+		return
+	}
+
+	lastSessions, err := models.GetSSHSessions(config.GetGlobalDatabasePath(), filter)
+	if err != nil {
+		return
+	}
+
+	return &adminSessionsResult{Sessions: newSessionResults(lastSessions)}, nil
+}
+
+func (r *adminSessionsResult) PrintTable() {
+
+	sessions := make([]string, 0, len(r.Sessions))
+	for id, sr := range r.Sessions {
+		sessions = append(sessions, fmt.Sprintf("%02d: %s", id+1, sr.session.String()))
+	}
+
+	if len(sessions) > 0 {
+		fmt.Printf(
+			"Here is the list of the matching SSH sessions:\n%s\n",
+			strings.Join(sessions, "\n"),
+		)
+	} else {
+		fmt.Println("No recorded SSH session matches your criteria")
+	}
+}
+
+func (c *AdminListSessions) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *AdminListSessions) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *AdminListSessions) buildFilter(arguments map[string]string) (filter *models.SSHSessionsFilter, err error) {
+
+	filter = &models.SSHSessionsFilter{
+		Account: arguments["account"],
+		Host:    arguments["host"],
+	}
+
+	filter.Limit, err = strconv.Atoi(arguments["limit"])
+	if err != nil || filter.Limit < 1 {
+		return filter, fmt.Errorf("argument limit should be a positive integer")
+	}
+
+	now := time.Now()
+	if arguments["since"] != "" {
+		filter.Since, err = helpers.ParseDate(arguments["since"], now)
+		if err != nil {
+			return
+		}
+	}
+	if arguments["until"] != "" {
+		filter.Until, err = helpers.ParseDate(arguments["until"], now)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
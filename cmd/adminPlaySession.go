@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/recording"
+)
+
+// AdminPlaySession describes the adminPlaySession command
+type AdminPlaySession struct {
+	options recording.PlayerOptions
+}
+
+func init() {
+	commands.RegisterCommand("admin session replay", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(AdminPlaySession), models.Auditor, helpers.Helper{
+				Header:      "watch a recording of any account's SSH session",
+				Usage:       "admin session replay --session-id SESSION-ID [--speed SPEED --max-idle DURATION --from OFFSET --to OFFSET --show-input]",
+				Description: `watch a recording of any account's SSH session (restricted to sb owners and members of the auditors group).
+             While watching: space pauses and resumes, "." steps to the next frame while paused,
+             "+" and "-" double and halve the speed, "f" skips 10 seconds ahead and "q" quits.`,
+				Aliases:     []string{"adminPlaySession"},
+			}, map[string]commands.Argument{
+				"session-id": {
+					Required:    true,
+					Description: "The session recording ID to watch",
+				},
+				"speed": {
+					Required:     false,
+					Description:  "The play speed factor of the session",
+					DefaultValue: "1.0",
+				},
+				"max-idle": {
+					Required:    false,
+					Description: "Compress the idle times longer than this duration (e.g. 2s)",
+				},
+				"from": {
+					Required:    false,
+					Description: "Start the replay at this offset of the session (e.g. 1h15m)",
+				},
+				"to": {
+					Required:    false,
+					Description: "Stop the replay at this offset of the session (e.g. 1h30m)",
+				},
+				"show-input": {
+					Required:    false,
+					Description: "Show the keystrokes of the session, if they were recorded",
+					Type:        commands.BOOL,
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *AdminPlaySession) Checks(ct *commands.Context) error {
+
+	options, err := parsePlayerOptions(ct.FormattedArguments)
+	if err != nil {
+		return err
+	}
+	c.options = options
+
+	return nil
+}
+
+// Execute executes the command
+func (c *AdminPlaySession) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	_, showInput := ct.FormattedArguments["show-input"]
+	localFilepath, cleanup, err := getAuditedSessionRecord(ct.FormattedArguments["session-id"], showInput)
+	if err != nil {
+		return
+	}
+
+	err = replaySessionRecord(localFilepath, c.options)
+	if err != nil {
+		cleanup()
+		return
+	}
+
+	err = cleanup()
+
+	return
+}
+
+func (c *AdminPlaySession) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *AdminPlaySession) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
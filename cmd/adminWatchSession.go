@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/recording"
+	"github.com/pkg/errors"
+)
+
+// AdminWatchSession describes the adminWatchSession command
+type AdminWatchSession struct{}
+
+func init() {
+	commands.RegisterCommand("admin session watch", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(AdminWatchSession), models.Auditor, helpers.Helper{
+				Header:      "follow a running SSH session of any account",
+				Usage:       "admin session watch --session-id SESSION-ID",
+				Description: `follow a running SSH session of any account, read-only (restricted to sb owners and members of the auditors group).
+             The session has to be handled by the instance you're connected to. Press "q" to stop watching.`,
+				Aliases:     []string{"adminWatchSession"},
+			}, map[string]commands.Argument{
+				"session-id": {
+					Required:    true,
+					Description: "The ID of the running session to watch",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *AdminWatchSession) Checks(ct *commands.Context) error {
+	// No specific checks, rights are handled by the Auditor right
+	return nil
+}
+
+// Execute executes the command
+func (c *AdminWatchSession) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	session, user, err := getRunningSession(ct.FormattedArguments["session-id"])
+	if err != nil {
+		return
+	}
+
+	f, err := os.Open(fmt.Sprintf("%s/%s.ttyrec", user.GetTtyrecDirectory(), session.UniqID))
+	if err != nil {
+		err = errors.Wrap(err, "unable to open the session recording")
+		return
+	}
+	defer f.Close()
+
+	controls, restore, err := readControls()
+	if err != nil {
+		return
+	}
+
+	// We stop when the user asks to, or when the session is over
+	quit := false
+	stop := func() bool {
+		select {
+		case key, ok := <-controls:
+			quit = !ok || key == recording.KeyQuit || key == recording.KeyInterrupt
+		default:
+		}
+		return quit || !sessionProcessRunning(session, user)
+	}
+
+	err = recording.Follow(f, os.Stdout, stop, 100*time.Millisecond)
+	restore()
+	if err != nil {
+		return
+	}
+
+	if quit {
+		fmt.Printf("\r\n<< Stopped watching session %s\n", session.UniqID)
+	} else {
+		fmt.Printf("\r\n<< Session %s is over\n", session.UniqID)
+	}
+
+	return
+}
+
+func (c *AdminWatchSession) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *AdminWatchSession) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
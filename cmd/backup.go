@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/inpher/sb/internal/commands"
@@ -48,42 +49,16 @@ func (c *Backup) Execute(ct *commands.Context) (repl models.ReplicationData, cmd
 		return
 	}
 
-	users, err := models.GetAllSBUsers()
-	if err != nil {
-		err = errors.Wrap(err, "unable to list all sb users")
-		return
-	}
-
-	groups, err := models.GetAllSBGroups()
-	if err != nil {
-		err = errors.Wrap(err, "unable to list all sb groups")
-		return
-	}
-
 	// Build the filename (without extension)
 	filename := fmt.Sprintf("%s/sb-backup_%s_%s_%s", ct.FormattedArguments["backup-directory"], config.GetSBName(), hostname, time.Now().Format("20060102T150405Z0700"))
 
 	// List all the files to backup
-	pathsToArchive := map[string]string{
-		"/etc/shadow":                  "/etc/shadow",
-		"/etc/group":                   "/etc/group",
-		"/etc/passwd":                  "/etc/passwd",
-		"/etc/sudoers.d":               "/etc/sudoers.d",
-		config.GetGlobalDatabasePath(): config.GetGlobalDatabasePath(),
-	}
-
-	for _, user := range users {
-		rootPath := fmt.Sprintf("/home/%s", user)
-		pathsToArchive[rootPath] = rootPath
-	}
-
-	for _, group := range groups {
-		rootPath := fmt.Sprintf("/home/%s", group.SystemName)
-		pathsToArchive[rootPath] = rootPath
-
+	pathsToArchive, err := getPathsToBackup()
+	if err != nil {
+		return
 	}
 
-	err = c.createArchive(fmt.Sprintf("%s.tar.gz", filename), pathsToArchive)
+	err = createArchive(fmt.Sprintf("%s.tar.gz", filename), pathsToArchive, nil)
 	if err != nil {
 		return
 	}
@@ -116,7 +91,65 @@ func (c *Backup) Replicate(repl models.ReplicationData) (err error) {
 	return
 }
 
-func (c *Backup) createArchive(filename string, pathsToArchive map[string]string) error {
+// getPathsToBackup returns the files and directories holding the state of sb, by their name in the archive
+func getPathsToBackup() (pathsToArchive map[string]string, err error) {
+
+	users, err := models.GetAllSBUsers()
+	if err != nil {
+		err = errors.Wrap(err, "unable to list all sb users")
+		return
+	}
+
+	groups, err := models.GetAllSBGroups()
+	if err != nil {
+		err = errors.Wrap(err, "unable to list all sb groups")
+		return
+	}
+
+	pathsToArchive = map[string]string{
+		"/etc/shadow":                    "/etc/shadow",
+		"/etc/group":                     "/etc/group",
+		"/etc/passwd":                    "/etc/passwd",
+		"/etc/sudoers.d":                 "/etc/sudoers.d",
+		config.GetGlobalDatabasePath():   config.GetGlobalDatabasePath(),
+		config.GetRequestsDatabasePath(): config.GetRequestsDatabasePath(),
+		config.GetHostsDatabasePath():    config.GetHostsDatabasePath(),
+	}
+
+	for _, user := range users {
+		rootPath := fmt.Sprintf("/home/%s", user)
+		pathsToArchive[rootPath] = rootPath
+	}
+
+	for _, group := range groups {
+		rootPath := fmt.Sprintf("/home/%s", group.SystemName)
+		pathsToArchive[rootPath] = rootPath
+
+	}
+
+	return
+}
+
+// getLocalDirectories returns the directories whose content is specific to this instance, and isn't replicated:
+// the session recordings of the accounts, by their name in the archive
+func getLocalDirectories() (directories []string, err error) {
+
+	users, err := models.GetAllSBUsers()
+	if err != nil {
+		err = errors.Wrap(err, "unable to list all sb users")
+		return
+	}
+
+	directories = make([]string, 0, len(users))
+	for _, user := range users {
+		directories = append(directories, fmt.Sprintf("/home/%s/ttyrecs", user))
+	}
+
+	return
+}
+
+// createArchive archives the paths, without the content of the excluded directories (the directories themselves are kept)
+func createArchive(filename string, pathsToArchive map[string]string, excludedDirectories []string) error {
 
 	// Create the backup file
 	out, err := os.Create(filename)
@@ -133,6 +166,9 @@ func (c *Backup) createArchive(filename string, pathsToArchive map[string]string
 	if err != nil {
 		return errors.Wrap(err, "unable to prepare files to archive")
 	}
+	if len(excludedDirectories) > 0 {
+		files = excludeFiles(files, excludedDirectories)
+	}
 
 	// Create the archive
 	err = format.Archive(context.Background(), out, files)
@@ -142,3 +178,23 @@ func (c *Backup) createArchive(filename string, pathsToArchive map[string]string
 
 	return nil
 }
+
+// excludeFiles returns the files that are not in the excluded directories
+func excludeFiles(files []archiver.File, excludedDirectories []string) (kept []archiver.File) {
+
+	kept = make([]archiver.File, 0, len(files))
+	for _, f := range files {
+		excluded := false
+		for _, directory := range excludedDirectories {
+			if strings.HasPrefix(f.NameInArchive, strings.TrimSuffix(directory, "/")+"/") {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, f)
+		}
+	}
+
+	return
+}
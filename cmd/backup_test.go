@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateArchiveExcludedDirectories(t *testing.T) {
+
+	dir := t.TempDir()
+	home := filepath.Join(dir, "t1000")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "ttyrecs"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "accesses.db"), []byte("accesses"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "ttyrecs", "session.ttyrec"), []byte("session"), 0600))
+
+	archivePath := filepath.Join(dir, "snapshot.tar.gz")
+	require.NoError(t, createArchive(archivePath, map[string]string{home: "/home/t1000"}, []string{"/home/t1000/ttyrecs"}))
+
+	archive, err := os.Open(archivePath)
+	require.NoError(t, err)
+	defer archive.Close()
+
+	// The content of the excluded directories is left out, but not the directories themselves
+	names := make([]string, 0)
+	require.NoError(t, restoreArchive(archive, map[string]func(io.Reader) error{
+		"/home/t1000":                        func(io.Reader) error { names = append(names, "/home/t1000"); return nil },
+		"/home/t1000/accesses.db":            func(io.Reader) error { names = append(names, "/home/t1000/accesses.db"); return nil },
+		"/home/t1000/ttyrecs":                func(io.Reader) error { names = append(names, "/home/t1000/ttyrecs"); return nil },
+		"/home/t1000/ttyrecs/session.ttyrec": func(io.Reader) error { names = append(names, "/home/t1000/ttyrecs/session.ttyrec"); return nil },
+	}))
+	require.ElementsMatch(t, []string{"/home/t1000", "/home/t1000/accesses.db", "/home/t1000/ttyrecs"}, names)
+}
@@ -1,24 +1,49 @@
 package cmd
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/ReneKroon/ttlcache"
+	"github.com/google/uuid"
+	"github.com/inpher/sb/internal/auditsink"
 	"github.com/inpher/sb/internal/commands"
 	"github.com/inpher/sb/internal/config"
 	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/metrics"
 	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/notification"
 	"github.com/inpher/sb/internal/replicationqueue"
+	"github.com/inpher/sb/internal/sessionctl"
+	"github.com/inpher/sb/internal/sshca"
 	"github.com/inpher/sb/internal/types"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
+// internalReplicationActions are the replication entries that aren't made by a command: they have no PostExecute step
+var internalReplicationActions = map[string]bool{
+	"log":              true,
+	"new-log":          true,
+	"state digest":     true,
+	"snapshot request": true,
+	"snapshot chunk":   true,
+}
+
 // CreateAccount describes the command
 type Daemon struct {
-	hostname   string
-	replicated *ttlcache.Cache
+	hostname      string
+	replicationDB *gorm.DB // The replication database, where the versions of the objects, the applied entries and the dead letters are kept
+	maxAttempts   int
+	consuming     sync.RWMutex // Held to apply an entry of another instance, and exclusively to take a snapshot
+	replaying     sync.Mutex   // Held to apply the entries buffered during a join
 }
 
 func init() {
@@ -40,14 +65,10 @@ func (c *Daemon) Checks(ct *commands.Context) error {
 // Execute executes the command
 func (c *Daemon) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
 
-	// IF replication is not enabled, nothing to do
 	replicationQueueConfig := config.GetReplicationQueueConfig()
 	ttyrecsOffloadingConfig := config.GetTTYRecsOffloadingConfig()
-
-	// If both replication and ttyrecs offloading is disabled, nothing to do
-	if !replicationQueueConfig.Enabled && !ttyrecsOffloadingConfig.Enabled {
-		return repl, cmdError, types.ErrCommandDisabled
-	}
+	egressCAConfig := config.GetEgressCAConfig()
+	notificationsConfig := config.GetNotificationsConfig()
 
 	// We need to guess our own hostname
 	c.hostname, err = helpers.GetHostname()
@@ -57,22 +78,92 @@ func (c *Daemon) Execute(ct *commands.Context) (repl models.ReplicationData, cmd
 
 	fmt.Fprintf(os.Stdout, "Starting daemon for hostname: %s\n", c.hostname)
 
+	// Periodically remove the expired accesses (and replicate their removal), even on a standalone instance
+	go c.purgeExpiredAccesses(replicationQueueConfig.Enabled)
+
+	// The egress CA signs the certificates of the ttyrec sessions
+	if egressCAConfig.Enabled {
+		ca, errCA := sshca.NewServer(egressCAConfig.Socket, egressCAConfig.PrivateKey, egressCAConfig.Validity, egressCAConfig.SourceAddresses, config.GetForwardAgent(), c.authorizeEgressCertificate)
+		if errCA != nil {
+			err = errCA
+			return
+		}
+
+		fmt.Fprintf(os.Stdout, "Egress CA listening on %s with public key: %s", egressCAConfig.Socket, ca.PublicKey())
+		go func() {
+			errServe := ca.Serve()
+			fmt.Fprintf(os.Stderr, "ERROR: egress CA stopped: %s\n", errServe)
+			os.Exit(1)
+		}()
+	}
+
+	// The sb owners terminate the running sessions through the daemon, the only one allowed to signal their processes
+	sessions, err := sessionctl.NewServer(config.GetSessionsControlSocket(), c.terminateSession)
+	if err != nil {
+		return
+	}
+	go func() {
+		errServe := sessions.Serve()
+		fmt.Fprintf(os.Stderr, "ERROR: sessions control stopped: %s\n", errServe)
+		os.Exit(1)
+	}()
+
+	// The audit events queued by the commands are emitted to the audit sinks
+	auditSinksConfig := config.GetAuditSinksConfig()
+	if len(auditSinksConfig) > 0 {
+		go c.emitAuditEvents(auditSinksConfig)
+	}
+
+	// The notifications queued by the commands are delivered to the webhooks
+	if notificationsConfig.Enabled {
+		go c.deliverNotifications(notificationsConfig)
+	}
+
+	// Without replication nor ttyrecs offloading, we're done
+	if !replicationQueueConfig.Enabled && !ttyrecsOffloadingConfig.Enabled {
+		select {}
+	}
+
 	// Init the replication queue backend
 	rq, err := replicationqueue.GetReplicationQueue(replicationQueueConfig, c.hostname)
 	if err != nil {
 		return
 	}
 
+	c.replicationDB, err = models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+	c.maxAttempts = replicationQueueConfig.MaxAttempts
+
+	// The state of the replication is exposed as Prometheus metrics
+	metricsConfig := config.GetMetricsConfig()
+	if metricsConfig.Enabled {
+		fmt.Fprintf(os.Stdout, "Exposing the metrics on http://%s/metrics\n", metricsConfig.Listen)
+		go func() {
+			errServe := metrics.ListenAndServe(metricsConfig.Listen, collectReplicationMetrics)
+			fmt.Fprintf(os.Stderr, "ERROR: metrics listener stopped: %s\n", errServe)
+			os.Exit(1)
+		}()
+	}
+
 	// If replication is enabled, we start replicating other instances' actions
 	if replicationQueueConfig.Enabled {
-		c.replicated = ttlcache.NewCache()
-		c.replicated.SetTTL(5 * time.Minute)
 		go c.consumeReplicationEvents(rq)
+		go c.purgeAppliedEntries(replicationQueueConfig.AppliedRetention)
 	}
 
+	// The dead letters are tried again on request
+	go c.retryDeadLetters()
+
 	// Handle post executions and potentially push events to other instances via the queue
 	go c.publishReplicationEvents(rq, !replicationQueueConfig.Enabled)
 
+	// Periodically send the digest of our state to the other instances, to detect the divergences
+	if replicationQueueConfig.Enabled && replicationQueueConfig.VerifyInterval > 0 {
+		go c.verifyState(replicationQueueConfig.VerifyInterval)
+	}
+
 	// Let's just wait indefinitely
 	select {}
 }
@@ -85,69 +176,327 @@ func (c *Daemon) Replicate(repl models.ReplicationData) (err error) {
 	return
 }
 
+// notificationsPollInterval is how often the daemon looks for notifications to deliver
+const notificationsPollInterval = 5 * time.Second
+
+func (c *Daemon) deliverNotifications(notificationsConfig *types.NotificationsConfig) {
+
+	db, err := models.GetNotificationsGormDB(config.GetNotificationsDatabasePath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: notifications won't be delivered: %s\n", err)
+		return
+	}
+
+	for range time.Tick(notificationsPollInterval) {
+
+		notifications, err := models.GetDueNotifications(db, 100)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			continue
+		}
+
+		for _, n := range notifications {
+			err = c.deliverNotification(db, notificationsConfig, n)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: unable to update notification %d: %s\n", n.ID, err)
+			}
+		}
+	}
+}
+
+// deliverNotification sends a notification to its webhook, and removes it from the queue unless it has to be retried
+func (c *Daemon) deliverNotification(db *gorm.DB, notificationsConfig *types.NotificationsConfig, n *models.Notification) (err error) {
+
+	webhook, ok := notificationsConfig.Webhooks[n.Webhook]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Dropping notification %d: webhook %s is not configured anymore\n", n.ID, n.Webhook)
+		return n.Delete(db)
+	}
+
+	var event types.NotificationEvent
+	err = json.Unmarshal([]byte(n.Event), &event)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Dropping notification %d: %s\n", n.ID, err)
+		return n.Delete(db)
+	}
+
+	errSend := notification.Send(webhook, &event)
+	if errSend == nil {
+		fmt.Printf("Notification %d (%s) delivered to webhook %s\n", n.ID, event.Event, webhook.Name)
+		return n.Delete(db)
+	}
+
+	if n.Attempts+1 >= notificationsConfig.MaxAttempts {
+		fmt.Fprintf(os.Stderr, "Dropping notification %d (%s) after %d attempts: %s\n", n.ID, event.Event, n.Attempts+1, errSend)
+		return n.Delete(db)
+	}
+
+	fmt.Fprintf(os.Stderr, "Unable to deliver notification %d (%s), it will be retried: %s\n", n.ID, event.Event, errSend)
+	return n.Postpone(db, errSend)
+}
+
+// auditEventsPollInterval is how often the daemon looks for audit events to emit
+const auditEventsPollInterval = time.Second
+
+func (c *Daemon) emitAuditEvents(auditSinksConfig []*types.AuditSinkConfig) {
+
+	sinks := make([]auditsink.AuditSink, len(auditSinksConfig))
+	for i, sinkConfig := range auditSinksConfig {
+		sink, err := auditsink.GetAuditSink(sinkConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: audit events won't be emitted to sink %d: %s\n", i, err)
+			continue
+		}
+		sinks[i] = sink
+	}
+
+	db, err := models.GetAuditGormDB(config.GetAuditDatabasePath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: audit events won't be emitted: %s\n", err)
+		return
+	}
+
+	for range time.Tick(auditEventsPollInterval) {
+
+		events, err := models.GetQueuedAuditEvents(db, 1000)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			continue
+		}
+
+		// While an event waits to be retried, the next ones of its sink wait for it, to keep them in order
+		failed := make(map[int]bool)
+		for _, e := range events {
+			if failed[e.Sink] {
+				continue
+			}
+			if !e.IsDue() {
+				failed[e.Sink] = true
+				continue
+			}
+			emitted, err := c.emitAuditEvent(db, sinks, e)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: unable to update audit event %d: %s\n", e.ID, err)
+			}
+			if !emitted {
+				failed[e.Sink] = true
+			}
+		}
+	}
+}
+
+// emitAuditEvent emits an audit event to its sink, and removes it from the queue unless it has to be retried.
+// The audit events are never dropped, but the ones of a sink that isn't configured anymore.
+func (c *Daemon) emitAuditEvent(db *gorm.DB, sinks []auditsink.AuditSink, e *models.QueuedAuditEvent) (emitted bool, err error) {
+
+	if e.Sink >= len(sinks) {
+		fmt.Fprintf(os.Stderr, "Dropping audit event %d: sink %d is not configured anymore\n", e.ID, e.Sink)
+		return true, e.Delete(db)
+	}
+
+	event, err := e.AuditEvent()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Dropping audit event %d: %s\n", e.ID, err)
+		return true, e.Delete(db)
+	}
+
+	errEmit := fmt.Errorf("the sink is not valid")
+	if sinks[e.Sink] != nil {
+		errEmit = sinks[e.Sink].Emit(event)
+	}
+	if errEmit == nil {
+		return true, e.Delete(db)
+	}
+
+	fmt.Fprintf(os.Stderr, "Unable to emit audit event %d to sink %d, it will be retried: %s\n", e.ID, e.Sink, errEmit)
+	return false, e.Postpone(db, errEmit)
+}
+
 func (c *Daemon) consumeReplicationEvents(rq replicationqueue.ReplicationQueue) (err error) {
 
 	return rq.ConsumeQueue(func(entry *models.Replication) (err error) {
 
 		fmt.Printf("New replication entry received: [instance:%s|type:%s|ID:%s]\n", entry.Instance, entry.Action, entry.UniqID)
-		if _, exists := c.replicated.Get(entry.UniqID); exists {
-			fmt.Println("  -> this message was already replicated")
-			return
-		}
 
 		// If I sent this entry myself, I don't care about it
 		if entry.Instance == c.hostname {
-			c.replicated.Set(entry.UniqID, "ok")
 			return
 		}
 
-		// Let's decipher the replication data
-		replicationData, err := models.DecryptReplicationData(entry.Data)
+		// No entry of another instance is applied while a snapshot is taken, so that its watermark matches its content
+		if entry.Action == "snapshot request" {
+			c.consuming.Lock()
+			defer c.consuming.Unlock()
+		} else {
+			c.consuming.RLock()
+			defer c.consuming.RUnlock()
+		}
+
+		applied, err := models.IsReplicationEntryApplied(c.replicationDB, entry.UniqID)
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			return
+		}
+		if applied {
+			fmt.Println("  -> this message was already replicated")
 			return
 		}
 
-		switch entry.Action {
-		case "log", "new-log":
-
-			var log models.Log
-			err = json.Unmarshal([]byte(replicationData["log"]), &log)
+		// While this instance joins the cluster, the entries are applied once the snapshot is restored
+		if entry.Action != "snapshot request" && entry.Action != "snapshot chunk" {
+			join, err := models.GetActiveReplicationJoin(c.replicationDB)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "ERROR: unable to json.Unmarshal log entry: %s", err)
-				return
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+				return err
+			}
+			if join != nil {
+				fmt.Printf("  -> joining from %s: the entry will be applied once the snapshot is restored\n", join.Peer)
+				return models.BufferReplicationEntry(c.replicationDB, entry)
 			}
 
-			err = log.Replicate((entry.Action == "new-log"))
+			// The entries kept by a join that failed are applied before the new ones
+			_, _, err = c.replayBufferedEntries(nil)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "ERROR: unable to save log entry: %s\n", err)
-				return
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+				return err
 			}
+		}
+
+		return c.consumeReplicationEntry(entry, true)
+	})
+
+}
 
-			c.replicated.Set(entry.UniqID, "ok")
+// consumeReplicationEntry applies an entry of another instance. If it fails, the error is returned for the queue to
+// deliver the entry again after a growing delay, until it failed too many times and is put in the dead letters.
+// An entry that won't be delivered again is put in the dead letters as soon as it fails.
+func (c *Daemon) consumeReplicationEntry(entry *models.Replication, redelivered bool) (err error) {
 
+	err = c.applyReplicationEntry(entry)
+	c.recordConsume(entry, err)
+	if err == nil {
+		return models.MarkReplicationEntryApplied(c.replicationDB, entry)
+	}
+
+	attempts, errAttempt := models.RecordReplicationEntryFailure(c.replicationDB, entry, err)
+	if errAttempt != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", errAttempt)
+		return err
+	}
+
+	if redelivered && attempts < c.maxAttempts {
+		delay := models.ReplicationRetryDelay(attempts)
+		fmt.Fprintf(os.Stderr, "ERROR: attempt %d/%d to apply entry %s failed, it will be delivered again in %s\n", attempts, c.maxAttempts, entry.UniqID, delay)
+		return &models.ReplicationRetryError{Err: err, Delay: delay}
+	}
+
+	fmt.Fprintf(os.Stderr, "ERROR: moving entry %s to the dead letters after %d attempts\n", entry.UniqID, attempts)
+	return models.NewReplicationDeadLetter(models.DeadLetterConsume, entry, attempts, err).Save(c.replicationDB)
+}
+
+// applyReplicationEntry applies an entry of another instance on this one
+func (c *Daemon) applyReplicationEntry(entry *models.Replication) (err error) {
+
+	// Let's decipher the replication data
+	replicationData, err := models.DecryptReplicationData(entry.Data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: unable to decrypt replication data: %s\n", err)
+		return
+	}
+
+	switch entry.Action {
+	case "log", "new-log":
+
+		var log models.Log
+		err = json.Unmarshal([]byte(replicationData["log"]), &log)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to json.Unmarshal log entry: %s\n", err)
+			return
+		}
+
+		err = log.Replicate((entry.Action == "new-log"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to save log entry: %s\n", err)
+			return
+		}
+
+		return nil
+
+	case "state digest":
+
+		err = c.compareStateDigest(entry.Instance, replicationData)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to compare the state of %s: %s\n", entry.Instance, err)
+			return
+		}
+
+		return nil
+
+	case "snapshot request":
+
+		if replicationData["peer"] != c.hostname {
 			return nil
+		}
 
-		default:
+		err = c.sendSnapshot(entry.Instance, replicationData["request"])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to send a snapshot to %s: %s\n", entry.Instance, err)
+			return
+		}
 
-			cmd, _, _, _, err := commands.GetCommand(entry.Action)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "ERROR: unknown command to replicate: %s\n", err)
-				return err
-			}
+		return nil
+
+	case "snapshot chunk":
+
+		if replicationData["requester"] != c.hostname {
+			return nil
+		}
+
+		err = c.receiveSnapshotChunk(replicationData)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to receive the snapshot: %s\n", err)
+			return
+		}
+
+		return nil
+
+	default:
+
+		cmd, _, _, _, err := commands.GetCommand(entry.Action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unknown command to replicate: %s\n", err)
+			return err
+		}
+
+		// The versioned actions are only applied if they're the latest on their object
+		apply, conflict, err := models.ResolveReplicationEntry(c.replicationDB, entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to resolve the version of the action: %s\n", err)
+			return err
+		}
+		if conflict != nil {
+			fmt.Printf("  -> conflict on %s: keeping %s from %s over %s from %s\n", conflict.ObjectKey, conflict.WinnerAction, conflict.WinnerInstance, conflict.LoserAction, conflict.LoserInstance)
+		}
 
+		if apply {
 			err = cmd.Replicate(replicationData)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "ERROR: unable to replicate action: %s\n", err)
 				return err
 			}
+		} else if conflict == nil {
+			fmt.Printf("  -> a later action was already applied on %s\n", entry.ObjectKey)
+		}
 
-			c.replicated.Set(entry.UniqID, "ok")
-
-			return nil
-
+		err = models.CommitReplicationEntry(c.replicationDB, entry, apply, conflict)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to record the version of the action: %s\n", err)
+			return err
 		}
-	})
 
+		return nil
+
+	}
 }
 
 func (c *Daemon) publishReplicationEvents(rq replicationqueue.ReplicationQueue, onlyHandlePostExec bool) (err error) {
@@ -157,6 +506,11 @@ func (c *Daemon) publishReplicationEvents(rq replicationqueue.ReplicationQueue,
 		return
 	}
 
+	// The entry at the head of the queue, whether its PostExecute step is done, and the failed attempts on it
+	var current string
+	var postExecuted bool
+	var attempts, pushAttempts int
+
 	for {
 		entry, err := models.GetNextReplicationEntryToPush(dbHandler)
 		if err != nil {
@@ -164,28 +518,49 @@ func (c *Daemon) publishReplicationEvents(rq replicationqueue.ReplicationQueue,
 			continue
 		}
 
+		if entry.UniqID != current {
+			current, postExecuted, attempts, pushAttempts = entry.UniqID, false, 0, 0
+		}
+
 		fmt.Printf("New action entry to process: [instance:%s|type:%s|ID:%s]\n", entry.Instance, entry.Action, entry.UniqID)
 
-		if entry.Action != "log" && entry.Action != "new-log" {
+		if !postExecuted && !internalReplicationActions[entry.Action] {
 			fmt.Println("  -> executing PostExecution step...")
 
 			// Starting with the PostExecute function
 			err = c.handlePostExecution(entry)
 			if err != nil {
+				attempts++
+				c.recordPush(err)
 				fmt.Fprintf(os.Stderr, "ERROR: unable to handle PostExecution: %s\n", err)
-				time.Sleep(time.Second * 5)
+
+				// The entry is put aside so that the next ones can proceed: it is neither post-executed nor published
+				if attempts >= c.maxAttempts {
+					fmt.Fprintf(os.Stderr, "ERROR: moving entry %s to the dead letters after %d attempts\n", entry.UniqID, attempts)
+					err = c.moveToDeadLetters(dbHandler, &entry, attempts, err)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+						time.Sleep(time.Second * 5)
+					}
+					continue
+				}
+
+				time.Sleep(models.ReplicationRetryDelay(attempts))
 				continue
 			}
 		}
+		postExecuted = true
 
 		if !onlyHandlePostExec {
 			fmt.Println("  -> publishing to PubSub...")
 
-			// Then publishing the entry
+			// Then publishing the entry: the queue is tried again until it's available, the entry itself being fine
 			err = rq.PushToQueue(&entry)
+			c.recordPush(err)
 			if err != nil {
+				pushAttempts++
 				fmt.Fprintf(os.Stderr, "ERROR: unable to handle publish: %s\n", err)
-				time.Sleep(time.Second * 5)
+				time.Sleep(models.ReplicationRetryDelay(pushAttempts))
 				continue
 			}
 		}
@@ -203,6 +578,442 @@ func (c *Daemon) publishReplicationEvents(rq replicationqueue.ReplicationQueue,
 
 }
 
+// moveToDeadLetters replaces an entry of this instance by its dead letter
+func (c *Daemon) moveToDeadLetters(db *gorm.DB, entry *models.Replication, attempts int, lastError error) (err error) {
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := models.NewReplicationDeadLetter(models.DeadLetterPublish, entry, attempts, lastError).Save(tx)
+		if err != nil {
+			return err
+		}
+		return entry.Delete(tx)
+	})
+}
+
+// deadLettersPollInterval is how often the daemon looks for the dead letters to try again
+const deadLettersPollInterval = 5 * time.Second
+
+// retryDeadLetters tries again the dead letters requested with the replication dlq retry command
+func (c *Daemon) retryDeadLetters() {
+
+	for range time.Tick(deadLettersPollInterval) {
+
+		deadLetters, err := models.GetRetryRequestedDeadLetters(c.replicationDB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			continue
+		}
+
+		for _, dl := range deadLetters {
+			err = c.retryDeadLetter(dl)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: unable to update dead letter %d: %s\n", dl.ID, err)
+			}
+		}
+	}
+}
+
+// retryDeadLetter tries a dead letter again, and removes it unless it failed again
+func (c *Daemon) retryDeadLetter(dl *models.ReplicationDeadLetter) (err error) {
+
+	entry := dl.Entry()
+
+	// An entry of this instance goes back to the queue of the entries to post-execute and publish
+	if dl.Step == models.DeadLetterPublish {
+		fmt.Printf("Dead letter %d (%s) queued again\n", dl.ID, dl.Action)
+		return c.replicationDB.Transaction(func(tx *gorm.DB) error {
+			err := entry.Save(tx)
+			if err != nil {
+				return err
+			}
+			return dl.Delete(tx)
+		})
+	}
+
+	c.consuming.RLock()
+	defer c.consuming.RUnlock()
+
+	applied, err := models.IsReplicationEntryApplied(c.replicationDB, entry.UniqID)
+	if err != nil {
+		return
+	}
+
+	if !applied {
+		errApply := c.applyReplicationEntry(entry)
+		c.recordConsume(entry, errApply)
+		if errApply != nil {
+			fmt.Fprintf(os.Stderr, "Dead letter %d (%s) failed again: %s\n", dl.ID, dl.Action, errApply)
+			return dl.Postpone(c.replicationDB, errApply)
+		}
+
+		err = models.MarkReplicationEntryApplied(c.replicationDB, entry)
+		if err != nil {
+			return
+		}
+	}
+
+	fmt.Printf("Dead letter %d (%s) applied\n", dl.ID, dl.Action)
+
+	return dl.Delete(c.replicationDB)
+}
+
+// recordPush records an attempt to publish an entry of this instance, for the replication status
+func (c *Daemon) recordPush(pushError error) {
+	err := models.RecordReplicationPush(c.replicationDB, c.hostname, pushError)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+	}
+}
+
+// recordConsume records an attempt to apply an entry of another instance, for the replication status
+func (c *Daemon) recordConsume(entry *models.Replication, consumeError error) {
+	err := models.RecordReplicationConsume(c.replicationDB, entry, consumeError)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+	}
+}
+
+// purgeAppliedEntries periodically forgets the old applied entries, that can't be delivered again
+func (c *Daemon) purgeAppliedEntries(retention time.Duration) {
+
+	for {
+		purged, err := models.PurgeAppliedReplicationEntries(c.replicationDB, time.Now().Add(-retention))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		} else if purged > 0 {
+			fmt.Printf("%d applied entries forgotten\n", purged)
+		}
+
+		time.Sleep(time.Hour)
+	}
+}
+
+func (c *Daemon) purgeExpiredAccesses(replicate bool) {
+
+	purger := new(PurgeExpiredAccesses)
+
+	for {
+		purged, err := purger.purge()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to purge expired accesses: %s\n", err)
+		}
+
+		// What was purged is replicated, even if some databases couldn't be purged
+		if !purged.isEmpty() && replicate {
+			err = c.pushPurgedAccesses(purged)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: unable to push purged accesses replication: %s\n", err)
+			}
+		}
+
+		time.Sleep(time.Minute)
+	}
+}
+
+func (c *Daemon) pushPurgedAccesses(purged *purgedAccesses) (err error) {
+
+	repl, err := purged.toReplicationData()
+	if err != nil {
+		return
+	}
+
+	entry, err := models.NewReplicationEntry("accesses purge", repl)
+	if err != nil {
+		return
+	}
+
+	dbHandler, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	return entry.Save(dbHandler)
+}
+
+// verifyState periodically sends the digest of our state to the other instances, which compare it with theirs
+func (c *Daemon) verifyState(interval time.Duration) {
+
+	for range time.Tick(interval) {
+		err := models.PushStateDigest(uuid.New().String())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to push the digest of our state: %s\n", err)
+		}
+	}
+}
+
+// compareStateDigest compares the digest of the state of another instance with our own, and records the differences
+func (c *Daemon) compareStateDigest(peer string, replicationData models.ReplicationData) (err error) {
+
+	var peerDigest models.StateDigest
+	err = json.Unmarshal([]byte(replicationData["digest"]), &peerDigest)
+	if err != nil {
+		return
+	}
+
+	local, err := models.ComputeStateDigest()
+	if err != nil {
+		return
+	}
+
+	verification, err := models.SaveStateVerification(c.replicationDB, peer, replicationData["round"], local, peerDigest)
+	if err != nil {
+		return
+	}
+
+	differences, err := verification.GetDifferences()
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("  -> %d items compared with %s, %d differences\n", verification.Items, peer, len(differences))
+	for _, d := range differences {
+		fmt.Printf("    -> %s: %s\n", d.Item, d.Difference)
+	}
+
+	return
+}
+
+// snapshotChunkSize is the size of the chunks the snapshots are sent in, small enough for every message queue
+const snapshotChunkSize = 256 * 1024
+
+// snapshotWatermarkName is the name of the watermark in the archive of a snapshot
+const snapshotWatermarkName = "sb-snapshot-watermark.json"
+
+// sendSnapshot sends a snapshot of this instance to another one through the replication queue. It's called with the
+// consumption lock held exclusively, so that the watermark matches the entries of the other instances it contains
+func (c *Daemon) sendSnapshot(requester, request string) (err error) {
+
+	fmt.Printf("  -> sending a snapshot to %s...\n", requester)
+
+	// The watermark is taken first: an action made meanwhile on this instance is applied twice rather than lost
+	watermark, err := models.GetReplicationWatermark(c.replicationDB, c.hostname)
+	if err != nil {
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "sb-snapshot-")
+	if err != nil {
+		return errors.Wrap(err, "unable to create the snapshot directory")
+	}
+	defer os.RemoveAll(dir)
+
+	watermarkJSON, err := json.Marshal(watermark)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the watermark")
+	}
+	watermarkPath := filepath.Join(dir, snapshotWatermarkName)
+	err = os.WriteFile(watermarkPath, watermarkJSON, 0600)
+	if err != nil {
+		return errors.Wrap(err, "unable to write the watermark")
+	}
+
+	pathsToArchive, err := getPathsToBackup()
+	if err != nil {
+		return
+	}
+	pathsToArchive[watermarkPath] = snapshotWatermarkName
+
+	// The session recordings stay on the instance they were made on
+	localDirectories, err := getLocalDirectories()
+	if err != nil {
+		return
+	}
+
+	archivePath := filepath.Join(dir, "snapshot.tar.gz")
+	err = createArchive(archivePath, pathsToArchive, localDirectories)
+	if err != nil {
+		return
+	}
+
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return errors.Wrap(err, "unable to open the snapshot")
+	}
+	defer archive.Close()
+
+	info, err := archive.Stat()
+	if err != nil {
+		return errors.Wrap(err, "unable to stat the snapshot")
+	}
+	total := int((info.Size() + snapshotChunkSize - 1) / snapshotChunkSize)
+
+	// The chunks are published like the entries of this instance
+	buf := make([]byte, snapshotChunkSize)
+	for index := 0; index < total; index++ {
+
+		n, err := io.ReadFull(archive, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return errors.Wrap(err, "unable to read the snapshot")
+		}
+
+		chunk, err := models.NewReplicationEntry("snapshot chunk", models.ReplicationData{
+			"request":   request,
+			"requester": requester,
+			"index":     strconv.Itoa(index),
+			"total":     strconv.Itoa(total),
+			"data":      base64.StdEncoding.EncodeToString(buf[:n]),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = chunk.Save(c.replicationDB)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("  -> snapshot of %d bytes queued for %s in %d chunks\n", info.Size(), requester, total)
+
+	return
+}
+
+// receiveSnapshotChunk records a chunk of the snapshot requested by this instance, and restores the snapshot once complete
+func (c *Daemon) receiveSnapshotChunk(replicationData models.ReplicationData) (err error) {
+
+	join, err := models.GetReplicationJoin(c.replicationDB, replicationData["request"])
+	if err != nil {
+		return
+	}
+	if join == nil || (join.State != models.JoinRequested && join.State != models.JoinReceiving) {
+		fmt.Println("  -> this snapshot is not expected anymore")
+		return nil
+	}
+
+	index, err := strconv.Atoi(replicationData["index"])
+	if err != nil {
+		return errors.Wrap(err, "invalid chunk index")
+	}
+	total, err := strconv.Atoi(replicationData["total"])
+	if err != nil {
+		return errors.Wrap(err, "invalid number of chunks")
+	}
+
+	complete, err := models.SaveSnapshotChunk(c.replicationDB, join, index, total, replicationData["data"])
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("  -> chunk %d/%d of the snapshot of %s received\n", join.Received, join.Chunks, join.Peer)
+	if !complete {
+		return
+	}
+
+	errJoin := c.completeJoin(join)
+	if errJoin != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: unable to join from %s: %s\n", join.Peer, errJoin)
+		err = models.DeleteSnapshotChunks(c.replicationDB, join.RequestID)
+		if err != nil {
+			return
+		}
+		return join.Fail(c.replicationDB, errJoin)
+	}
+
+	return
+}
+
+// completeJoin restores the snapshot of the join, then applies the entries received meanwhile it doesn't contain
+func (c *Daemon) completeJoin(join *models.ReplicationJoin) (err error) {
+
+	fmt.Printf("  -> restoring the snapshot of %s...\n", join.Peer)
+
+	archive, err := os.CreateTemp("", "sb-snapshot-*.tar.gz")
+	if err != nil {
+		return errors.Wrap(err, "unable to create the snapshot file")
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	err = models.WriteSnapshot(c.replicationDB, join.RequestID, archive)
+	if err != nil {
+		return
+	}
+
+	_, err = archive.Seek(0, io.SeekStart)
+	if err != nil {
+		return errors.Wrap(err, "unable to read the snapshot file")
+	}
+
+	var watermark *models.ReplicationWatermark
+	err = restoreArchive(archive, map[string]func(io.Reader) error{
+		snapshotWatermarkName: func(r io.Reader) error {
+			return json.NewDecoder(r).Decode(&watermark)
+		},
+	})
+	if err != nil {
+		return
+	}
+	if watermark == nil {
+		return fmt.Errorf("the snapshot has no watermark")
+	}
+
+	err = models.ImportReplicationWatermark(c.replicationDB, watermark)
+	if err != nil {
+		return
+	}
+
+	// The entries received during the join are applied, but the ones the snapshot contains
+	replayed, buffered, err := c.replayBufferedEntries(watermark)
+	if err != nil {
+		return
+	}
+
+	err = models.DeleteSnapshotChunks(c.replicationDB, join.RequestID)
+	if err != nil {
+		return
+	}
+
+	join.State = models.JoinDone
+	join.SnapshotAt = watermark.SnapshotAt
+	join.CompletedAt = time.Now()
+	err = join.Save(c.replicationDB)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("  -> joined from %s: snapshot restored, %d of the %d entries received meanwhile applied\n", join.Peer, replayed, buffered)
+
+	return
+}
+
+// replayBufferedEntries applies the entries buffered during a join, but the ones already applied or contained by the
+// snapshot of the watermark, if any. An entry is removed from the buffer once applied or put in the dead letters.
+func (c *Daemon) replayBufferedEntries(watermark *models.ReplicationWatermark) (replayed, total int, err error) {
+
+	c.replaying.Lock()
+	defer c.replaying.Unlock()
+
+	buffered, err := models.GetBufferedReplicationEntries(c.replicationDB)
+	if err != nil {
+		return
+	}
+
+	for _, b := range buffered {
+
+		entry := b.Entry()
+
+		applied, err := models.IsReplicationEntryApplied(c.replicationDB, entry.UniqID)
+		if err != nil {
+			return replayed, len(buffered), err
+		}
+
+		if !applied && (watermark == nil || !watermark.Covers(entry)) {
+			err = c.consumeReplicationEntry(entry, false)
+			if err != nil {
+				return replayed, len(buffered), err
+			}
+			replayed++
+		}
+
+		err = b.Delete(c.replicationDB)
+		if err != nil {
+			return replayed, len(buffered), err
+		}
+	}
+
+	return replayed, len(buffered), nil
+}
+
 func (c *Daemon) handlePostExecution(entry models.Replication) (err error) {
 
 	fmt.Println("    -> decrypting data...")
@@ -231,3 +1042,98 @@ func (c *Daemon) handlePostExecution(entry models.Replication) (err error) {
 
 	return
 }
+
+// authorizeEgressCertificate checks again, as root, that the account owning the calling process
+// has the requested access, and derives the certificate principals from the matching grants
+// terminateSession terminates a running session of this instance, on request of the sb owner with the specified uid
+func (c *Daemon) terminateSession(uid uint32, sessionID string) (err error) {
+
+	usr, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return fmt.Errorf("unknown user")
+	}
+
+	if uid != 0 {
+		account, err := models.LoadUser(usr.Username)
+		if err != nil {
+			return fmt.Errorf("unknown account %s", usr.Username)
+		}
+		if !account.IsOwnerOfGroup("owners") {
+			return fmt.Errorf("account %s is not a sb owner", usr.Username)
+		}
+	}
+
+	session, account, err := getRunningSession(sessionID)
+	if err != nil {
+		return
+	}
+
+	// Only the sb process of the session is signaled: it closes the connection to the distant host, and logs the end of the session
+	err = helpers.TerminateProcess(account.User.Uid, session.ProcessID)
+	if err != nil {
+		return errors.Wrapf(err, "unable to terminate session %s", sessionID)
+	}
+
+	fmt.Printf("Session %s of %s terminated on request of %s\n", sessionID, session.UserFrom, usr.Username)
+
+	return
+}
+
+func (c *Daemon) authorizeEgressCertificate(uid uint32, request *sshca.SignRequest) (keyID string, principals []string, portForwarding bool, err error) {
+
+	usr, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		err = fmt.Errorf("unknown user")
+		return
+	}
+
+	account, err := models.LoadUser(usr.Username)
+	if err != nil {
+		err = fmt.Errorf("unknown account %s", usr.Username)
+		return
+	}
+
+	ba, err := models.BuildSBAccessFromUserInput(request.Access)
+	if err != nil {
+		return
+	}
+
+	ai, err := account.HasAccess(ba)
+	if err != nil {
+		return
+	}
+	if !ai.Authorized {
+		err = fmt.Errorf("account %s can't access the host %s", account.User.Username, request.Access)
+		return
+	}
+
+	host := ba.Host
+	if host == "" && ba.IP != nil {
+		host = ba.IP.String()
+	}
+	if host == "" {
+		err = fmt.Errorf("unable to get the host of %s", request.Access)
+		return
+	}
+
+	groups := make([]string, 0, len(ai.Sources))
+	for _, source := range ai.Sources {
+		if source.Type == "group" {
+			groups = append(groups, source.Group)
+		}
+	}
+
+	// The certificate only permits port forwarding if one of the accesses allows it
+	for _, access := range ai.Accesses {
+		if access.ForwardingAllowed {
+			portForwarding = true
+		}
+	}
+
+	keyID = fmt.Sprintf("sb:%s:%s:%s", account.User.Username, request.SessionID, request.Access)
+	principals = sshca.Principals(ba.User, host, groups, config.GetEgressCAConfig().HostBoundPrincipals)
+
+	fmt.Printf("Signing egress certificate [%s] with principals %v (port forwarding: %t)\n", keyID, principals, portForwarding)
+
+	return
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/inpher/sb/internal/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayBufferedEntries(t *testing.T) {
+
+	db, err := models.GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+	c := &Daemon{hostname: "sb3", replicationDB: db, maxAttempts: 3}
+
+	now := time.Now()
+	require.NoError(t, models.MarkReplicationEntryApplied(db, &models.Replication{UniqID: "sb2-1", Instance: "sb2", Action: "account create"}))
+	for _, entry := range []*models.Replication{
+		{UniqID: "sb2-1", Instance: "sb2", Action: "account create", CreationDate: now},
+		{UniqID: "sb1-1", Instance: "sb1", Action: "group create", CreationDate: now.Add(-time.Minute)},
+		{UniqID: "sb2-2", Instance: "sb2", Action: "group create", CreationDate: now, Data: "not replication data"},
+	} {
+		require.NoError(t, models.BufferReplicationEntry(db, entry))
+	}
+
+	// Only the entry neither applied nor contained by the snapshot is applied, then put in the dead letters as it fails
+	replayed, total, err := c.replayBufferedEntries(&models.ReplicationWatermark{Instance: "sb1", SnapshotAt: now})
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+	require.Equal(t, 3, total)
+
+	buffered, err := models.GetBufferedReplicationEntries(db)
+	require.NoError(t, err)
+	require.Empty(t, buffered)
+
+	deadLetters, err := models.GetReplicationDeadLetters(db)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, "sb2-2", deadLetters[0].UniqID)
+
+	// Without a watermark, as after a join that failed, every entry not applied yet is applied
+	require.NoError(t, models.BufferReplicationEntry(db, &models.Replication{UniqID: "sb1-1", Instance: "sb1", Action: "group create", Data: "not replication data"}))
+	replayed, total, err = c.replayBufferedEntries(nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+	require.Equal(t, 1, total)
+}
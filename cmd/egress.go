@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/sshca"
+	"github.com/inpher/sb/internal/sshclient"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// egressCertificate describes the files of an ephemeral key pair signed by the egress CA
+type egressCertificate struct {
+	directory       string
+	privateKeyFile  string
+	certificateFile string
+}
+
+// requestEgressCertificate gets a certificate signed by the egress CA for a new ephemeral key pair
+func requestEgressCertificate(socketPath string, access *models.Access, sessionID string) (cert *ssh.Certificate, privateKey ed25519.PrivateKey, err error) {
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		err = errors.Wrap(err, "unable to generate ephemeral key")
+		return
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		err = errors.Wrap(err, "unable to convert ephemeral key")
+		return
+	}
+
+	cert, err = sshca.RequestCertificate(socketPath, &sshca.SignRequest{
+		PublicKey: string(ssh.MarshalAuthorizedKey(sshPublicKey)),
+		Access:    fmt.Sprintf("%s@%s:%d", access.User, access.Host, access.Port),
+		SessionID: sessionID,
+	})
+
+	return
+}
+
+// getEgressCertificate gets a certificate signed by the egress CA for an ephemeral key pair, and writes them for the ssh binary
+func getEgressCertificate(socketPath string, access *models.Access, sessionID string) (certificate *egressCertificate, err error) {
+
+	cert, privateKey, err := requestEgressCertificate(socketPath, access, sessionID)
+	if err != nil {
+		return
+	}
+
+	pemBlock, err := helpers.MarshalPrivateKey(privateKey, sessionID)
+	if err != nil {
+		err = errors.Wrap(err, "unable to marshal ephemeral key")
+		return
+	}
+
+	directory, err := os.MkdirTemp("", "sb-egress-")
+	if err != nil {
+		err = errors.Wrap(err, "unable to create ephemeral key directory")
+		return
+	}
+
+	certificate = &egressCertificate{
+		directory:       directory,
+		privateKeyFile:  filepath.Join(directory, "id_ed25519"),
+		certificateFile: filepath.Join(directory, "id_ed25519-cert.pub"),
+	}
+
+	err = os.WriteFile(certificate.privateKeyFile, pem.EncodeToMemory(pemBlock), 0600)
+	if err == nil {
+		err = os.WriteFile(certificate.certificateFile, ssh.MarshalAuthorizedKey(cert), 0600)
+	}
+	if err != nil {
+		os.RemoveAll(directory)
+		err = errors.Wrap(err, "unable to write ephemeral key")
+		return nil, err
+	}
+
+	return
+}
+
+// getEgressSigners returns the signers to authenticate on the distant host with: the certificate of
+// the egress CA first (when enabled), then the egress keys granting the access
+func getEgressSigners(ct *commands.Context, access *models.Access) (signers []ssh.Signer, err error) {
+
+	egressCAConfig := config.GetEgressCAConfig()
+	if egressCAConfig.Enabled {
+		cert, privateKey, errCertificate := requestEgressCertificate(egressCAConfig.Socket, access, ct.Log.UniqID)
+		if errCertificate == nil {
+			var signer, certSigner ssh.Signer
+			signer, errCertificate = ssh.NewSignerFromKey(privateKey)
+			if errCertificate == nil {
+				certSigner, errCertificate = ssh.NewCertSigner(cert, signer)
+			}
+			if errCertificate == nil {
+				signers = append(signers, certSigner)
+			}
+		}
+		if errCertificate != nil {
+			if len(ct.AI.KeyFilepathes) == 0 {
+				return nil, errCertificate
+			}
+			fmt.Fprintf(os.Stderr, "Unable to get a certificate, falling back to the egress keys: %s\n", errCertificate)
+		}
+	}
+
+	keySigners, errs := sshclient.LoadSigners(ct.AI.KeyFilepathes)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Ignoring egress key: %s\n", err)
+	}
+	signers = append(signers, keySigners...)
+
+	if len(signers) == 0 {
+		err = fmt.Errorf("no usable egress key or certificate to connect to the distant host")
+	}
+
+	return
+}
+
+// newEgressClient connects to the distant host with the native SSH client
+func newEgressClient(ct *commands.Context, access *models.Access, forwardAgent, interactive bool) (client *sshclient.Client, err error) {
+
+	signers, err := getEgressSigners(ct, access)
+	if err != nil {
+		return
+	}
+
+	env := make(map[string]string)
+	for _, envVar := range config.GetEnvironmentVarsToForward() {
+		env[fmt.Sprintf("LC_SB_%s", strings.ToUpper(envVar))] = os.Getenv(envVar)
+	}
+
+	var prompt sshclient.HostKeyPrompt
+	if interactive {
+		prompt = promptHostKey
+	}
+
+	return sshclient.Dial(&sshclient.Config{
+		Host:           access.Host,
+		Port:           access.Port,
+		User:           access.User,
+		Signers:        signers,
+		KnownHostsFile: ct.User.GetKnownHostsFilepath(),
+		HostKeyPrompt:  prompt,
+		ForwardAgent:   forwardAgent,
+		Env:            env,
+	})
+}
+
+// promptHostKey asks the user whether to trust an unknown host key, like ssh does
+func promptHostKey(hostname string, key ssh.PublicKey) bool {
+
+	fmt.Printf("The authenticity of host '%s' can't be established.\n", hostname)
+	fmt.Printf("%s key fingerprint is %s.\n", key.Type(), ssh.FingerprintSHA256(key))
+
+	for {
+		fmt.Print("Are you sure you want to continue connecting (yes/no)? ")
+		answer, err := readLine(os.Stdin)
+		if err != nil {
+			return false
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "yes":
+			return true
+		case "no":
+			return false
+		}
+	}
+}
+
+// readLine reads a line one byte at a time, so that nothing past the line is read: what follows is the input of the session
+func readLine(r io.Reader) (string, error) {
+
+	var buf []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if err != nil {
+			if err == io.EOF && len(buf) > 0 {
+				return string(buf), nil
+			}
+			return "", err
+		}
+		if n == 0 {
+			continue
+		}
+		if b[0] == '\n' {
+			return string(buf), nil
+		}
+		buf = append(buf, b[0])
+	}
+}
+
+// lockedWriter serializes the writes of concurrent streams, to keep the ttyrec frames whole
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
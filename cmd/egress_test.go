@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLine(t *testing.T) {
+
+	input := strings.NewReader("yes\nls -l\n")
+
+	line, err := readLine(input)
+	require.NoError(t, err)
+	require.Equal(t, "yes", line)
+
+	// The input following the line is left to the session
+	rest, err := io.ReadAll(input)
+	require.NoError(t, err)
+	require.Equal(t, "ls -l\n", string(rest))
+
+	line, err = readLine(strings.NewReader("no"))
+	require.NoError(t, err)
+	require.Equal(t, "no", line)
+
+	_, err = readLine(strings.NewReader(""))
+	require.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/pkg/errors"
+)
+
+// Forward describes the forward command
+type Forward struct {
+	duration time.Duration
+	accesses []*models.Access
+}
+
+func init() {
+	commands.RegisterCommand("forward", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(Forward), models.HasAccess, helpers.Helper{
+				Header: "forward a port of a distant service through sb, for a limited time",
+				Usage:  "forward --access HOST --local-port PORT --remote HOST:PORT [--duration DURATION]",
+				Description: fmt.Sprintf(`This command forwards a service reachable from a distant host (e.g. a web UI or a database),
+             without opening a shell on the distant host. The access must allow port forwarding.
+             The forwarded service is exposed on a unix socket on sb, to be reached with your own ssh client:
+                 ssh -L PORT:/home/ACCOUNT/forwards/PORT.sock ACCOUNT@%s -p %s -- forward --access HOST --local-port PORT --remote HOST:PORT
+             And voila, the service is reachable on localhost:PORT until the duration expires.
+             (The sshd of sb must allow it with AllowStreamLocalForwarding, which the setup sets to local.)`,
+					config.GetSBHostname(), config.GetSSHPort()),
+			}, map[string]commands.Argument{
+				"access": {
+					Required:    true,
+					Description: "The IP, host or alias of the distant host to forward through",
+				},
+				"local-port": {
+					Required:    true,
+					Description: "The port you will reach the service on, on your side",
+				},
+				"remote": {
+					Required:    true,
+					Description: "The service to forward, as seen from the distant host (e.g. 127.0.0.1:5432)",
+				},
+				"duration": {
+					Required:     false,
+					Description:  "How long the forwarding lasts (e.g. 30m, 2h)",
+					DefaultValue: "1h",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *Forward) Checks(ct *commands.Context) (err error) {
+
+	port, err := strconv.Atoi(ct.FormattedArguments["local-port"])
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("argument local-port should be a valid port")
+	}
+
+	_, remotePort, err := net.SplitHostPort(ct.FormattedArguments["remote"])
+	if err != nil {
+		return fmt.Errorf("argument remote should be HOST:PORT")
+	}
+	port, err = strconv.Atoi(remotePort)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("argument remote should be HOST:PORT")
+	}
+
+	c.duration, err = helpers.ParseDuration(ct.FormattedArguments["duration"])
+	if err != nil || c.duration <= 0 {
+		return fmt.Errorf("argument duration should be a positive duration (e.g. 30m, 2h)")
+	}
+	if maxDuration := config.GetForwardingMaxDuration(); c.duration > maxDuration {
+		return fmt.Errorf("argument duration can't exceed %s", maxDuration)
+	}
+
+	// Only the accesses allowing port forwarding can be used
+	for _, access := range ct.AI.Accesses {
+		if access.ForwardingAllowed {
+			c.accesses = append(c.accesses, access)
+		}
+	}
+	if len(c.accesses) == 0 {
+		return fmt.Errorf("none of your accesses to %s allows port forwarding", ct.BA.ShortString())
+	}
+
+	return nil
+}
+
+// Execute executes the command
+func (c *Forward) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	access, err := new(Ttyrec).getUniqueAccessFromAvailableAccesses(c.accesses, ct.BA.Host)
+	if err != nil {
+		return
+	}
+	ct.Log.SetTargetAccess(access)
+
+	// The socket lives in a directory only the account can access
+	forwardsDirectory := filepath.Join(ct.User.User.HomeDir, "forwards")
+	err = os.MkdirAll(forwardsDirectory, 0700)
+	if err != nil {
+		err = errors.Wrap(err, "unable to create the forwards directory")
+		return
+	}
+	err = os.Chmod(forwardsDirectory, 0700)
+	if err != nil {
+		err = errors.Wrap(err, "unable to chmod the forwards directory")
+		return
+	}
+	socketPath := filepath.Join(forwardsDirectory, fmt.Sprintf("%s.sock", ct.FormattedArguments["local-port"]))
+
+	// The tunnel is torn down once the duration expires, or if the user interrupts it
+	ctx, cancel := context.WithTimeout(context.Background(), c.duration)
+	defer cancel()
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(interrupted)
+	go func() {
+		select {
+		case <-interrupted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	expiresAt := time.Now().Add(c.duration)
+	description := fmt.Sprintf("port forwarding of %s through %s:%d until %s", ct.FormattedArguments["remote"], access.Host, access.Port, expiresAt.Format(time.RFC3339))
+	ct.Log.Comment = fmt.Sprintf("Started %s", description)
+	ct.Log.Save()
+
+	started := func() {
+		fmt.Printf("Started %s\n", description)
+		fmt.Printf("The service is reachable on your side with: ssh -L %s:%s ...\n", ct.FormattedArguments["local-port"], socketPath)
+		fmt.Printf("Press Ctrl-C to stop the forwarding.\n")
+	}
+
+	if config.GetSSHClient() == "native" {
+		cmdError, err = c.forwardNative(ctx, ct, access, socketPath, started)
+	} else {
+		cmdError, err = c.forwardSystem(ctx, ct, access, socketPath, started)
+	}
+
+	reason := "stopped"
+	if ctx.Err() == context.DeadlineExceeded {
+		reason = "expired"
+	}
+	ct.Log.Comment = fmt.Sprintf("Started %s, %s on %s", description, reason, time.Now().Format(time.RFC3339))
+	ct.Log.SessionEndDate = time.Now()
+	ct.Log.Save()
+
+	os.Remove(socketPath)
+
+	fmt.Printf("Port forwarding %s\n", reason)
+
+	return
+}
+
+// forwardNative forwards the connections to the socket with the native SSH client, until the context is done
+func (c *Forward) forwardNative(ctx context.Context, ct *commands.Context, access *models.Access, socketPath string, started func()) (cmdError error, err error) {
+
+	client, err := newEgressClient(ct, access, false, true)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	// A previous forwarding might have left its socket behind
+	os.Remove(socketPath)
+
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", socketPath)
+	syscall.Umask(oldMask)
+	if err != nil {
+		err = errors.Wrap(err, "unable to listen on the forwarding socket")
+		return
+	}
+	defer listener.Close()
+
+	started()
+
+	done := make(chan error, 2)
+	go func() {
+		done <- client.Forward(listener, ct.FormattedArguments["remote"])
+	}()
+	go func() {
+		done <- client.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+	case errDone := <-done:
+		// Being cancelled on expiry or interruption is the expected way to end
+		if ctx.Err() == nil {
+			cmdError = errors.Wrap(errDone, "the port forwarding failed")
+		}
+	}
+
+	return
+}
+
+// forwardSystem forwards the connections to the socket with the ssh binary, until the context is done
+func (c *Forward) forwardSystem(ctx context.Context, ct *commands.Context, access *models.Access, socketPath string, started func()) (cmdError error, err error) {
+
+	keyfilePathes := ct.AI.KeyFilepathes
+	egressCAConfig := config.GetEgressCAConfig()
+	if egressCAConfig.Enabled {
+		certificate, errCertificate := getEgressCertificate(egressCAConfig.Socket, access, ct.Log.UniqID)
+		if errCertificate != nil {
+			if len(keyfilePathes) == 0 {
+				return cmdError, errCertificate
+			}
+			fmt.Fprintf(os.Stderr, "Unable to get a certificate, falling back to the egress keys: %s\n", errCertificate)
+		} else {
+			defer os.RemoveAll(certificate.directory)
+			keyfilePathes = append([]string{certificate.privateKeyFile}, keyfilePathes...)
+		}
+	}
+
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		fmt.Printf("Unable to find ssh on system: %s\n", err)
+		return
+	}
+
+	command := []string{
+		sshPath,
+		"-N",
+		"-x",
+		"-oForwardAgent=no",
+		"-oPermitLocalCommand=no",
+		"-oExitOnForwardFailure=yes",
+		"-oStreamLocalBindMask=0177",
+		"-oStreamLocalBindUnlink=yes",
+		"-L", fmt.Sprintf("%s:%s", socketPath, ct.FormattedArguments["remote"]),
+		"-p", strconv.Itoa(access.Port),
+		"-l", access.User,
+	}
+	for _, privateKeyFile := range keyfilePathes {
+		command = append(command, "-i", privateKeyFile)
+	}
+	command = append(command, "--", access.Host)
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Start()
+	if err != nil {
+		err = errors.Wrap(err, "unable to start the port forwarding")
+		return
+	}
+
+	started()
+
+	err = cmd.Wait()
+
+	// Being killed on expiry or interruption is the expected way to end
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+	if err != nil {
+		var ok bool
+		cmdError, ok = err.(*exec.ExitError)
+		if !ok {
+			err = errors.Wrap(err, "unable to wait for the port forwarding")
+			return
+		}
+		err = nil
+		cmdError = errors.Wrap(cmdError, "the port forwarding failed")
+	}
+
+	return
+}
+
+func (c *Forward) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *Forward) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
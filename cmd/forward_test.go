@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardChecks(t *testing.T) {
+
+	newContext := func(arguments map[string]string, accesses ...*models.Access) *commands.Context {
+		formattedArguments := map[string]string{
+			"access":     "10.0.0.1",
+			"local-port": "8080",
+			"remote":     "127.0.0.1:5432",
+			"duration":   "1h",
+		}
+		for name, value := range arguments {
+			formattedArguments[name] = value
+		}
+		return &commands.Context{
+			FormattedArguments: formattedArguments,
+			BA:                 &models.Access{Host: "10.0.0.1", User: "root", Port: 22},
+			AI:                 &models.Info{Authorized: true, Accesses: accesses},
+		}
+	}
+
+	denied := &models.Access{Host: "10.0.0.1", User: "root", Port: 22}
+	allowed := &models.Access{Host: "10.0.0.1", User: "root", Port: 22, ForwardingAllowed: true}
+
+	// Port forwarding is refused when none of the accesses allows it
+	err := new(Forward).Checks(newContext(nil, denied))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "allows port forwarding")
+
+	// Only the accesses allowing it are used for port forwarding
+	c := new(Forward)
+	require.NoError(t, c.Checks(newContext(nil, denied, allowed)))
+	require.Equal(t, []*models.Access{allowed}, c.accesses)
+
+	for _, arguments := range []map[string]string{
+		{"local-port": "0"},
+		{"local-port": "http"},
+		{"remote": "127.0.0.1"},
+		{"remote": "127.0.0.1:70000"},
+		{"duration": "-1h"},
+		{"duration": "8760h"},
+	} {
+		require.Error(t, new(Forward).Checks(newContext(arguments, allowed)), arguments)
+	}
+}
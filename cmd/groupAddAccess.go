@@ -17,7 +17,7 @@ func init() {
 	commands.RegisterCommand("group access add", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
 		return new(GroupAddAccess), models.GroupACLKeeper, helpers.Helper{
 				Header:      "add a group access to a distant host",
-				Usage:       "group access add --group GROUP-NAME --host HOST --user USER [--port PORT --alias ALIAS]",
+				Usage:       "group access add --group GROUP-NAME --host HOST | --selector SELECTOR --user USER [--port PORT --alias ALIAS --expires-in DURATION | --expires-at DATE --forwarding-allowed]",
 				Description: "add a group access to a distant host",
 				Aliases:     []string{"groupAddAccess"},
 			}, map[string]commands.Argument{
@@ -26,9 +26,13 @@ func init() {
 					Description: "The group name you want to add an access for",
 				},
 				"host": {
-					Required:    true,
+					Required:    false,
 					Description: "An IP, IP range or hostname you're granting access to",
 				},
+				"selector": {
+					Required:    false,
+					Description: "A label selector like 'env=staging AND team=db': instead of a host, you're granting access to all the hosts of the inventory carrying these tags",
+				},
 				"user": {
 					Required:    true,
 					Description: "The user you're granting access to",
@@ -42,6 +46,19 @@ func init() {
 					Required:    false,
 					Description: "An optional alias to this access (to enable quick access by typing 'sb alias' or 'sb user@alias')",
 				},
+				"expires-in": {
+					Required:    false,
+					Description: "An optional duration after which the access expires (e.g. 12h, 14d, 2w)",
+				},
+				"expires-at": {
+					Required:    false,
+					Description: "An optional date at which the access expires, in RFC3339 format (e.g. 2006-01-02T15:04:05Z)",
+				},
+				"forwarding-allowed": {
+					Required:    false,
+					Description: "Allow this access to be used for port forwarding (see the forward command)",
+					Type:        commands.BOOL,
+				},
 			}
 	})
 }
@@ -49,6 +66,19 @@ func init() {
 // Checks checks whether or not the user can execute this method
 func (c *GroupAddAccess) Checks(ct *commands.Context) error {
 
+	if (ct.FormattedArguments["host"] == "") == (ct.FormattedArguments["selector"] == "") {
+		return fmt.Errorf("either a host or a selector should be provided")
+	}
+
+	if ct.FormattedArguments["selector"] != "" {
+		if ct.FormattedArguments["alias"] != "" {
+			return fmt.Errorf("you cannot add an alias to a selector access")
+		}
+		if _, err := models.NormalizeSelector(ct.FormattedArguments["selector"]); err != nil {
+			return err
+		}
+	}
+
 	for commandName := range commands.GetCommands() {
 		if strings.EqualFold(ct.FormattedArguments["alias"], commandName) ||
 			strings.EqualFold(ct.FormattedArguments["host"], commandName) {
@@ -56,6 +86,11 @@ func (c *GroupAddAccess) Checks(ct *commands.Context) error {
 		}
 	}
 
+	_, err := helpers.ParseExpiration(ct.FormattedArguments["expires-in"], ct.FormattedArguments["expires-at"], time.Now())
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -67,12 +102,23 @@ func (c *GroupAddAccess) PostExecute(repl models.ReplicationData) (err error) {
 func (c *GroupAddAccess) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
 
 	repl = models.ReplicationData{
-		"group":   ct.Group.Name,
-		"host":    ct.FormattedArguments["host"],
-		"user":    ct.FormattedArguments["user"],
-		"port":    ct.FormattedArguments["port"],
-		"alias":   ct.FormattedArguments["alias"],
-		"comment": fmt.Sprintf("Access granted by %s on %s", ct.User.User.Username, time.Now().Format(time.RFC3339)),
+		"group":              ct.Group.Name,
+		"host":               ct.FormattedArguments["host"],
+		"selector":           ct.FormattedArguments["selector"],
+		"user":               ct.FormattedArguments["user"],
+		"port":               ct.FormattedArguments["port"],
+		"alias":              ct.FormattedArguments["alias"],
+		"comment":            fmt.Sprintf("Access granted by %s on %s", ct.User.User.Username, time.Now().Format(time.RFC3339)),
+		"forwarding-allowed": ct.FormattedArguments["forwarding-allowed"],
+	}
+
+	// We store an absolute expiration date, so that all instances expire the access at the same time
+	expiresAt, err := helpers.ParseExpiration(ct.FormattedArguments["expires-in"], ct.FormattedArguments["expires-at"], time.Now())
+	if err != nil {
+		return
+	}
+	if !expiresAt.IsZero() {
+		repl["expires-at"] = expiresAt.Format(time.RFC3339)
 	}
 
 	err = c.Replicate(repl)
@@ -80,6 +126,20 @@ func (c *GroupAddAccess) Execute(ct *commands.Context) (repl models.ReplicationD
 	return
 }
 
+// ObjectKey returns the access of the group, which "group access add" and "group access remove" act on
+func (c *GroupAddAccess) ObjectKey(repl models.ReplicationData) string {
+	return groupAccessObjectKey(repl)
+}
+
+// groupAccessObjectKey returns the key of a group access, granted to a host or with a label selector
+func groupAccessObjectKey(repl models.ReplicationData) string {
+	if repl["selector"] != "" {
+		selector, _ := models.NormalizeSelector(repl["selector"])
+		return models.ObjectKey("group access", repl["group"], repl["user"], fmt.Sprintf("[%s]", selector), repl["port"])
+	}
+	return models.AccessObjectKey("group access", repl["group"], repl["host"], repl["user"], repl["port"])
+}
+
 func (c *GroupAddAccess) Replicate(repl models.ReplicationData) (err error) {
 
 	grp, err := models.GetGroup(repl["group"])
@@ -87,13 +147,36 @@ func (c *GroupAddAccess) Replicate(repl models.ReplicationData) (err error) {
 		return
 	}
 
-	ba, err := grp.AddAccess(
-		repl["host"],
-		repl["user"],
-		repl["port"],
-		repl["alias"],
-		repl["comment"],
-	)
+	// Entries replicated from older instances don't carry an expiration date
+	var expiresAt time.Time
+	if repl["expires-at"] != "" {
+		expiresAt, err = time.Parse(time.RFC3339, repl["expires-at"])
+		if err != nil {
+			return
+		}
+	}
+
+	var ba *models.Access
+	if repl["selector"] != "" {
+		ba, err = grp.AddSelectorAccess(
+			repl["selector"],
+			repl["user"],
+			repl["port"],
+			repl["comment"],
+			expiresAt,
+			repl["forwarding-allowed"] == "true",
+		)
+	} else {
+		ba, err = grp.AddAccess(
+			repl["host"],
+			repl["user"],
+			repl["port"],
+			repl["alias"],
+			repl["comment"],
+			expiresAt,
+			repl["forwarding-allowed"] == "true",
+		)
+	}
 	if err != nil {
 		return
 	}
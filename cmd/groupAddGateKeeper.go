@@ -63,6 +63,11 @@ func (c *GroupAddGateKeeper) PostExecute(repl models.ReplicationData) (err error
 	return
 }
 
+// ObjectKey returns the gate-keeper role of the account in the group, which "group gate-keeper add" and "group gate-keeper remove" act on
+func (c *GroupAddGateKeeper) ObjectKey(repl models.ReplicationData) string {
+	return models.ObjectKey("group gate-keeper", repl["group"], repl["account"])
+}
+
 func (c *GroupAddGateKeeper) Replicate(repl models.ReplicationData) (err error) {
 
 	err = helpers.AddAccountInGroup(repl["group"], repl["account"], "gk")
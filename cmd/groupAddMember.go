@@ -63,6 +63,11 @@ func (c *GroupAddMember) PostExecute(repl models.ReplicationData) (err error) {
 	return
 }
 
+// ObjectKey returns the member role of the account in the group, which "group member add" and "group member remove" act on
+func (c *GroupAddMember) ObjectKey(repl models.ReplicationData) string {
+	return models.ObjectKey("group member", repl["group"], repl["account"])
+}
+
 func (c *GroupAddMember) Replicate(repl models.ReplicationData) (err error) {
 
 	err = helpers.AddAccountInGroup(repl["group"], repl["account"], "m")
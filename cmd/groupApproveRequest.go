@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// GroupApproveRequest describes the command
+type GroupApproveRequest struct {
+	request *models.Request
+}
+
+func init() {
+	commands.RegisterCommand("group request approve", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(GroupApproveRequest), models.Public, helpers.Helper{
+				Header:      "approve a request made to a group",
+				Usage:       "group request approve --group GROUP --request-id REQUEST-ID [--comment COMMENT]",
+				Description: "approve an access request (for ACL keepers) or a membership request (for gate keepers) made to a group",
+				Aliases:     []string{"groupApproveRequest"},
+			}, map[string]commands.Argument{
+				"group": {
+					Required:    true,
+					Description: "The group the request was made to",
+				},
+				"request-id": {
+					Required:    true,
+					Description: "The ID of the request to approve",
+				},
+				"comment": {
+					Required:    false,
+					Description: "An optional comment on your decision",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *GroupApproveRequest) Checks(ct *commands.Context) (err error) {
+
+	c.request, err = getPendingRequest(ct.Group.Name, ct.FormattedArguments["request-id"])
+	if err != nil {
+		return
+	}
+
+	// A request that can't run the command of its type was not made by sb
+	if _, err = c.request.GetCommand(); err != nil {
+		return
+	}
+
+	if !canDecideRequest(ct.User, ct.Group.Name, c.request.Type) {
+		switch c.request.Type {
+		case models.RequestTypeAccess:
+			return fmt.Errorf("user is not an ACL keeper of the group")
+		default:
+			return fmt.Errorf("user is not a gate keeper of the group")
+		}
+	}
+
+	if c.request.Type == models.RequestTypeMember {
+		requester, err := models.LoadUser(c.request.Requester)
+		if err != nil {
+			return fmt.Errorf("account %s doesn't exist anymore", c.request.Requester)
+		}
+		if requester.IsMemberOfGroup(ct.Group.Name) {
+			return fmt.Errorf("account %s is already a group member", c.request.Requester)
+		}
+	}
+
+	return
+}
+
+// Execute executes the command
+func (c *GroupApproveRequest) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	data, err := c.request.GetData()
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	// Access requests carry the arguments of the requester: we build the ones the command would have
+	if c.request.Type == models.RequestTypeAccess {
+		var expiresAt time.Time
+		expiresAt, err = helpers.ParseExpiration(data["expires-in"], data["expires-at"], now)
+		if err != nil {
+			return
+		}
+		delete(data, "expires-in")
+		delete(data, "expires-at")
+		if !expiresAt.IsZero() {
+			data["expires-at"] = expiresAt.Format(time.RFC3339)
+		}
+		data["comment"] = fmt.Sprintf("Access requested by %s, granted by %s on %s", c.request.Requester, ct.User.User.Username, now.Format(time.RFC3339))
+	}
+
+	repl, err = decisionToReplicationData(c.request, models.RequestStatusApproved, ct.User.User.Username, ct.FormattedArguments["comment"], data)
+	if err != nil {
+		return
+	}
+
+	err = c.Replicate(repl)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Request %s of %s was approved\n", c.request.UniqID, c.request.Requester)
+
+	return
+}
+
+func (c *GroupApproveRequest) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+// ObjectKey returns the object the approved command acts on, so that the approval is versioned like the command
+func (c *GroupApproveRequest) ObjectKey(repl models.ReplicationData) string {
+	return approvedRequestObjectKey(repl)
+}
+
+func (c *GroupApproveRequest) Replicate(repl models.ReplicationData) (err error) {
+	return applyDecision(repl)
+}
@@ -63,6 +63,11 @@ func (c *GroupDelACLKeeper) PostExecute(repl models.ReplicationData) (err error)
 	return
 }
 
+// ObjectKey returns the acl-keeper role of the account in the group, which "group acl-keeper add" and "group acl-keeper remove" act on
+func (c *GroupDelACLKeeper) ObjectKey(repl models.ReplicationData) string {
+	return models.ObjectKey("group acl-keeper", repl["group"], repl["account"])
+}
+
 func (c *GroupDelACLKeeper) Replicate(repl models.ReplicationData) (err error) {
 
 	err = helpers.RemoveAccountFromGroup(repl["group"], repl["account"], "aclk")
@@ -15,7 +15,7 @@ func init() {
 	commands.RegisterCommand("group access remove", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
 		return new(GroupDelAccess), models.GroupACLKeeper, helpers.Helper{
 				Header:      "remove a group access to a distant host",
-				Usage:       "group access delete --group GROUP-NAME --host HOST --user USER --port PORT",
+				Usage:       "group access delete --group GROUP-NAME --host HOST | --selector SELECTOR --user USER --port PORT",
 				Description: "remove a group access to a distant host",
 				Aliases:     []string{"groupDelAccess"},
 			}, map[string]commands.Argument{
@@ -24,9 +24,13 @@ func init() {
 					Description: "The group name you want to add an access for",
 				},
 				"host": {
-					Required:    true,
+					Required:    false,
 					Description: "An IP, IP range or hostname you're granting access to",
 				},
+				"selector": {
+					Required:    false,
+					Description: "The label selector you're granting access to",
+				},
 				"user": {
 					Required:    true,
 					Description: "The user you're granting access to",
@@ -41,6 +45,11 @@ func init() {
 
 // Checks checks whether or not the user can execute this method
 func (c *GroupDelAccess) Checks(ct *commands.Context) error {
+
+	if (ct.FormattedArguments["host"] == "") == (ct.FormattedArguments["selector"] == "") {
+		return fmt.Errorf("either a host or a selector should be provided")
+	}
+
 	return nil
 }
 
@@ -48,10 +57,11 @@ func (c *GroupDelAccess) Checks(ct *commands.Context) error {
 func (c *GroupDelAccess) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
 
 	repl = models.ReplicationData{
-		"group": ct.Group.Name,
-		"host":  ct.FormattedArguments["host"],
-		"user":  ct.FormattedArguments["user"],
-		"port":  ct.FormattedArguments["port"],
+		"group":    ct.Group.Name,
+		"host":     ct.FormattedArguments["host"],
+		"selector": ct.FormattedArguments["selector"],
+		"user":     ct.FormattedArguments["user"],
+		"port":     ct.FormattedArguments["port"],
 	}
 
 	err = c.Replicate(repl)
@@ -63,6 +73,11 @@ func (c *GroupDelAccess) PostExecute(repl models.ReplicationData) (err error) {
 	return
 }
 
+// ObjectKey returns the access of the group, which "group access add" and "group access remove" act on
+func (c *GroupDelAccess) ObjectKey(repl models.ReplicationData) string {
+	return groupAccessObjectKey(repl)
+}
+
 func (c *GroupDelAccess) Replicate(repl models.ReplicationData) (err error) {
 
 	group, err := models.GetGroup(repl["group"])
@@ -70,11 +85,20 @@ func (c *GroupDelAccess) Replicate(repl models.ReplicationData) (err error) {
 		return
 	}
 
-	ba, err := group.DeleteAccess(
-		repl["host"],
-		repl["user"],
-		repl["port"],
-	)
+	var ba *models.Access
+	if repl["selector"] != "" {
+		ba, err = group.DeleteSelectorAccess(
+			repl["selector"],
+			repl["user"],
+			repl["port"],
+		)
+	} else {
+		ba, err = group.DeleteAccess(
+			repl["host"],
+			repl["user"],
+			repl["port"],
+		)
+	}
 	if err != nil {
 		return
 	}
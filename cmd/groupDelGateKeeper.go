@@ -63,6 +63,11 @@ func (c *GroupDelGateKeeper) PostExecute(repl models.ReplicationData) (err error
 	return
 }
 
+// ObjectKey returns the gate-keeper role of the account in the group, which "group gate-keeper add" and "group gate-keeper remove" act on
+func (c *GroupDelGateKeeper) ObjectKey(repl models.ReplicationData) string {
+	return models.ObjectKey("group gate-keeper", repl["group"], repl["account"])
+}
+
 func (c *GroupDelGateKeeper) Replicate(repl models.ReplicationData) (err error) {
 
 	err = helpers.RemoveAccountFromGroup(repl["group"], repl["account"], "gk")
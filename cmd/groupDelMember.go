@@ -63,6 +63,11 @@ func (c *GroupDelMember) PostExecute(repl models.ReplicationData) (err error) {
 	return
 }
 
+// ObjectKey returns the member role of the account in the group, which "group member add" and "group member remove" act on
+func (c *GroupDelMember) ObjectKey(repl models.ReplicationData) string {
+	return models.ObjectKey("group member", repl["group"], repl["account"])
+}
+
 func (c *GroupDelMember) Replicate(repl models.ReplicationData) (err error) {
 
 	err = helpers.RemoveAccountFromGroup(repl["group"], repl["account"], "m")
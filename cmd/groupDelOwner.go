@@ -63,6 +63,11 @@ func (c *GroupDelOwner) PostExecute(repl models.ReplicationData) (err error) {
 	return
 }
 
+// ObjectKey returns the owner role of the account in the group, which "group owner add" and "group owner remove" act on
+func (c *GroupDelOwner) ObjectKey(repl models.ReplicationData) string {
+	return models.ObjectKey("group owner", repl["group"], repl["account"])
+}
+
 func (c *GroupDelOwner) Replicate(repl models.ReplicationData) (err error) {
 
 	err = helpers.RemoveAccountFromGroup(repl["group"], repl["account"], "o")
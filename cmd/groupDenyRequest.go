@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// GroupDenyRequest describes the command
+type GroupDenyRequest struct {
+	request *models.Request
+}
+
+func init() {
+	commands.RegisterCommand("group request deny", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(GroupDenyRequest), models.Public, helpers.Helper{
+				Header:      "deny a request made to a group",
+				Usage:       "group request deny --group GROUP --request-id REQUEST-ID [--comment COMMENT]",
+				Description: "deny an access request (for ACL keepers) or a membership request (for gate keepers) made to a group",
+				Aliases:     []string{"groupDenyRequest"},
+			}, map[string]commands.Argument{
+				"group": {
+					Required:    true,
+					Description: "The group the request was made to",
+				},
+				"request-id": {
+					Required:    true,
+					Description: "The ID of the request to deny",
+				},
+				"comment": {
+					Required:    false,
+					Description: "An optional comment on your decision",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *GroupDenyRequest) Checks(ct *commands.Context) (err error) {
+
+	c.request, err = getPendingRequest(ct.Group.Name, ct.FormattedArguments["request-id"])
+	if err != nil {
+		return
+	}
+
+	if !canDecideRequest(ct.User, ct.Group.Name, c.request.Type) {
+		switch c.request.Type {
+		case models.RequestTypeAccess:
+			return fmt.Errorf("user is not an ACL keeper of the group")
+		default:
+			return fmt.Errorf("user is not a gate keeper of the group")
+		}
+	}
+
+	return
+}
+
+// Execute executes the command
+func (c *GroupDenyRequest) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	repl, err = decisionToReplicationData(c.request, models.RequestStatusDenied, ct.User.User.Username, ct.FormattedArguments["comment"], nil)
+	if err != nil {
+		return
+	}
+
+	err = c.Replicate(repl)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Request %s of %s was denied\n", c.request.UniqID, c.request.Requester)
+
+	return
+}
+
+func (c *GroupDenyRequest) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *GroupDenyRequest) Replicate(repl models.ReplicationData) (err error) {
+	return applyDecision(repl)
+}
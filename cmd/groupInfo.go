@@ -35,31 +35,81 @@ func (c *GroupInfo) Checks(ct *commands.Context) error {
 
 // Execute executes the command
 func (c *GroupInfo) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// groupInfoResult describes the result of the command.
+// The ACL keepers, members and egress keys are only provided to the group's members and ACL keepers.
+type groupInfoResult struct {
+	Group       string   `json:"group" yaml:"group"`
+	Owners      []string `json:"owners" yaml:"owners"`
+	GateKeepers []string `json:"gate_keepers" yaml:"gate_keepers"`
+	ACLKeepers  []string `json:"acl_keepers,omitempty" yaml:"acl_keepers,omitempty"`
+	Members     []string `json:"members,omitempty" yaml:"members,omitempty"`
+	EgressKeys  []string `json:"egress_keys,omitempty" yaml:"egress_keys,omitempty"`
+
+	isMember      bool
+	strEgressKeys string
+	membersByType map[string][]string
+	toFetch       []string
+}
 
-	fmt.Printf("Here are the info of group %s:\n", ct.Group.Name)
+// Result returns the members and, for the group's members, the egress keys of the group
+func (c *GroupInfo) Result(ct *commands.Context) (result commands.Result, err error) {
 
-	toFetch := []string{"owner", "gate-keeper"}
-	if ct.User.IsMemberOfGroup(ct.Group.Name) || ct.User.IsACLKeeperOfGroup(ct.Group.Name) {
-		toFetch = append(toFetch, "acl-keeper", "member")
+	res := &groupInfoResult{
+		Group:         ct.Group.Name,
+		isMember:      ct.User.IsMemberOfGroup(ct.Group.Name) || ct.User.IsACLKeeperOfGroup(ct.Group.Name),
+		membersByType: make(map[string][]string),
+		toFetch:       []string{"owner", "gate-keeper"},
+	}
+	if res.isMember {
+		res.toFetch = append(res.toFetch, "acl-keeper", "member")
 	}
 
-	for _, memberType := range toFetch {
+	// A listing error is not fatal: it is reported in place of the list
+	for _, memberType := range res.toFetch {
 		list, err := ct.Group.GetMembers(memberType)
 		if err != nil {
+			continue
+		}
+		res.membersByType[memberType] = list
+	}
+	res.Owners = res.membersByType["owner"]
+	res.GateKeepers = res.membersByType["gate-keeper"]
+	res.ACLKeepers = res.membersByType["acl-keeper"]
+	res.Members = res.membersByType["member"]
+
+	if res.isMember {
+		var keys []helpers.PublicKey
+		res.strEgressKeys, keys, _ = ct.Group.DisplayPubKeys("egress")
+		for _, key := range keys {
+			res.EgressKeys = append(res.EgressKeys, key.String())
+		}
+	}
+
+	return res, nil
+}
+
+func (r *groupInfoResult) PrintTable() {
+
+	fmt.Printf("Here are the info of group %s:\n", r.Group)
+
+	for _, memberType := range r.toFetch {
+		list, ok := r.membersByType[memberType]
+		if !ok {
 			fmt.Printf("    - List of group %ss: [error while listing, please report]\n", memberType)
 		} else {
 			fmt.Printf("    - List of group %ss: %s\n", memberType, strings.Join(list, ", "))
 		}
 	}
 
-	if ct.User.IsMemberOfGroup(ct.Group.Name) || ct.User.IsACLKeeperOfGroup(ct.Group.Name) {
-		strPubKeys, _, _ := ct.Group.DisplayPubKeys("egress")
-		fmt.Printf("List of group's egress public SSH keys (sb -> distant host):\n%s\n", strPubKeys)
+	if r.isMember {
+		fmt.Printf("List of group's egress public SSH keys (sb -> distant host):\n%s\n", r.strEgressKeys)
 	} else {
 		fmt.Println("You're not a member of this group: ask the owners or gate-keepers if you think you should be added.")
 	}
-
-	return
 }
 
 func (c *GroupInfo) PostExecute(repl models.ReplicationData) (err error) {
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/inpher/sb/internal/commands"
 	"github.com/inpher/sb/internal/helpers"
@@ -36,11 +37,24 @@ func (c *GroupList) Checks(ct *commands.Context) error {
 
 // Execute executes the command
 func (c *GroupList) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// groupListResult describes the result of the command
+type groupListResult struct {
+	Groups []string `json:"groups" yaml:"groups"`
+
+	all bool
+}
+
+// Result returns the names of the groups, sorted alphabetically
+func (c *GroupList) Result(ct *commands.Context) (result commands.Result, err error) {
 
 	var groups map[string]*models.Group
 
-	_, ok := ct.FormattedArguments["all"]
-	if ok {
+	_, all := ct.FormattedArguments["all"]
+	if all {
 		groups, err = models.GetAllSBGroups()
 		if err != nil {
 			return
@@ -52,23 +66,36 @@ func (c *GroupList) Execute(ct *commands.Context) (repl models.ReplicationData,
 		}
 	}
 
-	if len(groups) == 0 {
-		if ok {
+	res := &groupListResult{
+		Groups: make([]string, 0, len(groups)),
+		all:    all,
+	}
+	for groupName := range groups {
+		res.Groups = append(res.Groups, groupName)
+	}
+	sort.Strings(res.Groups)
+
+	return res, nil
+}
+
+func (r *groupListResult) PrintTable() {
+
+	if len(r.Groups) == 0 {
+		if r.all {
 			fmt.Println("No group was created on this sb instance yet")
 		} else {
 			fmt.Println("You don't have access to any group yet. Use --all to list all sb groups")
 		}
-	} else {
-		fmt.Println("Here are the list of sb groups:")
-		for groupName := range groups {
-			fmt.Printf("%s\n", groupName)
-		}
-		if !ok {
-			fmt.Println("If you want to see all the groups, even the ones you don't have access to, use --all")
-		}
+		return
 	}
 
-	return
+	fmt.Println("Here are the list of sb groups:")
+	for _, groupName := range r.Groups {
+		fmt.Printf("%s\n", groupName)
+	}
+	if !r.all {
+		fmt.Println("If you want to see all the groups, even the ones you don't have access to, use --all")
+	}
 }
 
 func (c *GroupList) PostExecute(repl models.ReplicationData) (err error) {
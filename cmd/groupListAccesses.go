@@ -35,28 +35,51 @@ func (c *GroupListAccesses) Checks(ct *commands.Context) error {
 
 // Execute executes the command
 func (c *GroupListAccesses) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// groupAccessesResult describes the result of the command
+type groupAccessesResult struct {
+	Group    string          `json:"group" yaml:"group"`
+	Accesses []*accessResult `json:"accesses" yaml:"accesses"`
+}
+
+// Result returns the accesses of the group
+func (c *GroupListAccesses) Result(ct *commands.Context) (result commands.Result, err error) {
 
 	accesses, err := ct.Group.GetAccesses()
 	if err != nil {
 		return
 	}
 
-	allAccesses := make([]string, 0, len(accesses.Accesses))
+	res := &groupAccessesResult{
+		Group:    ct.Group.Name,
+		Accesses: make([]*accessResult, 0, len(accesses.Accesses)),
+	}
 	for _, host := range accesses.Accesses {
-		allAccesses = append(allAccesses, host.String())
+		res.Accesses = append(res.Accesses, newAccessResult(accesses.Type, accesses.Group, host))
+	}
+
+	return res, nil
+}
+
+func (r *groupAccessesResult) PrintTable() {
+
+	allAccesses := make([]string, 0, len(r.Accesses))
+	for _, ar := range r.Accesses {
+		allAccesses = append(allAccesses, ar.access.String())
 	}
 
 	if len(allAccesses) > 0 {
 		fmt.Printf(
 			"Here is the list of accessible distant hosts to the group %s:\n%s\n",
-			ct.Group.Name,
+			r.Group,
 			strings.Join(allAccesses, "\n"),
 		)
 	} else {
-		fmt.Printf("Group %s doesn't have any access to distant hosts yet\n", ct.Group.Name)
+		fmt.Printf("Group %s doesn't have any access to distant hosts yet\n", r.Group)
 	}
-
-	return
 }
 
 func (c *GroupListAccesses) PostExecute(repl models.ReplicationData) (err error) {
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// GroupListRequests describes the command
+type GroupListRequests struct{}
+
+func init() {
+	commands.RegisterCommand("group requests list", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(GroupListRequests), models.Public, helpers.Helper{
+				Header:      "list the requests made to a group",
+				Usage:       "group requests list --group GROUP [--all]",
+				Description: "list the pending access requests (for ACL keepers) and membership requests (for gate keepers) of a group",
+				Aliases:     []string{"groupListRequests"},
+			}, map[string]commands.Argument{
+				"group": {
+					Required:    true,
+					Description: "The group you want to list the requests of",
+				},
+				"all": {
+					Required:    false,
+					Description: "List all the requests, including the ones already approved or denied",
+					Type:        commands.BOOL,
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *GroupListRequests) Checks(ct *commands.Context) error {
+
+	if len(c.getRequestTypes(ct)) == 0 {
+		return fmt.Errorf("user is neither an ACL keeper nor a gate keeper of the group")
+	}
+
+	return nil
+}
+
+// Execute executes the command
+func (c *GroupListRequests) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+func (c *GroupListRequests) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *GroupListRequests) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
+
+// requestResult describes a request, as rendered by the command
+type requestResult struct {
+	ID              string            `json:"id" yaml:"id"`
+	Type            string            `json:"type" yaml:"type"`
+	Requester       string            `json:"requester" yaml:"requester"`
+	Reason          string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Data            map[string]string `json:"data" yaml:"data"`
+	Status          string            `json:"status" yaml:"status"`
+	CreationDate    time.Time         `json:"creation_date" yaml:"creation_date"`
+	DecidedBy       string            `json:"decided_by,omitempty" yaml:"decided_by,omitempty"`
+	DecisionDate    *time.Time        `json:"decision_date,omitempty" yaml:"decision_date,omitempty"`
+	DecisionComment string            `json:"decision_comment,omitempty" yaml:"decision_comment,omitempty"`
+}
+
+// groupRequestsResult describes the result of the command
+type groupRequestsResult struct {
+	Group    string           `json:"group" yaml:"group"`
+	Requests []*requestResult `json:"requests" yaml:"requests"`
+}
+
+// Result returns the requests the user can decide on
+func (c *GroupListRequests) Result(ct *commands.Context) (result commands.Result, err error) {
+
+	db, err := models.GetRequestsGormDB(config.GetRequestsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	status := models.RequestStatusPending
+	if _, ok := ct.FormattedArguments["all"]; ok {
+		status = ""
+	}
+
+	requests, err := models.GetRequests(db, ct.Group.Name, status, c.getRequestTypes(ct)...)
+	if err != nil {
+		return
+	}
+
+	res := &groupRequestsResult{
+		Group:    ct.Group.Name,
+		Requests: make([]*requestResult, 0, len(requests)),
+	}
+	for _, request := range requests {
+		data, err := request.GetData()
+		if err != nil {
+			return nil, err
+		}
+		rr := &requestResult{
+			ID:              request.UniqID,
+			Type:            request.Type,
+			Requester:       request.Requester,
+			Reason:          request.Reason,
+			Data:            data,
+			Status:          request.Status,
+			CreationDate:    request.CreationDate.UTC(),
+			DecidedBy:       request.DecidedBy,
+			DecisionComment: request.DecisionComment,
+		}
+		if !request.DecisionDate.IsZero() {
+			decisionDate := request.DecisionDate.UTC()
+			rr.DecisionDate = &decisionDate
+		}
+		res.Requests = append(res.Requests, rr)
+	}
+
+	return res, nil
+}
+
+func (r *groupRequestsResult) PrintTable() {
+
+	if len(r.Requests) == 0 {
+		fmt.Printf("There is no request for group %s\n", r.Group)
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Printf("Here is the list of the requests made to group %s:\n", r.Group)
+	for _, rr := range r.Requests {
+
+		var what string
+		switch rr.Type {
+		case models.RequestTypeAccess:
+			what = fmt.Sprintf("access to %s@%s:%s", rr.Data["user"], rr.Data["host"], rr.Data["port"])
+			if rr.Data["alias"] != "" {
+				what += fmt.Sprintf(" (%s)", rr.Data["alias"])
+			}
+			if rr.Data["expires-in"] != "" {
+				what += fmt.Sprintf(", expiring %s after approval", rr.Data["expires-in"])
+			} else if rr.Data["expires-at"] != "" {
+				what += fmt.Sprintf(", expiring at %s", rr.Data["expires-at"])
+			}
+			if rr.Data["forwarding-allowed"] == "true" {
+				what += ", with port forwarding"
+			}
+		case models.RequestTypeMember:
+			what = "membership"
+		}
+
+		lines := []string{
+			fmt.Sprintf("%s: %s (%s)", green("Request"), rr.ID, rr.Status),
+			fmt.Sprintf("\t- %s requested %s on %s", rr.Requester, what, rr.CreationDate.Format("2006-01-02 15:04:05")),
+		}
+		if rr.Reason != "" {
+			lines = append(lines, fmt.Sprintf("\t- Reason: %s", rr.Reason))
+		}
+		if rr.DecisionDate != nil {
+			lines = append(lines, fmt.Sprintf("\t- Decision: %s by %s on %s", rr.Status, rr.DecidedBy, rr.DecisionDate.Format("2006-01-02 15:04:05")))
+		}
+		if rr.DecisionComment != "" {
+			lines = append(lines, fmt.Sprintf("\t- Comment: %s", rr.DecisionComment))
+		}
+		fmt.Println(strings.Join(lines, "\n"))
+	}
+}
+
+// getRequestTypes returns the types of requests the user can decide on for the group
+func (c *GroupListRequests) getRequestTypes(ct *commands.Context) (requestTypes []string) {
+	for _, requestType := range []string{models.RequestTypeAccess, models.RequestTypeMember} {
+		if canDecideRequest(ct.User, ct.Group.Name, requestType) {
+			requestTypes = append(requestTypes, requestType)
+		}
+	}
+	return
+}
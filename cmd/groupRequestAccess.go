@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// GroupRequestAccess describes the command
+type GroupRequestAccess struct{}
+
+func init() {
+	commands.RegisterCommand("group access request", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(GroupRequestAccess), models.GroupMember, helpers.Helper{
+				Header:      "request a group access to a distant host",
+				Usage:       "group access request --group GROUP-NAME --host HOST --user USER [--port PORT --alias ALIAS --expires-in DURATION | --expires-at DATE --forwarding-allowed --reason REASON]",
+				Description: "request a group access to a distant host, to be approved by an ACL keeper of the group",
+				Aliases:     []string{"groupRequestAccess"},
+			}, map[string]commands.Argument{
+				"group": {
+					Required:    true,
+					Description: "The group name you want to request an access for",
+				},
+				"host": {
+					Required:    true,
+					Description: "An IP, IP range or hostname you're requesting access to",
+				},
+				"user": {
+					Required:    true,
+					Description: "The user you're requesting access to",
+				},
+				"port": {
+					Required:     false,
+					Description:  "An optional port you're requesting access to. If not provided, the default SSH port (22) will be used.",
+					DefaultValue: "22",
+				},
+				"alias": {
+					Required:    false,
+					Description: "An optional alias to this access (to enable quick access by typing 'sb alias' or 'sb user@alias')",
+				},
+				"expires-in": {
+					Required:    false,
+					Description: "An optional duration after which the access expires, starting at approval (e.g. 12h, 14d, 2w)",
+				},
+				"expires-at": {
+					Required:    false,
+					Description: "An optional date at which the access expires, in RFC3339 format (e.g. 2006-01-02T15:04:05Z)",
+				},
+				"forwarding-allowed": {
+					Required:    false,
+					Description: "Allow this access to be used for port forwarding (see the forward command)",
+					Type:        commands.BOOL,
+				},
+				"reason": {
+					Required:    false,
+					Description: "Why you need this access",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *GroupRequestAccess) Checks(ct *commands.Context) error {
+
+	for commandName := range commands.GetCommands() {
+		if strings.EqualFold(ct.FormattedArguments["alias"], commandName) ||
+			strings.EqualFold(ct.FormattedArguments["host"], commandName) {
+			return fmt.Errorf("the host or alias provided matches a sb command name, please provide a different value")
+		}
+	}
+
+	_, err := helpers.ParseExpiration(ct.FormattedArguments["expires-in"], ct.FormattedArguments["expires-at"], time.Now())
+	if err != nil {
+		return err
+	}
+
+	// We validate the access now, rather than letting the ACL keepers approve an invalid one
+	_, err = models.BuildSBAccess(ct.FormattedArguments["host"], ct.FormattedArguments["user"], ct.FormattedArguments["port"], ct.FormattedArguments["alias"], true)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Execute executes the command
+func (c *GroupRequestAccess) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	// The expiration is kept as provided: a duration only starts once the request is approved
+	request, err := models.NewRequest(
+		models.RequestTypeAccess,
+		ct.Group.Name,
+		ct.User.User.Username,
+		"group access add",
+		ct.FormattedArguments["reason"],
+		models.ReplicationData{
+			"group":              ct.Group.Name,
+			"host":               ct.FormattedArguments["host"],
+			"user":               ct.FormattedArguments["user"],
+			"port":               ct.FormattedArguments["port"],
+			"alias":              ct.FormattedArguments["alias"],
+			"expires-in":         ct.FormattedArguments["expires-in"],
+			"expires-at":         ct.FormattedArguments["expires-at"],
+			"forwarding-allowed": ct.FormattedArguments["forwarding-allowed"],
+		},
+	)
+	if err != nil {
+		return
+	}
+
+	repl, err = requestToReplicationData(request)
+	if err != nil {
+		return
+	}
+
+	err = c.Replicate(repl)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Your request %s was recorded, an ACL keeper of group %s now has to approve it\n", request.UniqID, ct.Group.Name)
+
+	return
+}
+
+func (c *GroupRequestAccess) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *GroupRequestAccess) Replicate(repl models.ReplicationData) (err error) {
+	_, err = saveReplicatedRequest(repl)
+	return
+}
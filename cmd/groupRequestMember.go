@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// GroupRequestMember describes the command
+type GroupRequestMember struct{}
+
+func init() {
+	commands.RegisterCommand("group member request", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(GroupRequestMember), models.Public, helpers.Helper{
+				Header:      "request to become a group member",
+				Usage:       "group member request --group GROUP [--reason REASON]",
+				Description: "request to become a group member, to be approved by a gate keeper of the group",
+				Aliases:     []string{"groupRequestMember"},
+			}, map[string]commands.Argument{
+				"group": {
+					Required:    true,
+					Description: "The group you want to become a member of",
+				},
+				"reason": {
+					Required:    false,
+					Description: "Why you need to become a member of the group",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *GroupRequestMember) Checks(ct *commands.Context) error {
+
+	if ct.User.IsMemberOfGroup(ct.Group.Name) {
+		return fmt.Errorf("you already are a member of group %s", ct.Group.Name)
+	}
+
+	db, err := models.GetRequestsGormDB(config.GetRequestsDatabasePath())
+	if err != nil {
+		return err
+	}
+
+	requests, err := models.GetRequests(db, ct.Group.Name, models.RequestStatusPending, models.RequestTypeMember)
+	if err != nil {
+		return err
+	}
+	for _, request := range requests {
+		if request.Requester == ct.User.User.Username {
+			return fmt.Errorf("you already requested to become a member of group %s (request %s)", ct.Group.Name, request.UniqID)
+		}
+	}
+
+	return nil
+}
+
+// Execute executes the command
+func (c *GroupRequestMember) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	request, err := models.NewRequest(
+		models.RequestTypeMember,
+		ct.Group.Name,
+		ct.User.User.Username,
+		"group member add",
+		ct.FormattedArguments["reason"],
+		models.ReplicationData{
+			"group":   ct.Group.Name,
+			"account": ct.User.User.Username,
+		},
+	)
+	if err != nil {
+		return
+	}
+
+	repl, err = requestToReplicationData(request)
+	if err != nil {
+		return
+	}
+
+	err = c.Replicate(repl)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Your request %s was recorded, a gate keeper of group %s now has to approve it\n", request.UniqID, ct.Group.Name)
+
+	return
+}
+
+func (c *GroupRequestMember) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *GroupRequestMember) Replicate(repl models.ReplicationData) (err error) {
+	_, err = saveReplicatedRequest(repl)
+	return
+}
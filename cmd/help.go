@@ -81,6 +81,10 @@ func (c *Help) Execute(ct *commands.Context) (repl models.ReplicationData, cmdEr
 			continue
 		}
 
+		if rightLevel == models.Auditor && !ct.User.IsAuditor() {
+			continue
+		}
+
 		if rightLevel < models.SBOwner || ct.User.IsOwnerOfGroup("owners") {
 			fmt.Printf("  - %-"+strconv.Itoa(maxLength)+"s : %s\n", commandName, helper.Header)
 		}
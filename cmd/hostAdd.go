@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// HostAdd describes the command
+type HostAdd struct{}
+
+func init() {
+	commands.RegisterCommand("host add", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(HostAdd), models.SBOwner, helpers.Helper{
+				Header:      "add a host to the inventory, or replace it",
+				Usage:       "host add --name HOST [--aliases 'ALIAS,ALIAS' --addresses 'IP,IP' --port PORT --user USER --tags 'KEY=VALUE,KEY=VALUE' --description DESCRIPTION]",
+				Description: "add a host to the inventory, or replace it. The users can connect to it with any of its names, and the accesses granted to its canonical name or with a label selector matching its tags apply",
+				Aliases:     []string{"hostAdd"},
+			}, map[string]commands.Argument{
+				"name": {
+					Required:    true,
+					Description: "The canonical name of the host",
+				},
+				"aliases": {
+					Required:    false,
+					Description: "Other names of the host, like 'db1,db1.staging'",
+				},
+				"addresses": {
+					Required:    false,
+					Description: "The IPs of the host, used when its name doesn't resolve",
+				},
+				"port": {
+					Required:    false,
+					Description: "The SSH port to use when the user doesn't provide one",
+				},
+				"user": {
+					Required:    false,
+					Description: "The remote user to use when the user doesn't provide one",
+				},
+				"tags": {
+					Required:    false,
+					Description: "The tags of the host, like 'env=prod,team=db'",
+				},
+				"description": {
+					Required:    false,
+					Description: "A description of the host",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *HostAdd) Checks(ct *commands.Context) error {
+
+	host, err := c.buildHost(ct)
+	if err != nil {
+		return err
+	}
+
+	db, err := models.GetHostsGormDB(config.GetHostsDatabasePath())
+	if err != nil {
+		return err
+	}
+
+	return models.CheckInventoryConflicts(db, []*models.Host{host})
+}
+
+// Execute executes the command
+func (c *HostAdd) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	host, err := c.buildHost(ct)
+	if err != nil {
+		return
+	}
+
+	repl = hostReplicationData(host)
+
+	err = c.Replicate(repl)
+
+	return
+}
+
+// hostReplicationData returns the replication data of a "host add" of the host
+func hostReplicationData(host *models.Host) models.ReplicationData {
+	return models.ReplicationData{
+		"name":        host.Name,
+		"aliases":     host.Aliases,
+		"addresses":   host.Addresses,
+		"port":        strconv.Itoa(host.Port),
+		"user":        host.User,
+		"tags":        host.Tags,
+		"description": host.Description,
+	}
+}
+
+// buildHost builds the host of the inventory described by the arguments
+func (c *HostAdd) buildHost(ct *commands.Context) (host *models.Host, err error) {
+
+	host = &models.Host{
+		Name:        ct.FormattedArguments["name"],
+		Aliases:     ct.FormattedArguments["aliases"],
+		Addresses:   ct.FormattedArguments["addresses"],
+		User:        ct.FormattedArguments["user"],
+		Tags:        ct.FormattedArguments["tags"],
+		Description: ct.FormattedArguments["description"],
+	}
+
+	if ct.FormattedArguments["port"] != "" {
+		host.Port, err = strconv.Atoi(ct.FormattedArguments["port"])
+		if err != nil {
+			return nil, fmt.Errorf("port is invalid")
+		}
+	}
+
+	err = host.Normalize()
+
+	return
+}
+
+func (c *HostAdd) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+// ObjectKey returns the host of the inventory, which "host add" and "host remove" act on
+func (c *HostAdd) ObjectKey(repl models.ReplicationData) string {
+	return models.ObjectKey("host", repl["name"])
+}
+
+func (c *HostAdd) Replicate(repl models.ReplicationData) (err error) {
+
+	db, err := models.GetHostsGormDB(config.GetHostsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	// Entries replicated from older instances don't carry a port
+	port, _ := strconv.Atoi(repl["port"])
+
+	host := &models.Host{
+		Name:        repl["name"],
+		Aliases:     repl["aliases"],
+		Addresses:   repl["addresses"],
+		Port:        port,
+		User:        repl["user"],
+		Tags:        repl["tags"],
+		Description: repl["description"],
+	}
+
+	err = host.Save(db)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Host %s is now in the inventory\n", host.Name)
+
+	return
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/pkg/errors"
+)
+
+// HostImport describes the command
+type HostImport struct {
+	hosts []*models.Host
+}
+
+func init() {
+	commands.RegisterCommand("host import", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(HostImport), models.SBOwner, helpers.Helper{
+				Header:      "import hosts into the inventory",
+				Usage:       "host import --csv | --yaml < FILE",
+				Description: "import hosts read on the standard input into the inventory, replacing the hosts with the same names",
+				Aliases:     []string{"hostImport"},
+			}, map[string]commands.Argument{
+				"csv": {
+					Required:    false,
+					Description: "Read CSV data, whose first line names the columns among name, aliases, addresses, port, user, tags and description",
+					Type:        commands.BOOL,
+				},
+				"yaml": {
+					Required:    false,
+					Description: "Read YAML data: a list of hosts with their name, aliases, addresses, port, user, tags and description",
+					Type:        commands.BOOL,
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *HostImport) Checks(ct *commands.Context) (err error) {
+
+	switch {
+	case ct.FormattedArguments["csv"] == "true" && ct.FormattedArguments["yaml"] != "true":
+		c.hosts, err = models.ParseHostsCSV(os.Stdin)
+	case ct.FormattedArguments["yaml"] == "true" && ct.FormattedArguments["csv"] != "true":
+		c.hosts, err = models.ParseHostsYAML(os.Stdin)
+	default:
+		return fmt.Errorf("either --csv or --yaml should be provided")
+	}
+	if err != nil {
+		return
+	}
+	if len(c.hosts) == 0 {
+		return fmt.Errorf("no host to import")
+	}
+
+	db, err := models.GetHostsGormDB(config.GetHostsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	return models.CheckInventoryConflicts(db, c.hosts)
+}
+
+// Execute executes the command
+func (c *HostImport) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	hosts, err := json.Marshal(c.hosts)
+	if err != nil {
+		err = errors.Wrap(err, "unable to marshal hosts")
+		return
+	}
+
+	repl = models.ReplicationData{
+		"hosts": string(hosts),
+	}
+
+	err = c.Replicate(repl)
+
+	return
+}
+
+func (c *HostImport) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+// SplitReplicationData replicates the import as a "host add" of each host, so that each host is versioned on its own.
+// The "host import" entries of former versions are still applied by Replicate.
+func (c *HostImport) SplitReplicationData(repl models.ReplicationData) (action string, objects []models.ReplicationData, err error) {
+
+	var hosts []*models.Host
+	err = json.Unmarshal([]byte(repl["hosts"]), &hosts)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "unable to unmarshal hosts")
+	}
+
+	objects = make([]models.ReplicationData, 0, len(hosts))
+	for _, host := range hosts {
+		objects = append(objects, hostReplicationData(host))
+	}
+
+	return "host add", objects, nil
+}
+
+func (c *HostImport) Replicate(repl models.ReplicationData) (err error) {
+
+	var hosts []*models.Host
+	err = json.Unmarshal([]byte(repl["hosts"]), &hosts)
+	if err != nil {
+		return errors.Wrap(err, "unable to unmarshal hosts")
+	}
+
+	db, err := models.GetHostsGormDB(config.GetHostsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	err = models.SaveHosts(db, hosts)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("%d hosts were imported into the inventory\n", len(hosts))
+
+	return
+}
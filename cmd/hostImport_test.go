@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostImportSplitReplicationData(t *testing.T) {
+
+	hosts, err := json.Marshal([]*models.Host{
+		{Name: "db1.example.invalid", Aliases: "db1", Port: 2222, Tags: "env=prod"},
+		{Name: "db2.example.invalid"},
+	})
+	require.NoError(t, err)
+
+	// The import is replicated as a versioned "host add" of each host
+	action, objects, err := new(HostImport).SplitReplicationData(models.ReplicationData{"hosts": string(hosts)})
+	require.NoError(t, err)
+	require.Equal(t, "host add", action)
+	require.Len(t, objects, 2)
+	require.Equal(t, "db1", objects[0]["aliases"])
+	require.Equal(t, "2222", objects[0]["port"])
+	require.Equal(t, "env=prod", objects[0]["tags"])
+
+	cmd, _, _, _, err := commands.GetCommand(action)
+	require.NoError(t, err)
+	vc, ok := cmd.(commands.VersionedCommand)
+	require.True(t, ok)
+	require.Equal(t, "host:db1.example.invalid", vc.ObjectKey(objects[0]))
+	require.Equal(t, "host:db2.example.invalid", vc.ObjectKey(objects[1]))
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+
+	"github.com/fatih/color"
+)
+
+// HostList describes the command
+type HostList struct{}
+
+func init() {
+	commands.RegisterCommand("host list", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(HostList), models.Public, helpers.Helper{
+				Header:      "list the hosts of the inventory",
+				Usage:       "host list [--selector SELECTOR]",
+				Description: "list the hosts of the inventory, with their aliases, addresses, default user and port, tags and description",
+				Aliases:     []string{"hostList"},
+			}, map[string]commands.Argument{
+				"selector": {
+					Required:    false,
+					Description: "Only list the hosts matching a label selector like 'env=staging AND team=db'",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *HostList) Checks(ct *commands.Context) error {
+
+	if ct.FormattedArguments["selector"] != "" {
+		if _, err := models.NormalizeSelector(ct.FormattedArguments["selector"]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Execute executes the command
+func (c *HostList) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// hostResult describes a host of the inventory
+type hostResult struct {
+	Name        string            `json:"name" yaml:"name"`
+	Aliases     []string          `json:"aliases" yaml:"aliases"`
+	Addresses   []string          `json:"addresses" yaml:"addresses"`
+	Port        int               `json:"port,omitempty" yaml:"port,omitempty"`
+	User        string            `json:"user,omitempty" yaml:"user,omitempty"`
+	Tags        map[string]string `json:"tags" yaml:"tags"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+
+	host *models.Host
+}
+
+// hostListResult describes the result of the command
+type hostListResult struct {
+	Hosts []*hostResult `json:"hosts" yaml:"hosts"`
+}
+
+// Result returns the hosts of the inventory
+func (c *HostList) Result(ct *commands.Context) (result commands.Result, err error) {
+
+	db, err := models.GetHostsGormDB(config.GetHostsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	hosts, err := models.GetAllHosts(db)
+	if err != nil {
+		return
+	}
+
+	res := &hostListResult{
+		Hosts: make([]*hostResult, 0, len(hosts)),
+	}
+	for _, host := range hosts {
+		labels := host.Labels()
+		if ct.FormattedArguments["selector"] != "" && !models.MatchesSelector(ct.FormattedArguments["selector"], labels) {
+			continue
+		}
+		res.Hosts = append(res.Hosts, &hostResult{
+			Name:        host.Name,
+			Aliases:     host.AliasesList(),
+			Addresses:   host.AddressesList(),
+			Port:        host.Port,
+			User:        host.User,
+			Tags:        labels,
+			Description: host.Description,
+			host:        host,
+		})
+	}
+
+	return res, nil
+}
+
+func (r *hostListResult) PrintTable() {
+
+	if len(r.Hosts) == 0 {
+		fmt.Println("No host of the inventory matches")
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Println("Here are the hosts of the inventory:")
+	for _, hr := range r.Hosts {
+		str := fmt.Sprintf("%s: %-30s | %s: %-20s | %s: %s", green("Host"), hr.Name, green("Aliases"), hr.host.Aliases, green("Tags"), hr.host.Tags)
+		if hr.host.Addresses != "" {
+			str += fmt.Sprintf(" | %s: %s", green("Addresses"), hr.host.Addresses)
+		}
+		if hr.User != "" {
+			str += fmt.Sprintf(" | %s: %s", green("User"), hr.User)
+		}
+		if hr.Port != 0 {
+			str += fmt.Sprintf(" | %s: %d", green("Port"), hr.Port)
+		}
+		if hr.Description != "" {
+			str += fmt.Sprintf(" | %s: %s", green("Description"), hr.Description)
+		}
+		fmt.Println(str)
+	}
+}
+
+func (c *HostList) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *HostList) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
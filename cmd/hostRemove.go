@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// HostRemove describes the command
+type HostRemove struct{}
+
+func init() {
+	commands.RegisterCommand("host remove", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(HostRemove), models.SBOwner, helpers.Helper{
+				Header:      "remove a host from the inventory",
+				Usage:       "host remove --name HOST",
+				Description: "remove a host from the inventory: the group accesses granted with a label selector don't apply to it anymore",
+				Aliases:     []string{"hostRemove"},
+			}, map[string]commands.Argument{
+				"name": {
+					Required:    true,
+					Description: "The name of the host",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *HostRemove) Checks(ct *commands.Context) error {
+
+	db, err := models.GetHostsGormDB(config.GetHostsDatabasePath())
+	if err != nil {
+		return err
+	}
+
+	host, err := models.GetHost(db, ct.FormattedArguments["name"])
+	if err != nil {
+		return err
+	}
+	if host == nil {
+		return fmt.Errorf("host %s is not in the inventory", ct.FormattedArguments["name"])
+	}
+
+	return nil
+}
+
+// Execute executes the command
+func (c *HostRemove) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	repl = models.ReplicationData{
+		"name": ct.FormattedArguments["name"],
+	}
+
+	err = c.Replicate(repl)
+
+	return
+}
+
+func (c *HostRemove) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+// ObjectKey returns the host of the inventory, which "host add" and "host remove" act on
+func (c *HostRemove) ObjectKey(repl models.ReplicationData) string {
+	return models.ObjectKey("host", repl["name"])
+}
+
+func (c *HostRemove) Replicate(repl models.ReplicationData) (err error) {
+
+	db, err := models.GetHostsGormDB(config.GetHostsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	err = (&models.Host{Name: repl["name"]}).Delete(db)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Host %s was removed from the inventory\n", repl["name"])
+
+	return
+}
@@ -34,6 +34,31 @@ func (c *Info) Checks(ct *commands.Context) error {
 
 // Execute executes the command
 func (c *Info) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// infoResult describes the result of the command
+type infoResult struct {
+	Account                 string   `json:"account" yaml:"account"`
+	Name                    string   `json:"name" yaml:"name"`
+	Location                string   `json:"location" yaml:"location"`
+	Hostname                string   `json:"hostname" yaml:"hostname"`
+	IPAddresses             []string `json:"ip_addresses" yaml:"ip_addresses"`
+	Accounts                int      `json:"accounts" yaml:"accounts"`
+	Groups                  int      `json:"groups" yaml:"groups"`
+	SSHAlias                string   `json:"ssh_alias" yaml:"ssh_alias"`
+	MOSHAlias               string   `json:"mosh_alias" yaml:"mosh_alias"`
+	MemberOfGroups          int      `json:"member_of_groups" yaml:"member_of_groups"`
+	Owner                   bool     `json:"owner" yaml:"owner"`
+	Version                 string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Commit                  string   `json:"commit,omitempty" yaml:"commit,omitempty"`
+	TOTPEnabled             bool     `json:"totp_enabled" yaml:"totp_enabled"`
+	TOTPEmergencyCodesCount int      `json:"totp_emergency_codes_count" yaml:"totp_emergency_codes_count"`
+}
+
+// Result returns the information on this sb instance and on the account
+func (c *Info) Result(ct *commands.Context) (result commands.Result, err error) {
 
 	users, err := models.GetAllSBUsers()
 	if err != nil {
@@ -49,40 +74,65 @@ func (c *Info) Execute(ct *commands.Context) (repl models.ReplicationData, cmdEr
 	}
 	totpEnabled, _, totpEmergency := ct.User.GetTOTP()
 
+	res := &infoResult{
+		Account:        ct.User.User.Username,
+		Name:           config.GetSBName(),
+		Location:       config.GetSBLocation(),
+		Hostname:       config.GetSBHostname(),
+		IPAddresses:    ipAddresses,
+		Accounts:       len(users),
+		Groups:         len(groups),
+		SSHAlias:       fmt.Sprintf("alias %s='ssh %s@%s -t -A --'", config.GetSBName(), ct.User.User.Username, config.GetSBHostname()),
+		MOSHAlias:      fmt.Sprintf("alias m%s='mosh %s@%s -A --'", config.GetSBName(), ct.User.User.Username, config.GetSBHostname()),
+		MemberOfGroups: len(ct.User.Groups),
+		Owner:          ct.User.IsOwnerOfGroup("owners"),
+		TOTPEnabled:    totpEnabled,
+	}
+	if res.Owner {
+		res.Version = config.VERSION
+		res.Commit = config.COMMIT
+	}
+	if totpEnabled {
+		res.TOTPEmergencyCodesCount = len(totpEmergency)
+	}
+
+	return res, nil
+}
+
+func (r *infoResult) PrintTable() {
+
 	green := color.New(color.FgGreen).SprintFunc()
 	red := color.New(color.FgRed).SprintFunc()
 
-	fmt.Printf("Hi, %s!\n", green(ct.User.User.Username))
+	fmt.Printf("Hi, %s!\n", green(r.Account))
 	fmt.Println()
 	fmt.Printf("Here are a few information about the wonderful place you just connected to:\n")
-	fmt.Printf("  -> my name is %s\n", green(config.GetSBName()))
-	fmt.Printf("  -> I'm located in %s, my address is %s\n", green(config.GetSBLocation()), green(config.GetSBHostname()))
-	fmt.Printf("  -> here is the list of my IP addresses: %s\n", green(strings.Join(ipAddresses, ", ")))
-	fmt.Printf("  -> I currently have %s guests accounts\n", green(len(users)))
-	fmt.Printf("  -> I'm currently hosting %s groups\n", green(len(groups)))
+	fmt.Printf("  -> my name is %s\n", green(r.Name))
+	fmt.Printf("  -> I'm located in %s, my address is %s\n", green(r.Location), green(r.Hostname))
+	fmt.Printf("  -> here is the list of my IP addresses: %s\n", green(strings.Join(r.IPAddresses, ", ")))
+	fmt.Printf("  -> I currently have %s guests accounts\n", green(r.Accounts))
+	fmt.Printf("  -> I'm currently hosting %s groups\n", green(r.Groups))
 	fmt.Println()
 	fmt.Printf("If you want to interact with me, just one of these aliases:\n")
-	fmt.Printf("  -> SSH:  %s", green(fmt.Sprintf("alias %s='ssh %s@%s -t -A --'\n", config.GetSBName(), ct.User.User.Username, config.GetSBHostname())))
-	fmt.Printf("  -> MOSH: %s", green(fmt.Sprintf("alias m%s='mosh %s@%s -A --'\n", config.GetSBName(), ct.User.User.Username, config.GetSBHostname())))
+	fmt.Printf("  -> SSH:  %s\n", green(r.SSHAlias))
+	fmt.Printf("  -> MOSH: %s\n", green(r.MOSHAlias))
 	fmt.Println()
-	fmt.Printf("But let's talk a bit about yourself, %s!\n", green(ct.User.User.Username))
-	fmt.Printf("  -> you're a member of %s groups\n", green(len(ct.User.Groups)))
-	if ct.User.IsOwnerOfGroup("owners") {
+	fmt.Printf("But let's talk a bit about yourself, %s!\n", green(r.Account))
+	fmt.Printf("  -> you're a member of %s groups\n", green(r.MemberOfGroups))
+	if r.Owner {
 		fmt.Printf("  -> as you're a member of the %s group, you have extra admin privileges\n", green("owners"))
-		fmt.Printf("  -> FYI, I'm running version %s on commit %s\n", green(config.VERSION), green(config.COMMIT))
+		fmt.Printf("  -> FYI, I'm running version %s on commit %s\n", green(r.Version), green(r.Commit))
 	}
-	if !totpEnabled {
+	if !r.TOTPEnabled {
 		fmt.Printf("  -> TOTP is %s on your account\n", red("disabled"))
 	} else {
 		fmt.Printf("  -> TOTP is %s on your account\n", green("enabled"))
-		if len(totpEmergency) >= 3 {
-			fmt.Printf("  -> you have %s unused emergency codes, feel free to generate new ones if you wish\n", green(len(totpEmergency)))
+		if r.TOTPEmergencyCodesCount >= 3 {
+			fmt.Printf("  -> you have %s unused emergency codes, feel free to generate new ones if you wish\n", green(r.TOTPEmergencyCodesCount))
 		} else {
-			fmt.Printf("  -> you only have %s unused emergency codes left. %s\n", red(len(totpEmergency)), red("You should generate new ones right away!"))
+			fmt.Printf("  -> you only have %s unused emergency codes left. %s\n", red(r.TOTPEmergencyCodesCount), red("You should generate new ones right away!"))
 		}
 	}
-
-	return
 }
 
 func (c *Info) PostExecute(repl models.ReplicationData) (err error) {
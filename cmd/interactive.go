@@ -178,7 +178,8 @@ func (c *Interactive) promptCompleter(d prompt.Document) (s []prompt.Suggest) {
 
 			// We have a known command
 			// We have to give the argument list to user
-			_, _, _, args := commandFactory()
+			cmd, _, _, args := commandFactory()
+			commands.AddGlobalArguments(cmd, args)
 
 			for argName, arg := range args {
 				if !strings.Contains(d.CurrentLine(), fmt.Sprintf("--%s", argName)) {
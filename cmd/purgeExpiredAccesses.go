@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/pkg/errors"
+)
+
+// PurgeExpiredAccesses describes the command
+type PurgeExpiredAccesses struct{}
+
+// purgedAccesses describes the personal and group access databases that had expired accesses removed
+type purgedAccesses struct {
+	Accounts []string
+	Groups   []string
+}
+
+func init() {
+	commands.RegisterCommand("accesses purge", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
+		return new(PurgeExpiredAccesses), models.Private, helpers.Helper{
+			Header:      "remove the expired personal and group accesses",
+			Usage:       "accesses purge",
+			Description: "remove the expired personal and group accesses (this is also done periodically by the daemon)",
+			Aliases:     []string{"purgeExpiredAccesses"},
+		}, map[string]commands.Argument{}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *PurgeExpiredAccesses) Checks(ct *commands.Context) error {
+	return nil
+}
+
+// Execute executes the command
+func (c *PurgeExpiredAccesses) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	// The databases that were purged are replicated, even if others couldn't be
+	purged, cmdError := c.purge()
+
+	repl, err = purged.toReplicationData()
+
+	return
+}
+
+func (c *PurgeExpiredAccesses) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+// Replicate purges the expired accesses of the databases that were purged on the emitting instance.
+// As the expiration date is replicated with the access, this is idempotent: if this instance already
+// purged them by itself, there is nothing left to do.
+func (c *PurgeExpiredAccesses) Replicate(repl models.ReplicationData) (err error) {
+
+	var purged purgedAccesses
+	err = json.Unmarshal([]byte(repl["purged"]), &purged)
+	if err != nil {
+		return errors.Wrap(err, "unable to json.Unmarshal purged accesses")
+	}
+
+	failures := 0
+
+	for _, account := range purged.Accounts {
+		if _, errPurge := purgeAccountExpiredAccesses(account); errPurge != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", errPurge)
+			failures++
+		}
+	}
+
+	for _, groupName := range purged.Groups {
+		group, errGroup := models.GetGroup(groupName)
+		if errGroup == nil {
+			_, errGroup = group.PurgeExpiredAccesses()
+		}
+		if errGroup != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to purge expired accesses of group %s: %s\n", groupName, errGroup)
+			failures++
+		}
+	}
+
+	return purgeFailuresError(failures)
+}
+
+// purge removes the expired accesses from every personal and group access database of this instance.
+// A database that can't be purged doesn't prevent the others from being purged: the error is displayed, and
+// returned once they all were.
+func (c *PurgeExpiredAccesses) purge() (purged *purgedAccesses, err error) {
+
+	purged = &purgedAccesses{
+		Accounts: make([]string, 0),
+		Groups:   make([]string, 0),
+	}
+
+	users, err := models.GetAllSBUsers()
+	if err != nil {
+		return
+	}
+
+	failures := 0
+
+	for _, username := range users {
+		accesses, errPurge := purgeAccountExpiredAccesses(username)
+		if errPurge != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", errPurge)
+			failures++
+			continue
+		}
+
+		for _, ba := range accesses {
+			fmt.Printf("Expired access %s of account %s has been removed\n", ba.ShortString(), username)
+		}
+		if len(accesses) > 0 {
+			purged.Accounts = append(purged.Accounts, username)
+		}
+	}
+
+	groups, err := models.GetAllSBGroups()
+	if err != nil {
+		return
+	}
+
+	for groupName, group := range groups {
+		accesses, errPurge := group.PurgeExpiredAccesses()
+		if errPurge != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to purge expired accesses of group %s: %s\n", groupName, errPurge)
+			failures++
+			continue
+		}
+
+		for _, ba := range accesses {
+			fmt.Printf("Expired access %s of group %s has been removed\n", ba.ShortString(), groupName)
+		}
+		if len(accesses) > 0 {
+			purged.Groups = append(purged.Groups, groupName)
+		}
+	}
+
+	return purged, purgeFailuresError(failures)
+}
+
+// purgeAccountExpiredAccesses removes the expired accesses of an account
+func purgeAccountExpiredAccesses(account string) (accesses []*models.Access, err error) {
+
+	user, err := models.LoadUser(account)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load account %s", account)
+	}
+
+	accesses, err = user.PurgeExpiredAccesses()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to purge expired accesses of account %s", account)
+	}
+
+	return
+}
+
+// purgeFailuresError returns the error of a purge, if some of the access databases couldn't be purged
+func purgeFailuresError(failures int) error {
+	if failures == 0 {
+		return nil
+	}
+	return fmt.Errorf("the expired accesses of %d accounts or groups could not be purged", failures)
+}
+
+// isEmpty returns true if no access was purged
+func (p *purgedAccesses) isEmpty() bool {
+	return len(p.Accounts) == 0 && len(p.Groups) == 0
+}
+
+// toReplicationData serializes the purged databases as a replication data
+func (p *purgedAccesses) toReplicationData() (repl models.ReplicationData, err error) {
+
+	purgedJSON, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+
+	repl = models.ReplicationData{
+		"purged": string(purgedJSON),
+	}
+
+	return
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// ReplicationDLQDrop describes the command
+type ReplicationDLQDrop struct {
+	deadLetters []*models.ReplicationDeadLetter
+}
+
+func init() {
+	commands.RegisterCommand("replication dlq drop", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(ReplicationDLQDrop), models.SBOwner, helpers.Helper{
+				Header:      "give up on a dead letter",
+				Usage:       "replication dlq drop --id ID|--all",
+				Description: "remove a dead letter: the entry it holds will never be applied nor published",
+				Aliases:     []string{"replicationDLQDrop"},
+			}, map[string]commands.Argument{
+				"id": {
+					Required:    false,
+					Description: "The ID of the dead letter, as displayed by replication dlq list",
+				},
+				"all": {
+					Required:    false,
+					Description: "Remove all the dead letters",
+					Type:        commands.BOOL,
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *ReplicationDLQDrop) Checks(ct *commands.Context) (err error) {
+	c.deadLetters, err = selectDeadLetters(ct.FormattedArguments)
+	return
+}
+
+// Execute executes the command
+func (c *ReplicationDLQDrop) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	for _, dl := range c.deadLetters {
+		err = dl.Delete(db)
+		if err != nil {
+			return
+		}
+	}
+
+	fmt.Printf("%d dead letters dropped.\n", len(c.deadLetters))
+
+	return
+}
+
+func (c *ReplicationDLQDrop) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *ReplicationDLQDrop) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
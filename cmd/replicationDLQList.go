@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+
+	"github.com/fatih/color"
+)
+
+// ReplicationDLQList describes the command
+type ReplicationDLQList struct{}
+
+func init() {
+	commands.RegisterCommand("replication dlq list", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(ReplicationDLQList), models.SBOwner, helpers.Helper{
+				Header:      "list the replication entries that failed too many times",
+				Usage:       "replication dlq list",
+				Description: "list the dead letters: the entries of other instances that could not be applied, and the entries of this instance whose PostExecute step failed, after all their attempts",
+				Aliases:     []string{"replicationDLQList"},
+			}, map[string]commands.Argument{}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *ReplicationDLQList) Checks(ct *commands.Context) error {
+	return nil
+}
+
+// Execute executes the command
+func (c *ReplicationDLQList) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// deadLetterResult describes a replication entry put aside after failing too many times
+type deadLetterResult struct {
+	ID             uint      `json:"id" yaml:"id"`
+	Step           string    `json:"step" yaml:"step"`
+	EntryID        string    `json:"entry_id" yaml:"entry_id"`
+	Instance       string    `json:"instance" yaml:"instance"`
+	Action         string    `json:"action" yaml:"action"`
+	CreatedAt      time.Time `json:"created_at" yaml:"created_at"`
+	FailedAt       time.Time `json:"failed_at" yaml:"failed_at"`
+	Attempts       int       `json:"attempts" yaml:"attempts"`
+	LastError      string    `json:"last_error" yaml:"last_error"`
+	RetryRequested bool      `json:"retry_requested" yaml:"retry_requested"`
+}
+
+// deadLettersResult describes the result of the command
+type deadLettersResult struct {
+	DeadLetters []*deadLetterResult `json:"dead_letters" yaml:"dead_letters"`
+}
+
+// Result returns the dead letters of this instance
+func (c *ReplicationDLQList) Result(ct *commands.Context) (result commands.Result, err error) {
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	deadLetters, err := models.GetReplicationDeadLetters(db)
+	if err != nil {
+		return
+	}
+
+	res := &deadLettersResult{
+		DeadLetters: make([]*deadLetterResult, 0, len(deadLetters)),
+	}
+	for _, dl := range deadLetters {
+		res.DeadLetters = append(res.DeadLetters, &deadLetterResult{
+			ID:             dl.ID,
+			Step:           dl.Step,
+			EntryID:        dl.UniqID,
+			Instance:       dl.Instance,
+			Action:         dl.Action,
+			CreatedAt:      dl.CreationDate,
+			FailedAt:       dl.FailedAt,
+			Attempts:       dl.Attempts,
+			LastError:      dl.LastError,
+			RetryRequested: dl.RetryRequested,
+		})
+	}
+
+	return res, nil
+}
+
+func (r *deadLettersResult) PrintTable() {
+
+	if len(r.DeadLetters) == 0 {
+		fmt.Println("No replication entry is in the dead letters")
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	deadLetters := make([]string, 0, len(r.DeadLetters))
+	for _, dl := range r.DeadLetters {
+		str := fmt.Sprintf("%s: %d | %s: %s from %s (%s) | %s: %s | %s: %s after %d attempts\n    %s: %s",
+			green("ID"), dl.ID, green("Action"), dl.Action, dl.Instance, dl.EntryID, green("Step"), dl.Step,
+			green("Failed"), dl.FailedAt.Format(time.RFC3339), dl.Attempts, yellow("Error"), dl.LastError,
+		)
+		if dl.RetryRequested {
+			str += "\n    retry requested"
+		}
+		deadLetters = append(deadLetters, str)
+	}
+
+	fmt.Printf("Here is the list of the dead letters:\n%s\n", strings.Join(deadLetters, "\n"))
+}
+
+func (c *ReplicationDLQList) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *ReplicationDLQList) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// ReplicationDLQRetry describes the command
+type ReplicationDLQRetry struct {
+	deadLetters []*models.ReplicationDeadLetter
+}
+
+func init() {
+	commands.RegisterCommand("replication dlq retry", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(ReplicationDLQRetry), models.SBOwner, helpers.Helper{
+				Header:      "try a dead letter again",
+				Usage:       "replication dlq retry --id ID|--all",
+				Description: "ask the daemon to try a dead letter again: the entry of another instance is applied, the entry of this instance is post-executed and published",
+				Aliases:     []string{"replicationDLQRetry"},
+			}, map[string]commands.Argument{
+				"id": {
+					Required:    false,
+					Description: "The ID of the dead letter, as displayed by replication dlq list",
+				},
+				"all": {
+					Required:    false,
+					Description: "Try all the dead letters again",
+					Type:        commands.BOOL,
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *ReplicationDLQRetry) Checks(ct *commands.Context) (err error) {
+	c.deadLetters, err = selectDeadLetters(ct.FormattedArguments)
+	return
+}
+
+// Execute executes the command
+func (c *ReplicationDLQRetry) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	for _, dl := range c.deadLetters {
+		dl.RetryRequested = true
+		err = dl.Save(db)
+		if err != nil {
+			return
+		}
+	}
+
+	fmt.Printf("%d dead letters will be tried again by the daemon.\n", len(c.deadLetters))
+
+	return
+}
+
+func (c *ReplicationDLQRetry) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *ReplicationDLQRetry) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
+
+// selectDeadLetters returns the dead letters selected with the --id or the --all arguments
+func selectDeadLetters(arguments map[string]string) (deadLetters []*models.ReplicationDeadLetter, err error) {
+
+	all := arguments["all"] == "true"
+	if (arguments["id"] == "") == !all {
+		return nil, fmt.Errorf("either argument id or argument all should be provided")
+	}
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	if all {
+		return models.GetReplicationDeadLetters(db)
+	}
+
+	id, err := strconv.ParseUint(arguments["id"], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("argument id should be the ID of a dead letter")
+	}
+
+	deadLetter, err := models.GetReplicationDeadLetter(db, uint(id))
+	if err != nil {
+		return
+	}
+
+	return []*models.ReplicationDeadLetter{deadLetter}, nil
+}
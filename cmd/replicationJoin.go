@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/types"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// joinPollInterval is how often the progress of the join is checked
+const joinPollInterval = 2 * time.Second
+
+// ReplicationJoin describes the command
+type ReplicationJoin struct {
+	timeout time.Duration
+}
+
+func init() {
+	commands.RegisterCommand("replication join", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(ReplicationJoin), models.Private, helpers.Helper{
+				Header:      "bootstrap this instance from a live peer",
+				Usage:       "replication join --from-peer PEER [--timeout DURATION]",
+				Description: "replace the state of this instance by a snapshot of a peer, requested through the replication queue, then apply the entries received since the snapshot",
+				Aliases:     []string{"replicationJoin"},
+			}, map[string]commands.Argument{
+				"from-peer": {
+					Required:    true,
+					Description: "The hostname of the instance to take the snapshot of",
+				},
+				"timeout": {
+					Required:     false,
+					Description:  "How long to wait for the join to complete (default is 1h)",
+					DefaultValue: "1h",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *ReplicationJoin) Checks(ct *commands.Context) (err error) {
+
+	if !config.GetReplicationEnabled() {
+		return types.ErrCommandDisabled
+	}
+
+	c.timeout, err = time.ParseDuration(ct.FormattedArguments["timeout"])
+	if err != nil || c.timeout <= 0 {
+		return fmt.Errorf("argument timeout should be a positive duration")
+	}
+
+	hostname, err := helpers.GetHostname()
+	if err != nil {
+		return
+	}
+	if ct.FormattedArguments["from-peer"] == hostname {
+		return fmt.Errorf("this instance can't join from itself")
+	}
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	join, err := models.GetActiveReplicationJoin(db)
+	if err != nil {
+		return
+	}
+	if join != nil {
+		return fmt.Errorf("this instance is already joining from %s (request %s)", join.Peer, join.RequestID)
+	}
+
+	return
+}
+
+// Execute executes the command
+func (c *ReplicationJoin) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	join := &models.ReplicationJoin{
+		RequestID:   uuid.New().String(),
+		Peer:        ct.FormattedArguments["from-peer"],
+		State:       models.JoinRequested,
+		RequestedAt: time.Now(),
+	}
+
+	request, err := models.NewReplicationEntry("snapshot request", models.ReplicationData{
+		"peer":    join.Peer,
+		"request": join.RequestID,
+	})
+	if err != nil {
+		return
+	}
+
+	// From now on, the daemon keeps the entries it receives until the snapshot is restored
+	err = join.Save(db)
+	if err != nil {
+		return
+	}
+
+	err = request.Save(db)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Snapshot %s requested from %s, waiting for the daemon to receive and restore it...\n", join.RequestID, join.Peer)
+
+	received := -1
+	deadline := time.Now().Add(c.timeout)
+	for time.Now().Before(deadline) {
+
+		time.Sleep(joinPollInterval)
+
+		join, err = models.GetReplicationJoin(db, join.RequestID)
+		if err != nil {
+			return
+		}
+		if join == nil {
+			err = fmt.Errorf("the join was removed")
+			return
+		}
+
+		switch join.State {
+		case models.JoinDone:
+			fmt.Printf("This instance joined from %s: its snapshot of %s was restored.\n", join.Peer, join.SnapshotAt.Format(time.RFC3339))
+			return
+		case models.JoinFailed:
+			err = errors.Errorf("the join failed: %s", join.Error)
+			return
+		case models.JoinReceiving:
+			if join.Received != received {
+				received = join.Received
+				fmt.Printf("%d/%d chunks of the snapshot received\n", join.Received, join.Chunks)
+			}
+		}
+	}
+
+	// The daemon stops keeping the entries it receives, and applies the ones it kept before the next ones
+	err = fmt.Errorf("the join didn't complete in %s", c.timeout)
+	errFail := join.Fail(db, err)
+	if errFail != nil {
+		err = errFail
+	}
+
+	return
+}
+
+func (c *ReplicationJoin) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *ReplicationJoin) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
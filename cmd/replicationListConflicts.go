@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+
+	"github.com/fatih/color"
+)
+
+// ReplicationListConflicts describes the command
+type ReplicationListConflicts struct{}
+
+func init() {
+	commands.RegisterCommand("replication conflicts list", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(ReplicationListConflicts), models.SBOwner, helpers.Helper{
+				Header:      "list the replicated actions overridden by concurrent ones",
+				Usage:       "replication conflicts list [--since DATE --limit LIMIT]",
+				Description: "list the conflicts between actions made concurrently on the same object by different instances, with the action that was kept and the one that was overridden",
+				Aliases:     []string{"replicationListConflicts"},
+			}, map[string]commands.Argument{
+				"since": {
+					Required:    false,
+					Description: "Only list the conflicts detected after this date (RFC3339, 2006-01-02 or a duration like 7d)",
+				},
+				"limit": {
+					Required:     false,
+					Description:  "The maximum number of conflicts to list (default is 50)",
+					DefaultValue: "50",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *ReplicationListConflicts) Checks(ct *commands.Context) error {
+	_, _, err := c.parseArguments(ct.FormattedArguments)
+	return err
+}
+
+// Execute executes the command
+func (c *ReplicationListConflicts) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// replicationConflictResult describes a conflict between two replicated actions
+type replicationConflictResult struct {
+	Object     string                  `json:"object" yaml:"object"`
+	DetectedAt time.Time               `json:"detected_at" yaml:"detected_at"`
+	Kept       replicationActionResult `json:"kept" yaml:"kept"`
+	Overridden replicationActionResult `json:"overridden" yaml:"overridden"`
+}
+
+// replicationActionResult describes a replicated action
+type replicationActionResult struct {
+	Action   string `json:"action" yaml:"action"`
+	Instance string `json:"instance" yaml:"instance"`
+	EntryID  string `json:"entry_id" yaml:"entry_id"`
+	Clock    string `json:"clock" yaml:"clock"`
+}
+
+// replicationConflictsResult describes the result of the command
+type replicationConflictsResult struct {
+	Conflicts []*replicationConflictResult `json:"conflicts" yaml:"conflicts"`
+}
+
+// Result returns the conflicts detected on this instance
+func (c *ReplicationListConflicts) Result(ct *commands.Context) (result commands.Result, err error) {
+
+	since, limit, err := c.parseArguments(ct.FormattedArguments)
+	if err != nil {
+		return
+	}
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	conflicts, err := models.GetReplicationConflicts(db, since, limit)
+	if err != nil {
+		return
+	}
+
+	res := &replicationConflictsResult{
+		Conflicts: make([]*replicationConflictResult, 0, len(conflicts)),
+	}
+	for _, conflict := range conflicts {
+		res.Conflicts = append(res.Conflicts, &replicationConflictResult{
+			Object:     conflict.ObjectKey,
+			DetectedAt: conflict.DetectedAt,
+			Kept: replicationActionResult{
+				Action:   conflict.WinnerAction,
+				Instance: conflict.WinnerInstance,
+				EntryID:  conflict.WinnerEntryID,
+				Clock:    conflict.WinnerClock,
+			},
+			Overridden: replicationActionResult{
+				Action:   conflict.LoserAction,
+				Instance: conflict.LoserInstance,
+				EntryID:  conflict.LoserEntryID,
+				Clock:    conflict.LoserClock,
+			},
+		})
+	}
+
+	return res, nil
+}
+
+func (r *replicationConflictsResult) PrintTable() {
+
+	if len(r.Conflicts) == 0 {
+		fmt.Println("No replication conflict was detected")
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	conflicts := make([]string, 0, len(r.Conflicts))
+	for _, cr := range r.Conflicts {
+		conflicts = append(conflicts, fmt.Sprintf("%s: %s | %s: %s\n    %s: %s from %s %s\n    %s: %s from %s %s",
+			green("Detected"), cr.DetectedAt.Format(time.RFC3339), green("Object"), cr.Object,
+			green("Kept"), cr.Kept.Action, cr.Kept.Instance, cr.Kept.Clock,
+			yellow("Overridden"), cr.Overridden.Action, cr.Overridden.Instance, cr.Overridden.Clock,
+		))
+	}
+
+	fmt.Printf("Here is the list of the replication conflicts:\n%s\n", strings.Join(conflicts, "\n"))
+}
+
+func (c *ReplicationListConflicts) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *ReplicationListConflicts) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *ReplicationListConflicts) parseArguments(arguments map[string]string) (since time.Time, limit int, err error) {
+
+	limit, err = strconv.Atoi(arguments["limit"])
+	if err != nil || limit < 1 {
+		return since, limit, fmt.Errorf("argument limit should be a positive integer")
+	}
+
+	if arguments["since"] != "" {
+		since, err = helpers.ParseDate(arguments["since"], time.Now())
+	}
+
+	return
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/metrics"
+	"github.com/inpher/sb/internal/models"
+
+	"github.com/fatih/color"
+)
+
+// ReplicationStatus describes the command
+type ReplicationStatus struct{}
+
+func init() {
+	commands.RegisterCommand("replication status", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(ReplicationStatus), models.SBOwner, helpers.Helper{
+				Header:      "display how far behind the replication is",
+				Usage:       "replication status",
+				Description: "display the entries of this instance waiting to be published, the dead letters, and the last entries pushed and consumed for each instance, with the errors",
+				Aliases:     []string{"replicationStatus"},
+			}, map[string]commands.Argument{}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *ReplicationStatus) Checks(ct *commands.Context) error {
+	return nil
+}
+
+// Execute executes the command
+func (c *ReplicationStatus) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// replicationInstanceResult describes the replication of the entries of an instance
+type replicationInstanceResult struct {
+	Instance      string     `json:"instance" yaml:"instance"`
+	Pushed        int64      `json:"pushed" yaml:"pushed"`
+	LastPushAt    *time.Time `json:"last_push_at,omitempty" yaml:"last_push_at,omitempty"`
+	PushErrors    int64      `json:"push_errors" yaml:"push_errors"`
+	Consumed      int64      `json:"consumed" yaml:"consumed"`
+	LastConsumeAt *time.Time `json:"last_consume_at,omitempty" yaml:"last_consume_at,omitempty"`
+	LagSeconds    float64    `json:"lag_seconds" yaml:"lag_seconds"`
+	ConsumeErrors int64      `json:"consume_errors" yaml:"consume_errors"`
+	LastError     string     `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty" yaml:"last_error_at,omitempty"`
+}
+
+// replicationStatusResult describes the result of the command
+type replicationStatusResult struct {
+	Pending                 int64                        `json:"pending" yaml:"pending"`
+	OldestPendingAgeSeconds float64                      `json:"oldest_pending_age_seconds" yaml:"oldest_pending_age_seconds"`
+	DeadLetters             int64                        `json:"dead_letters" yaml:"dead_letters"`
+	Instances               []*replicationInstanceResult `json:"instances" yaml:"instances"`
+}
+
+// Result returns the state of the replication on this instance
+func (c *ReplicationStatus) Result(ct *commands.Context) (result commands.Result, err error) {
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	status, err := models.GetReplicationStatus(db)
+	if err != nil {
+		return
+	}
+
+	res := &replicationStatusResult{
+		Pending:                 status.Pending,
+		OldestPendingAgeSeconds: oldestPendingAge(status).Seconds(),
+		DeadLetters:             status.DeadLetters,
+		Instances:               make([]*replicationInstanceResult, 0, len(status.Instances)),
+	}
+	for _, i := range status.Instances {
+		res.Instances = append(res.Instances, &replicationInstanceResult{
+			Instance:      i.Instance,
+			Pushed:        i.Pushed,
+			LastPushAt:    optionalTime(i.LastPushAt),
+			PushErrors:    i.PushErrors,
+			Consumed:      i.Consumed,
+			LastConsumeAt: optionalTime(i.LastConsumeAt),
+			LagSeconds:    i.Lag().Seconds(),
+			ConsumeErrors: i.ConsumeErrors,
+			LastError:     i.LastError,
+			LastErrorAt:   optionalTime(i.LastErrorAt),
+		})
+	}
+
+	return res, nil
+}
+
+func (r *replicationStatusResult) PrintTable() {
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Printf("%s: %d (the oldest for %s) | %s: %d\n",
+		green("Pending entries"), r.Pending, time.Duration(r.OldestPendingAgeSeconds*float64(time.Second)).Round(time.Second),
+		green("Dead letters"), r.DeadLetters,
+	)
+
+	if len(r.Instances) == 0 {
+		fmt.Println("No entry was pushed nor consumed yet")
+		return
+	}
+
+	instances := make([]string, 0, len(r.Instances))
+	for _, i := range r.Instances {
+		str := fmt.Sprintf("%s: %s\n    %s: %d, last at %s, %d errors\n    %s: %d, last at %s (lag %s), %d errors",
+			green("Instance"), i.Instance,
+			green("Pushed"), i.Pushed, formatOptionalTime(i.LastPushAt), i.PushErrors,
+			green("Consumed"), i.Consumed, formatOptionalTime(i.LastConsumeAt), time.Duration(i.LagSeconds*float64(time.Second)).Round(time.Millisecond), i.ConsumeErrors,
+		)
+		if i.LastError != "" {
+			str += fmt.Sprintf("\n    %s: %s at %s", yellow("Last error"), i.LastError, formatOptionalTime(i.LastErrorAt))
+		}
+		instances = append(instances, str)
+	}
+
+	fmt.Printf("Here is the replication of each instance:\n%s\n", strings.Join(instances, "\n"))
+}
+
+func (c *ReplicationStatus) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *ReplicationStatus) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
+
+// collectReplicationMetrics returns the state of the replication on this instance as Prometheus metrics
+func collectReplicationMetrics() (replicationMetrics []*metrics.Metric, err error) {
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	status, err := models.GetReplicationStatus(db)
+	if err != nil {
+		return
+	}
+
+	perInstance := func(name, help, metricType string, value func(i *models.ReplicationInstanceStatus) float64) *metrics.Metric {
+		m := &metrics.Metric{Name: name, Help: help, Type: metricType}
+		for _, i := range status.Instances {
+			m.Samples = append(m.Samples, &metrics.Sample{Labels: map[string]string{"instance": i.Instance}, Value: value(i)})
+		}
+		return m
+	}
+
+	timestamp := func(t time.Time) float64 {
+		if t.IsZero() {
+			return 0
+		}
+		return float64(t.UnixNano()) / float64(time.Second)
+	}
+
+	return []*metrics.Metric{
+		{
+			Name:    "sb_replication_pending_entries",
+			Help:    "The entries of this instance waiting to be post-executed and published",
+			Type:    metrics.Gauge,
+			Samples: []*metrics.Sample{{Value: float64(status.Pending)}},
+		},
+		{
+			Name:    "sb_replication_oldest_pending_entry_age_seconds",
+			Help:    "The age of the oldest entry waiting to be published",
+			Type:    metrics.Gauge,
+			Samples: []*metrics.Sample{{Value: oldestPendingAge(status).Seconds()}},
+		},
+		{
+			Name:    "sb_replication_dead_letters",
+			Help:    "The entries put aside after failing too many times",
+			Type:    metrics.Gauge,
+			Samples: []*metrics.Sample{{Value: float64(status.DeadLetters)}},
+		},
+		perInstance("sb_replication_pushed_entries_total", "The entries of the instance published to the queue", metrics.Counter,
+			func(i *models.ReplicationInstanceStatus) float64 { return float64(i.Pushed) }),
+		perInstance("sb_replication_push_errors_total", "The failed PostExecute steps and pushes to the queue", metrics.Counter,
+			func(i *models.ReplicationInstanceStatus) float64 { return float64(i.PushErrors) }),
+		perInstance("sb_replication_last_push_timestamp_seconds", "When an entry of the instance was last published", metrics.Gauge,
+			func(i *models.ReplicationInstanceStatus) float64 { return timestamp(i.LastPushAt) }),
+		perInstance("sb_replication_consumed_entries_total", "The entries of the instance applied on this one", metrics.Counter,
+			func(i *models.ReplicationInstanceStatus) float64 { return float64(i.Consumed) }),
+		perInstance("sb_replication_consume_errors_total", "The failed attempts to apply an entry of the instance", metrics.Counter,
+			func(i *models.ReplicationInstanceStatus) float64 { return float64(i.ConsumeErrors) }),
+		perInstance("sb_replication_last_consume_timestamp_seconds", "When an entry of the instance was last applied on this one", metrics.Gauge,
+			func(i *models.ReplicationInstanceStatus) float64 { return timestamp(i.LastConsumeAt) }),
+		perInstance("sb_replication_lag_seconds", "The delay between the creation of the last applied entry of the instance and its application", metrics.Gauge,
+			func(i *models.ReplicationInstanceStatus) float64 { return i.Lag().Seconds() }),
+	}, nil
+}
+
+// oldestPendingAge returns for how long the oldest pending entry has been waiting
+func oldestPendingAge(status *models.ReplicationStatus) time.Duration {
+	if status.OldestPending.IsZero() {
+		return 0
+	}
+	return time.Since(status.OldestPending)
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/types"
+
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+)
+
+// ReplicationVerify describes the command
+type ReplicationVerify struct{}
+
+func init() {
+	commands.RegisterCommand("replication verify", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(ReplicationVerify), models.SBOwner, helpers.Helper{
+				Header:      "compare the state of the replicated instances",
+				Usage:       "replication verify [--report-only]",
+				Description: "start a new verification round, where every instance sends the digest of its accounts, groups, accesses and keys to the others, and display the differences found by the last rounds",
+				Aliases:     []string{"replicationVerify"},
+			}, map[string]commands.Argument{
+				"report-only": {
+					Required:    false,
+					Description: "Only display the differences found by the last rounds, without starting a new one",
+					Type:        commands.BOOL,
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *ReplicationVerify) Checks(ct *commands.Context) error {
+	if !config.GetReplicationEnabled() {
+		return types.ErrCommandDisabled
+	}
+	return nil
+}
+
+// Execute executes the command
+func (c *ReplicationVerify) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	err = commands.Render(c, ct)
+	if err != nil {
+		return
+	}
+
+	if ct.FormattedArguments["report-only"] == "true" {
+		return
+	}
+
+	// The digests are computed by the daemons, as they can read the state of every account and group
+	round := uuid.New().String()
+	if ct.FormattedArguments["output"] == commands.OutputTable {
+		fmt.Printf("\nVerification round %s started: run this command again in a few moments to see its results.\n", round)
+	}
+
+	repl = models.ReplicationData{
+		"round": round,
+	}
+
+	return
+}
+
+// stateVerificationResult describes the last comparison of the state of a peer with the one of this instance
+type stateVerificationResult struct {
+	Peer        string                    `json:"peer" yaml:"peer"`
+	Round       string                    `json:"round" yaml:"round"`
+	ComparedAt  time.Time                 `json:"compared_at" yaml:"compared_at"`
+	Items       int                       `json:"items" yaml:"items"`
+	Differences []*models.StateDifference `json:"differences" yaml:"differences"`
+}
+
+// stateVerificationsResult describes the result of the command
+type stateVerificationsResult struct {
+	Verifications []*stateVerificationResult `json:"verifications" yaml:"verifications"`
+}
+
+// Result returns the last comparisons of the state of the peers with the one of this instance
+func (c *ReplicationVerify) Result(ct *commands.Context) (result commands.Result, err error) {
+
+	db, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	verifications, err := models.GetStateVerifications(db)
+	if err != nil {
+		return
+	}
+
+	res := &stateVerificationsResult{
+		Verifications: make([]*stateVerificationResult, 0, len(verifications)),
+	}
+	for _, v := range verifications {
+		differences, errDiff := v.GetDifferences()
+		if errDiff != nil {
+			return result, errDiff
+		}
+		res.Verifications = append(res.Verifications, &stateVerificationResult{
+			Peer:        v.Peer,
+			Round:       v.Round,
+			ComparedAt:  v.ComparedAt,
+			Items:       v.Items,
+			Differences: differences,
+		})
+	}
+
+	return res, nil
+}
+
+func (r *stateVerificationsResult) PrintTable() {
+
+	if len(r.Verifications) == 0 {
+		fmt.Println("The state of this instance was not compared with any other instance yet")
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	verifications := make([]string, 0, len(r.Verifications))
+	for _, v := range r.Verifications {
+		str := fmt.Sprintf("%s: %s | %s: %s | %s: %s (%d items)",
+			green("Peer"), v.Peer, green("Compared"), v.ComparedAt.Format(time.RFC3339), green("Round"), v.Round, v.Items,
+		)
+		if len(v.Differences) == 0 {
+			str += "\n    no difference"
+		}
+		for _, d := range v.Differences {
+			str += fmt.Sprintf("\n    %s: %s", yellow(d.Difference), d.Item)
+		}
+		verifications = append(verifications, str)
+	}
+
+	fmt.Printf("Here is the last comparison of the state of this instance with the other ones:\n%s\n", strings.Join(verifications, "\n"))
+}
+
+// PostExecute sends the digest of the state of this instance to the other ones
+func (c *ReplicationVerify) PostExecute(repl models.ReplicationData) (err error) {
+	if repl["round"] == "" || !config.GetReplicationEnabled() {
+		return
+	}
+	return models.PushStateDigest(repl["round"])
+}
+
+// Replicate sends the digest of the state of this instance to the other ones, for the round started elsewhere
+func (c *ReplicationVerify) Replicate(repl models.ReplicationData) (err error) {
+	if repl["round"] == "" {
+		return
+	}
+	return models.PushStateDigest(repl["round"])
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/models"
+	"github.com/pkg/errors"
+)
+
+// requestToReplicationData serializes a request as a replication data
+func requestToReplicationData(request *models.Request) (repl models.ReplicationData, err error) {
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+
+	repl = models.ReplicationData{
+		"request": string(requestJSON),
+	}
+
+	return
+}
+
+// saveReplicatedRequest saves the request carried by a replication data in the requests database.
+// Saving is an upsert, so replicating the same request twice is harmless.
+func saveReplicatedRequest(repl models.ReplicationData) (request *models.Request, err error) {
+
+	request = &models.Request{}
+	err = json.Unmarshal([]byte(repl["request"]), request)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to json.Unmarshal request")
+	}
+
+	db, err := models.GetRequestsGormDB(config.GetRequestsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	err = request.Save(db)
+
+	return
+}
+
+// getPendingRequest returns a request of the group that is still waiting for a decision
+func getPendingRequest(group, requestID string) (request *models.Request, err error) {
+
+	db, err := models.GetRequestsGormDB(config.GetRequestsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	request, err = models.GetRequest(db, requestID)
+	if err != nil {
+		return
+	}
+	if request.Group != group {
+		return nil, fmt.Errorf("request %s was not made to group %s", requestID, group)
+	}
+	if !request.IsPending() {
+		return nil, fmt.Errorf("request %s was already %s by %s", requestID, request.Status, request.DecidedBy)
+	}
+
+	return
+}
+
+// canDecideRequest returns true if the user is allowed to approve or deny this type of request for the group:
+// ACL keepers decide on access requests, gate keepers on membership requests
+func canDecideRequest(user *models.User, group, requestType string) bool {
+	switch requestType {
+	case models.RequestTypeAccess:
+		return user.IsACLKeeperOfGroup(group)
+	case models.RequestTypeMember:
+		return user.IsGateKeeperOfGroup(group)
+	}
+	return false
+}
+
+// decisionToReplicationData builds the replication data of a decision taken on a request
+func decisionToReplicationData(request *models.Request, status, decidedBy, comment string, data models.ReplicationData) (repl models.ReplicationData, err error) {
+
+	repl = models.ReplicationData{
+		"request-id":    request.UniqID,
+		"status":        status,
+		"decided-by":    decidedBy,
+		"decision-date": time.Now().UTC().Format(time.RFC3339),
+		"comment":       comment,
+	}
+
+	// Once approved, the data of the requested command is replicated as is
+	if data != nil {
+		command, err := request.GetCommand()
+		if err != nil {
+			return repl, err
+		}
+		dataJSON, err := json.Marshal(data)
+		if err != nil {
+			return repl, err
+		}
+		repl["command"] = command
+		repl["data"] = string(dataJSON)
+	}
+
+	return
+}
+
+// applyDecision runs the requested command's Replicate path if the request was approved,
+// then records the decision in the requests database
+func applyDecision(repl models.ReplicationData) (err error) {
+
+	db, err := models.GetRequestsGormDB(config.GetRequestsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	request, err := models.GetRequest(db, repl["request-id"])
+	if err != nil {
+		return
+	}
+
+	// The decision was already applied on this instance
+	if request.Status == repl["status"] {
+		return
+	}
+
+	decisionDate, err := time.Parse(time.RFC3339, repl["decision-date"])
+	if err != nil {
+		return
+	}
+
+	if repl["status"] == models.RequestStatusApproved {
+
+		data, err := getApprovedRequestData(request, repl)
+		if err != nil {
+			return err
+		}
+
+		cmd, _, _, _, err := commands.GetCommand(repl["command"])
+		if err != nil {
+			return errors.Wrapf(err, "unable to get command %s", repl["command"])
+		}
+
+		err = cmd.Replicate(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return request.Decide(db, repl["status"], repl["decided-by"], repl["comment"], decisionDate)
+}
+
+// getApprovedRequestData returns the data of the command to run for an approved request, after checking the command
+// and the data match the request: the command only depends on the type of the request, and the data on its group
+// and requester
+func getApprovedRequestData(request *models.Request, repl models.ReplicationData) (data models.ReplicationData, err error) {
+
+	command, err := request.GetCommand()
+	if err != nil {
+		return
+	}
+	if repl["command"] != command {
+		return nil, fmt.Errorf("the approval of request %s runs command %s instead of %s", request.UniqID, repl["command"], command)
+	}
+
+	err = json.Unmarshal([]byte(repl["data"]), &data)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to json.Unmarshal request data")
+	}
+
+	if data["group"] != request.Group {
+		return nil, fmt.Errorf("the approval of request %s acts on group %s instead of %s", request.UniqID, data["group"], request.Group)
+	}
+	if request.Type == models.RequestTypeMember && data["account"] != request.Requester {
+		return nil, fmt.Errorf("the approval of request %s adds account %s instead of %s", request.UniqID, data["account"], request.Requester)
+	}
+
+	return
+}
+
+// approvedRequestObjectKey returns the object an approval acts on, like the command it runs would
+func approvedRequestObjectKey(repl models.ReplicationData) string {
+
+	var data models.ReplicationData
+	if json.Unmarshal([]byte(repl["data"]), &data) != nil {
+		return ""
+	}
+
+	switch repl["command"] {
+	case "group access add":
+		return groupAccessObjectKey(data)
+	case "group member add":
+		return models.ObjectKey("group member", data["group"], data["account"])
+	}
+
+	return ""
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/inpher/sb/internal/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovedRequestData(t *testing.T) {
+
+	data := models.ReplicationData{
+		"group": "developers",
+		"host":  "10.0.0.1",
+		"user":  "root",
+		"port":  "22",
+	}
+	request, err := models.NewRequest(models.RequestTypeAccess, "developers", "alice", "group access add", "", data)
+	require.NoError(t, err)
+
+	repl, err := decisionToReplicationData(request, models.RequestStatusApproved, "carol", "", data)
+	require.NoError(t, err)
+	require.Equal(t, "group access add", repl["command"])
+
+	approved, err := getApprovedRequestData(request, repl)
+	require.NoError(t, err)
+	require.Equal(t, data, approved)
+
+	// The approval is versioned like a direct "group access add"
+	require.Equal(t, groupAccessObjectKey(data), approvedRequestObjectKey(repl))
+	require.Equal(t, "group access:developers:root:10.0.0.1/32:22", approvedRequestObjectKey(repl))
+
+	// The command run on approval only depends on the type of the request
+	tampered := *request
+	tampered.Command = "account delete"
+	_, err = decisionToReplicationData(&tampered, models.RequestStatusApproved, "carol", "", data)
+	require.Error(t, err)
+	_, err = getApprovedRequestData(&tampered, repl)
+	require.Error(t, err)
+
+	forged := models.ReplicationData{}
+	for k, v := range repl {
+		forged[k] = v
+	}
+	forged["command"] = "account delete"
+	_, err = getApprovedRequestData(request, forged)
+	require.Error(t, err)
+
+	// The approval acts on the group of the request, and adds its requester
+	forged["command"] = "group access add"
+	forged["data"] = `{"group":"owners","host":"10.0.0.1","user":"root","port":"22"}`
+	_, err = getApprovedRequestData(request, forged)
+	require.Error(t, err)
+
+	member, err := models.NewRequest(models.RequestTypeMember, "developers", "bob", "group member add", "", nil)
+	require.NoError(t, err)
+	repl, err = decisionToReplicationData(member, models.RequestStatusApproved, "carol", "", models.ReplicationData{"group": "developers", "account": "mallory"})
+	require.NoError(t, err)
+	_, err = getApprovedRequestData(member, repl)
+	require.Error(t, err)
+	require.Equal(t, "group member:developers:mallory", approvedRequestObjectKey(repl))
+}
@@ -67,6 +67,27 @@ func (c *Restore) Execute(ct *commands.Context) (repl models.ReplicationData, cm
 		return
 	}
 
+	err = restoreArchive(f, nil)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Backup successfully restored\n")
+
+	return
+}
+
+func (c *Restore) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *Restore) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
+
+// restoreArchive extracts a backup archive on the filesystem, but the files read by the provided handlers, by name
+func restoreArchive(archive io.Reader, handlers map[string]func(io.Reader) error) (err error) {
+
 	format := archiver.CompressedArchive{
 		Compression: archiver.Gz{},
 		Archival:    archiver.Tar{},
@@ -74,6 +95,15 @@ func (c *Restore) Execute(ct *commands.Context) (repl models.ReplicationData, cm
 
 	handler := func(ctx context.Context, f archiver.File) error {
 
+		if readFile, ok := handlers[f.NameInArchive]; ok {
+			src, err := f.Open()
+			if err != nil {
+				return errors.Wrap(err, "unable to open file for read")
+			}
+			defer src.Close()
+			return readFile(src)
+		}
+
 		stat := f.Sys().(*tar.Header)
 
 		if f.IsDir() {
@@ -95,7 +125,7 @@ func (c *Restore) Execute(ct *commands.Context) (repl models.ReplicationData, cm
 			return errors.Wrap(err, "unable to open file for read")
 		}
 
-		dst, err := os.OpenFile(f.NameInArchive, os.O_RDWR|os.O_CREATE, f.Mode().Perm())
+		dst, err := os.OpenFile(f.NameInArchive, os.O_RDWR|os.O_CREATE|os.O_TRUNC, f.Mode().Perm())
 		if err != nil {
 			return errors.Wrap(err, "unable to open file for write")
 		}
@@ -114,21 +144,10 @@ func (c *Restore) Execute(ct *commands.Context) (repl models.ReplicationData, cm
 		return nil
 	}
 
-	err = format.Extract(context.Background(), f, nil, handler)
+	err = format.Extract(context.Background(), archive, nil, handler)
 	if err != nil {
 		err = errors.Wrap(err, "unable to restore backup file")
-		return
 	}
 
-	fmt.Printf("Backup successfully restored\n")
-
-	return
-}
-
-func (c *Restore) PostExecute(repl models.ReplicationData) (err error) {
-	return
-}
-
-func (c *Restore) Replicate(repl models.ReplicationData) (err error) {
 	return
 }
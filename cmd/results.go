@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// accessResult describes an access, as rendered by the listing commands
+type accessResult struct {
+	Source            string     `json:"source" yaml:"source"`
+	Group             string     `json:"group,omitempty" yaml:"group,omitempty"`
+	Prefix            string     `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Host              string     `json:"host,omitempty" yaml:"host,omitempty"`
+	Alias             string     `json:"alias,omitempty" yaml:"alias,omitempty"`
+	Selector          string     `json:"selector,omitempty" yaml:"selector,omitempty"`
+	User              string     `json:"user" yaml:"user"`
+	Port              int        `json:"port" yaml:"port"`
+	Comment           string     `json:"comment,omitempty" yaml:"comment,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	ForwardingAllowed bool       `json:"forwarding_allowed" yaml:"forwarding_allowed"`
+
+	access *models.Access
+}
+
+func newAccessResult(source, group string, ba *models.Access) *accessResult {
+
+	ar := &accessResult{
+		Source:            source,
+		Group:             group,
+		Prefix:            ba.Prefix,
+		Host:              ba.Host,
+		Alias:             ba.Alias,
+		Selector:          ba.Selector,
+		User:              ba.User,
+		Port:              ba.Port,
+		Comment:           ba.Comment,
+		ForwardingAllowed: ba.ForwardingAllowed,
+		access:            ba,
+	}
+	if !ba.ExpiresAt.IsZero() {
+		expiresAt := ba.ExpiresAt.UTC()
+		ar.ExpiresAt = &expiresAt
+	}
+
+	return ar
+}
+
+// sessionResult describes a recorded SSH session, as rendered by the listing commands
+type sessionResult struct {
+	ID        string     `json:"id" yaml:"id"`
+	Allowed   bool       `json:"allowed" yaml:"allowed"`
+	StartDate time.Time  `json:"start_date" yaml:"start_date"`
+	EndDate   *time.Time `json:"end_date,omitempty" yaml:"end_date,omitempty"`
+	UserFrom  string     `json:"user_from" yaml:"user_from"`
+	IPFrom    string     `json:"ip_from" yaml:"ip_from"`
+	PortFrom  string     `json:"port_from" yaml:"port_from"`
+	HostFrom  string     `json:"host_from" yaml:"host_from"`
+	UserTo    string     `json:"user_to" yaml:"user_to"`
+	HostTo    string     `json:"host_to" yaml:"host_to"`
+	PortTo    string     `json:"port_to" yaml:"port_to"`
+	Instance  string     `json:"instance,omitempty" yaml:"instance,omitempty"`
+
+	session *helpers.SSHSession
+}
+
+func newSessionResults(sessions []*helpers.SSHSession) []*sessionResult {
+
+	results := make([]*sessionResult, 0, len(sessions))
+	for _, s := range sessions {
+		sr := &sessionResult{
+			ID:        s.UniqID,
+			Allowed:   s.Allowed,
+			StartDate: s.StartDate.UTC(),
+			UserFrom:  s.UserFrom,
+			IPFrom:    s.IPFrom,
+			PortFrom:  s.PortFrom,
+			HostFrom:  s.HostFrom,
+			UserTo:    s.UserTo,
+			HostTo:    s.HostTo,
+			PortTo:    s.PortTo,
+			Instance:  s.BastionHost,
+			session:   s,
+		}
+		if !s.EndDate.IsZero() {
+			endDate := s.EndDate.UTC()
+			sr.EndDate = &endDate
+		}
+		results = append(results, sr)
+	}
+
+	return results
+}
@@ -193,6 +193,34 @@ func TestBuildSBCommand(t *testing.T) {
 			i: commandTestStructureInputData{user: user, arguments: []string{"group accesses list", "--group", "everyone"}},
 			o: nil,
 		},
+		{
+			i: commandTestStructureInputData{user: user, arguments: []string{"admin sessions list"}},
+			o: fmt.Errorf("user is not an auditor"),
+		},
+		{
+			i: commandTestStructureInputData{user: owner, arguments: []string{"admin sessions list", "--limit", "0"}},
+			o: fmt.Errorf("argument limit should be a positive integer"),
+		},
+		{
+			i: commandTestStructureInputData{user: owner, arguments: []string{"admin sessions list", "--since", "7d"}},
+			o: nil,
+		},
+		{
+			i: commandTestStructureInputData{user: user, arguments: []string{"group requests list", "--group", "everyone"}},
+			o: fmt.Errorf("user is neither an ACL keeper nor a gate keeper of the group"),
+		},
+		{
+			i: commandTestStructureInputData{user: user, arguments: []string{"group access request", "--group", "developers", "--host", "127.0.0.1", "--user", "root"}},
+			o: fmt.Errorf("user is not a member of the group"),
+		},
+		{
+			i: commandTestStructureInputData{user: user, arguments: []string{"group accesses list", "--group", "everyone", "--output", "json"}},
+			o: nil,
+		},
+		{
+			i: commandTestStructureInputData{user: user, arguments: []string{"group accesses list", "--group", "everyone", "--output", "xml"}},
+			o: fmt.Errorf("argument --output's value should be from the list: table, json, yaml"),
+		},
 	}
 
 	for _, testData := range tests {
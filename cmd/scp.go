@@ -12,6 +12,7 @@ import (
 	"github.com/inpher/sb/internal/config"
 	"github.com/inpher/sb/internal/helpers"
 	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/sshclient"
 
 	"golang.org/x/term"
 )
@@ -112,6 +113,10 @@ func (c *Scp) Execute(ct *commands.Context) (repl models.ReplicationData, cmdErr
 	}
 	ct.Log.SetTargetAccess(access)
 
+	if config.GetSSHClient() == "native" {
+		return c.executeNative(ct, access)
+	}
+
 	// Get ssh command path on the system
 	sshPath, err := exec.LookPath("ssh")
 	if err != nil {
@@ -160,6 +165,24 @@ func (c *Scp) Execute(ct *commands.Context) (repl models.ReplicationData, cmdErr
 	return
 }
 
+// executeNative runs the scp command on the distant host with the native SSH client, stdin and stdout carrying the transfer
+func (c *Scp) executeNative(ct *commands.Context, access *models.Access) (repl models.ReplicationData, cmdError error, err error) {
+
+	client, err := newEgressClient(ct, access, false, false)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	err = client.Run(ct.FormattedArguments["scp-cmd"], os.Stdin, os.Stdout, os.Stderr)
+	if sshclient.ExitStatus(err) > 0 {
+		cmdError = err
+		err = nil
+	}
+
+	return
+}
+
 func (c *Scp) PostExecute(repl models.ReplicationData) (err error) {
 	return
 }
@@ -17,7 +17,7 @@ func init() {
 	commands.RegisterCommand("self access add", func() (c commands.Command, r models.Right, helper helpers.Helper, args map[string]commands.Argument) {
 		return new(SelfAddAccess), models.Public, helpers.Helper{
 				Header:      "add a personal access to a distant host",
-				Usage:       "self access add --host HOST --user USER [--port PORT --alias ALIAS]",
+				Usage:       "self access add --host HOST --user USER [--port PORT --alias ALIAS --expires-in DURATION | --expires-at DATE --forwarding-allowed]",
 				Description: "add a personal access to a distant host. Your personal egress key will be required to be on the distant host to connect to it.",
 				Aliases:     []string{"selfAddAccess"},
 			}, map[string]commands.Argument{
@@ -38,6 +38,19 @@ func init() {
 					Required:    false,
 					Description: "An optional alias to this access (to enable quick access by typing 'sb alias' or 'sb user@alias')",
 				},
+				"expires-in": {
+					Required:    false,
+					Description: "An optional duration after which the access expires (e.g. 12h, 14d, 2w)",
+				},
+				"expires-at": {
+					Required:    false,
+					Description: "An optional date at which the access expires, in RFC3339 format (e.g. 2006-01-02T15:04:05Z)",
+				},
+				"forwarding-allowed": {
+					Required:    false,
+					Description: "Allow this access to be used for port forwarding (see the forward command)",
+					Type:        commands.BOOL,
+				},
 			}
 	})
 }
@@ -52,6 +65,11 @@ func (c *SelfAddAccess) Checks(ct *commands.Context) error {
 		}
 	}
 
+	_, err := helpers.ParseExpiration(ct.FormattedArguments["expires-in"], ct.FormattedArguments["expires-at"], time.Now())
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -59,12 +77,22 @@ func (c *SelfAddAccess) Checks(ct *commands.Context) error {
 func (c *SelfAddAccess) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
 
 	repl = models.ReplicationData{
-		"account": ct.User.User.Username,
-		"host":    ct.FormattedArguments["host"],
-		"user":    ct.FormattedArguments["user"],
-		"port":    ct.FormattedArguments["port"],
-		"alias":   ct.FormattedArguments["alias"],
-		"comment": fmt.Sprintf("Access granted by %s on %s", ct.User.User.Username, time.Now().Format(time.RFC3339)),
+		"account":            ct.User.User.Username,
+		"host":               ct.FormattedArguments["host"],
+		"user":               ct.FormattedArguments["user"],
+		"port":               ct.FormattedArguments["port"],
+		"alias":              ct.FormattedArguments["alias"],
+		"comment":            fmt.Sprintf("Access granted by %s on %s", ct.User.User.Username, time.Now().Format(time.RFC3339)),
+		"forwarding-allowed": ct.FormattedArguments["forwarding-allowed"],
+	}
+
+	// We store an absolute expiration date, so that all instances expire the access at the same time
+	expiresAt, err := helpers.ParseExpiration(ct.FormattedArguments["expires-in"], ct.FormattedArguments["expires-at"], time.Now())
+	if err != nil {
+		return
+	}
+	if !expiresAt.IsZero() {
+		repl["expires-at"] = expiresAt.Format(time.RFC3339)
 	}
 
 	err = c.Replicate(repl)
@@ -76,6 +104,11 @@ func (c *SelfAddAccess) PostExecute(repl models.ReplicationData) (err error) {
 	return
 }
 
+// ObjectKey returns the personal access, which "self access add" and "self access remove" act on
+func (c *SelfAddAccess) ObjectKey(repl models.ReplicationData) string {
+	return models.AccessObjectKey("self access", repl["account"], repl["host"], repl["user"], repl["port"])
+}
+
 func (c *SelfAddAccess) Replicate(repl models.ReplicationData) (err error) {
 
 	user, err := models.LoadUser(repl["account"])
@@ -83,12 +116,23 @@ func (c *SelfAddAccess) Replicate(repl models.ReplicationData) (err error) {
 		return
 	}
 
+	// Entries replicated from older instances don't carry an expiration date
+	var expiresAt time.Time
+	if repl["expires-at"] != "" {
+		expiresAt, err = time.Parse(time.RFC3339, repl["expires-at"])
+		if err != nil {
+			return
+		}
+	}
+
 	ba, err := user.AddAccess(
 		repl["host"],
 		repl["user"],
 		repl["port"],
 		repl["alias"],
 		repl["comment"],
+		expiresAt,
+		repl["forwarding-allowed"] == "true",
 	)
 	if err != nil {
 		return
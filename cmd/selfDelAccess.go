@@ -59,6 +59,11 @@ func (c *SelfDelAccess) PostExecute(repl models.ReplicationData) (err error) {
 	return
 }
 
+// ObjectKey returns the personal access, which "self access add" and "self access remove" act on
+func (c *SelfDelAccess) ObjectKey(repl models.ReplicationData) string {
+	return models.AccessObjectKey("self access", repl["account"], repl["host"], repl["user"], repl["port"])
+}
+
 func (c *SelfDelAccess) Replicate(repl models.ReplicationData) (err error) {
 
 	user, err := models.LoadUser(repl["account"])
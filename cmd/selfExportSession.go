@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// SelfExportSession describes the selfExportSession command
+type SelfExportSession struct{}
+
+func init() {
+	commands.RegisterCommand("self session export", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(SelfExportSession), models.Public, helpers.Helper{
+				Header:      "export a recording of an SSH session as asciicast, ttyrec or gif",
+				Usage:       "self session export --session-id SESSION-ID [--format asciicast|ttyrec|gif]",
+				Description: "export a recording of an SSH session as asciicast v2 (with the keystrokes, if they were recorded), ttyrec or gif",
+				Aliases:     []string{"selfExportSession"},
+			}, map[string]commands.Argument{
+				"session-id": {
+					Required:    true,
+					Description: "The session recording ID to export",
+				},
+				"format": {
+					Required:      false,
+					Description:   "The format of the export",
+					AllowedValues: []string{"asciicast", "ttyrec", "gif"},
+					DefaultValue:  "asciicast",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *SelfExportSession) Checks(ct *commands.Context) error {
+	// No specific rights needed but a sb account
+	return nil
+}
+
+// Execute executes the command
+func (c *SelfExportSession) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+
+	sessions, err := models.GetSSHSessions(ct.User.GetLocalLogDatabasePath(), &models.SSHSessionsFilter{SessionID: ct.FormattedArguments["session-id"]})
+	if err != nil {
+		return
+	}
+	if len(sessions) == 0 {
+		err = fmt.Errorf("session %s not found", ct.FormattedArguments["session-id"])
+		return
+	}
+
+	ttyrecDirectory := ct.User.GetTtyrecDirectory()
+
+	if ct.FormattedArguments["format"] == "asciicast" {
+		err = writeSessionRecordAsAsciicast(sessions[0], ttyrecDirectory, ttyrecDirectory)
+		return
+	}
+
+	localFilepath, cleanup, err := getSessionRecord(ttyrecDirectory, ttyrecDirectory, sessions[0].UniqID, false)
+	if err != nil {
+		return
+	}
+
+	if ct.FormattedArguments["format"] == "gif" {
+		err = writeSessionRecordAsGif(localFilepath, fmt.Sprintf("%s/%s.ttyrec.gif", ttyrecDirectory, sessions[0].UniqID), 1.0, false)
+	} else {
+		err = writeSessionRecordAsTtyrec(localFilepath)
+	}
+	if err != nil {
+		cleanup()
+		return
+	}
+
+	err = cleanup()
+
+	return
+}
+
+func (c *SelfExportSession) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *SelfExportSession) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
@@ -31,19 +31,42 @@ func (c *SelfListAccesses) Checks(ct *commands.Context) error {
 
 // Execute executes the command
 func (c *SelfListAccesses) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// selfAccessesResult describes the result of the command
+type selfAccessesResult struct {
+	Accesses []*accessResult `json:"accesses" yaml:"accesses"`
+}
+
+// Result returns the accesses of the account, personal and inherited from groups
+func (c *SelfListAccesses) Result(ct *commands.Context) (result commands.Result, err error) {
 
 	accesses, err := ct.User.GetAccesses()
 	if err != nil {
 		return
 	}
 
-	allAccesses := make([]string, 0)
+	res := &selfAccessesResult{
+		Accesses: make([]*accessResult, 0),
+	}
 	for _, source := range accesses {
 		for _, host := range source.Accesses {
-			allAccesses = append(allAccesses, host.String())
+			res.Accesses = append(res.Accesses, newAccessResult(source.Type, source.Group, host))
 		}
 	}
 
+	return res, nil
+}
+
+func (r *selfAccessesResult) PrintTable() {
+
+	allAccesses := make([]string, 0, len(r.Accesses))
+	for _, ar := range r.Accesses {
+		allAccesses = append(allAccesses, ar.access.String())
+	}
+
 	if len(allAccesses) > 0 {
 		fmt.Printf(
 			"Here is the list of your accessible distant hosts:\n%s\n",
@@ -52,8 +75,6 @@ func (c *SelfListAccesses) Execute(ct *commands.Context) (repl models.Replicatio
 	} else {
 		fmt.Println("You currently don't have any access to distant hosts")
 	}
-
-	return
 }
 
 func (c *SelfListAccesses) PostExecute(repl models.ReplicationData) (err error) {
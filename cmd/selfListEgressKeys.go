@@ -30,15 +30,38 @@ func (c *SelfListEgressKeys) Checks(ct *commands.Context) error {
 
 // Execute executes the command
 func (c *SelfListEgressKeys) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// selfEgressKeysResult describes the result of the command
+type selfEgressKeysResult struct {
+	Keys []string `json:"keys" yaml:"keys"`
+
+	str string
+}
+
+// Result returns the egress public SSH keys of the account
+func (c *SelfListEgressKeys) Result(ct *commands.Context) (result commands.Result, err error) {
 
-	str, _, err := ct.User.DisplayPubKeys("egress")
+	str, keys, err := ct.User.DisplayPubKeys("egress")
 	if err != nil {
 		return
 	}
 
-	fmt.Printf("Here is the list of your egress public SSH keys (sb -> distant host):\n%s\n", str)
+	res := &selfEgressKeysResult{
+		Keys: make([]string, 0, len(keys)),
+		str:  str,
+	}
+	for _, key := range keys {
+		res.Keys = append(res.Keys, key.String())
+	}
 
-	return
+	return res, nil
+}
+
+func (r *selfEgressKeysResult) PrintTable() {
+	fmt.Printf("Here is the list of your egress public SSH keys (sb -> distant host):\n%s\n", r.str)
 }
 
 func (c *SelfListEgressKeys) PostExecute(repl models.ReplicationData) (err error) {
@@ -30,15 +30,38 @@ func (c *SelfListIngressKeys) Checks(ct *commands.Context) error {
 
 // Execute executes the command
 func (c *SelfListIngressKeys) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// selfIngressKeysResult describes the result of the command
+type selfIngressKeysResult struct {
+	Keys []string `json:"keys" yaml:"keys"`
+
+	str string
+}
+
+// Result returns the ingress public SSH keys of the account
+func (c *SelfListIngressKeys) Result(ct *commands.Context) (result commands.Result, err error) {
 
-	str, _, err := ct.User.DisplayPubKeys("ingress")
+	str, keys, err := ct.User.DisplayPubKeys("ingress")
 	if err != nil {
 		return
 	}
 
-	fmt.Printf("Here is the list of your current ingress public SSH keys (you -> sb):\n%s\n", str)
+	res := &selfIngressKeysResult{
+		Keys: make([]string, 0, len(keys)),
+		str:  str,
+	}
+	for _, key := range keys {
+		res.Keys = append(res.Keys, key.String())
+	}
 
-	return
+	return res, nil
+}
+
+func (r *selfIngressKeysResult) PrintTable() {
+	fmt.Printf("Here is the list of your current ingress public SSH keys (you -> sb):\n%s\n", r.str)
 }
 
 func (c *SelfListIngressKeys) PostExecute(repl models.ReplicationData) (err error) {
@@ -31,15 +31,31 @@ func (c *SelfListSessions) Checks(ct *commands.Context) error {
 
 // Execute executes the command
 func (c *SelfListSessions) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// selfSessionsResult describes the result of the command
+type selfSessionsResult struct {
+	Sessions []*sessionResult `json:"sessions" yaml:"sessions"`
+}
+
+// Result returns the last 20 SSH sessions of the account
+func (c *SelfListSessions) Result(ct *commands.Context) (result commands.Result, err error) {
 
 	lastSessions, err := ct.User.GetLastSSHSessions(20)
 	if err != nil {
 		return
 	}
 
-	sessions := make([]string, 0, len(lastSessions))
-	for id, session := range lastSessions {
-		sessions = append(sessions, fmt.Sprintf("%02d: %s", id+1, session.String()))
+	return &selfSessionsResult{Sessions: newSessionResults(lastSessions)}, nil
+}
+
+func (r *selfSessionsResult) PrintTable() {
+
+	sessions := make([]string, 0, len(r.Sessions))
+	for id, sr := range r.Sessions {
+		sessions = append(sessions, fmt.Sprintf("%02d: %s", id+1, sr.session.String()))
 	}
 
 	if len(sessions) > 0 {
@@ -50,8 +66,6 @@ func (c *SelfListSessions) Execute(ct *commands.Context) (repl models.Replicatio
 	} else {
 		fmt.Println("You currently don't have any recorded SSH session")
 	}
-
-	return
 }
 
 func (c *SelfListSessions) PostExecute(repl models.ReplicationData) (err error) {
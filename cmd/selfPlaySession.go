@@ -2,104 +2,100 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"time"
 
 	"github.com/inpher/sb/internal/commands"
-	"github.com/inpher/sb/internal/config"
 	"github.com/inpher/sb/internal/helpers"
 	"github.com/inpher/sb/internal/models"
-	"github.com/inpher/sb/internal/storage"
-	"github.com/pkg/errors"
-	"maze.io/x/ttyrec"
+	"github.com/inpher/sb/internal/recording"
 )
 
 // SelfPlaySession describes the selfListAccesses command
-type SelfPlaySession struct{}
+type SelfPlaySession struct {
+	options recording.PlayerOptions
+}
 
 func init() {
 	commands.RegisterCommand("self session replay", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
 		return new(SelfPlaySession), models.Public, helpers.Helper{
 				Header:      "watch a recording of an SSH session",
-				Usage:       "self session replay",
-				Description: "watch a recording of an SSH session",
+				Usage:       "self session replay --session-id SESSION-ID|--file FILE [--speed SPEED --max-idle DURATION --from OFFSET --to OFFSET --show-input]",
+				Description: `watch a recording of an SSH session, or a ttyrec or asciicast v2 file.
+             While watching: space pauses and resumes, "." steps to the next frame while paused,
+             "+" and "-" double and halve the speed, "f" skips 10 seconds ahead and "q" quits.`,
 				Aliases:     []string{"selfPlaySession"},
 			}, map[string]commands.Argument{
 				"session-id": {
-					Required:    true,
+					Required:    false,
 					Description: "The session recording ID to watch",
 				},
+				"file": {
+					Required:    false,
+					Description: "A ttyrec or asciicast v2 file to watch instead of a session recording",
+				},
+				"speed": {
+					Required:     false,
+					Description:  "The play speed factor of the session",
+					DefaultValue: "1.0",
+				},
+				"max-idle": {
+					Required:    false,
+					Description: "Compress the idle times longer than this duration (e.g. 2s)",
+				},
+				"from": {
+					Required:    false,
+					Description: "Start the replay at this offset of the session (e.g. 1h15m)",
+				},
+				"to": {
+					Required:    false,
+					Description: "Stop the replay at this offset of the session (e.g. 1h30m)",
+				},
+				"show-input": {
+					Required:    false,
+					Description: "Show the keystrokes of the session, if they were recorded",
+					Type:        commands.BOOL,
+				},
 			}
 	})
 }
 
 // Checks checks whether or not the user can execute this method
 func (c *SelfPlaySession) Checks(ct *commands.Context) error {
+
 	// No specific rights needed but a sb account
+	if (ct.FormattedArguments["session-id"] == "") == (ct.FormattedArguments["file"] == "") {
+		return fmt.Errorf("either argument session-id or file is required")
+	}
+
+	options, err := parsePlayerOptions(ct.FormattedArguments)
+	if err != nil {
+		return err
+	}
+	c.options = options
+
 	return nil
 }
 
 // Execute executes the command
 func (c *SelfPlaySession) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
 
-	filename := fmt.Sprintf("%s.ttyrec", ct.FormattedArguments["session-id"])
-	localFilepath := fmt.Sprintf("%s/%s", ct.User.GetTtyrecDirectory(), filename)
-
-	// If TTYRecs offloading is enabled, we start by getting the ttyrec file from a storage
-	ttyRecsOffloadingConfig := config.GetTTYRecsOffloadingConfig()
-	if ttyRecsOffloadingConfig.Enabled {
-
-		var rs storage.Storage
-		rs, err = storage.GetStorage(ttyRecsOffloadingConfig)
-		if err != nil {
-			return
-		}
-
-		err = rs.GetFromStorage(fmt.Sprintf("%s.bin", filename), fmt.Sprintf("%s.bin", localFilepath))
-		if err != nil {
-			return
-		}
-
-		err = helpers.DecryptFile(fmt.Sprintf("%s.bin", localFilepath), localFilepath, config.GetEncryptionKey())
-		if err != nil {
-			return
-		}
-
-		err = os.Remove(fmt.Sprintf("%s.bin", localFilepath))
-		if err != nil {
-			return
-		}
+	_, showInput := ct.FormattedArguments["show-input"]
+
+	if ct.FormattedArguments["file"] != "" {
+		err = replayFile(ct.FormattedArguments["file"], showInput, c.options)
+		return
 	}
 
-	r, err := os.Open(localFilepath)
+	localFilepath, cleanup, err := getSessionRecord(ct.User.GetTtyrecDirectory(), ct.User.GetTtyrecDirectory(), ct.FormattedArguments["session-id"], showInput)
 	if err != nil {
-		err = errors.Wrap(err, "file not found")
 		return
 	}
 
-	d := ttyrec.NewDecoder(r)
-	frames, stop := d.DecodeStream()
-	defer stop()
-
-	var previous *ttyrec.Frame
-	for frame := range frames {
-		if _, errFrame := os.Stdout.Write(frame.Data); err != nil {
-			err = errors.Wrap(errFrame, "error writing frame")
-			return
-		}
-		if previous != nil {
-			d := frame.Time.Sub(previous.Time)
-			time.Sleep(time.Duration(float64(d)))
-		}
-		previous = frame
+	err = replaySessionRecord(localFilepath, c.options)
+	if err != nil {
+		return
 	}
 
-	if ttyRecsOffloadingConfig.Enabled {
-		err = os.Remove(localFilepath)
-		if err != nil {
-			return
-		}
-	}
+	err = cleanup()
 
 	return
 }
@@ -0,0 +1,546 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/recording"
+	"github.com/inpher/sb/internal/storage"
+	"github.com/pkg/errors"
+
+	"github.com/golgeek/ttyrec2gif"
+	"golang.org/x/term"
+	"gorm.io/gorm"
+)
+
+// getSessionRecord returns the local path of a session recording.
+// If TTYRecs offloading is enabled, the recording is fetched from the storage into the work directory,
+// and the returned cleanup function removes it once the caller is done with it.
+// If showInput is set, the returned recording shows the keystrokes of the session among its output.
+func getSessionRecord(ttyrecDirectory, workDirectory, sessionID string, showInput bool) (localFilepath string, cleanup func() error, err error) {
+
+	localFilepath, cleanup, err = fetchSessionRecord(ttyrecDirectory, workDirectory, fmt.Sprintf("%s.ttyrec", sessionID))
+	if err != nil || !showInput {
+		return
+	}
+
+	inputFilepath, cleanupInput, err := fetchSessionRecord(ttyrecDirectory, workDirectory, fmt.Sprintf("%s.input.ttyrec", sessionID))
+	if err != nil {
+		cleanup()
+		err = errors.Wrapf(err, "no input was recorded for session %s", sessionID)
+		return
+	}
+
+	mergedFilepath := filepath.Join(workDirectory, fmt.Sprintf("%s.merged.ttyrec", sessionID))
+	err = mergeSessionRecords(localFilepath, inputFilepath, mergedFilepath)
+	errCleanup := cleanup()
+	if errCleanup == nil {
+		errCleanup = cleanupInput()
+	}
+	if err == nil {
+		err = errCleanup
+	}
+	if err != nil {
+		os.Remove(mergedFilepath)
+		return
+	}
+
+	localFilepath = mergedFilepath
+	cleanup = func() error {
+		return os.Remove(mergedFilepath)
+	}
+
+	return
+}
+
+// mergeSessionRecords writes the output and the input recordings of a session as a single recording
+func mergeSessionRecords(outputFilepath, inputFilepath, mergedFilepath string) (err error) {
+
+	output, err := os.Open(outputFilepath)
+	if err != nil {
+		return errors.Wrap(err, "file not found")
+	}
+	defer output.Close()
+
+	input, err := os.Open(inputFilepath)
+	if err != nil {
+		return errors.Wrap(err, "file not found")
+	}
+	defer input.Close()
+
+	merged, err := os.Create(mergedFilepath)
+	if err != nil {
+		return errors.Wrap(err, "unable to create the merged recording")
+	}
+	defer merged.Close()
+
+	w := bufio.NewWriter(merged)
+	err = recording.Merge(bufio.NewReader(output), bufio.NewReader(input), w)
+	if err != nil {
+		return
+	}
+
+	return w.Flush()
+}
+
+// fetchSessionRecord returns the local path of a recording file, fetched from the storage into the work directory
+// if TTYRecs offloading is enabled
+func fetchSessionRecord(ttyrecDirectory, workDirectory, filename string) (localFilepath string, cleanup func() error, err error) {
+
+	localFilepath = filepath.Join(ttyrecDirectory, filename)
+	cleanup = func() error { return nil }
+
+	// If TTYRecs offloading is disabled, the recording is on the local disk
+	ttyRecsOffloadingConfig := config.GetTTYRecsOffloadingConfig()
+	if !ttyRecsOffloadingConfig.Enabled {
+		_, err = os.Stat(localFilepath)
+		if err != nil {
+			err = errors.Wrap(err, "file not found")
+		}
+		return
+	}
+
+	rs, err := storage.GetStorage(ttyRecsOffloadingConfig)
+	if err != nil {
+		return
+	}
+
+	localFilepath = filepath.Join(workDirectory, filename)
+	encryptedFilepath := fmt.Sprintf("%s.bin", localFilepath)
+
+	err = rs.GetFromStorage(fmt.Sprintf("%s.bin", filename), encryptedFilepath)
+	if err != nil {
+		return
+	}
+
+	err = helpers.DecryptFile(encryptedFilepath, localFilepath, config.GetEncryptionKey())
+	if err != nil {
+		return
+	}
+
+	err = os.Remove(encryptedFilepath)
+	if err != nil {
+		return
+	}
+
+	cleanup = func() error {
+		return os.Remove(localFilepath)
+	}
+
+	return
+}
+
+// replaySessionRecord writes the frames of a session recording to stdout, respecting the delays between frames.
+// If stdin is a terminal, the keys it receives control the replay.
+func replaySessionRecord(localFilepath string, options recording.PlayerOptions) (err error) {
+
+	r, err := os.Open(localFilepath)
+	if err != nil {
+		return errors.Wrap(err, "file not found")
+	}
+	defer r.Close()
+
+	controls, restore, err := readControls()
+	if err != nil {
+		return
+	}
+	defer restore()
+
+	return recording.NewPlayer(options, controls).Play(bufio.NewReader(r), os.Stdout)
+}
+
+// readControls puts the terminal in raw mode and returns the keys pressed by the user, to control a replay.
+// The keys pressed while the replay doesn't wait for them are dropped. Once restored, the terminal isn't read anymore:
+// the next keys are left to the prompt of sb.
+func readControls() (controls chan byte, restore func(), err error) {
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, func() {}, nil
+	}
+
+	input, err := helpers.NewCancelableReader(os.Stdin)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to read the terminal")
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to set the terminal in raw mode")
+	}
+
+	controls = make(chan byte, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(controls)
+		key := make([]byte, 1)
+		for {
+			if _, errRead := input.Read(key); errRead != nil {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case controls <- key[0]:
+			default:
+			}
+		}
+	}()
+
+	var once sync.Once
+	restore = func() {
+		once.Do(func() {
+			close(done)
+			input.Cancel()
+			term.Restore(fd, state)
+		})
+	}
+
+	return controls, restore, nil
+}
+
+// getRunningSession returns a session running on this instance, with the account it belongs to
+func getRunningSession(sessionID string) (session *helpers.SSHSession, user *models.User, err error) {
+
+	sessions, err := models.GetSSHSessions(config.GetGlobalDatabasePath(), &models.SSHSessionsFilter{SessionID: sessionID})
+	if err != nil {
+		return
+	}
+	if len(sessions) == 0 {
+		return nil, nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	session = sessions[0]
+
+	if !session.EndDate.IsZero() {
+		return nil, nil, fmt.Errorf("session %s is over, it can be replayed with admin session replay", sessionID)
+	}
+	if session.ProcessID == 0 {
+		return nil, nil, fmt.Errorf("session %s was started by a former version of sb, it can't be followed", sessionID)
+	}
+
+	hostname, err := helpers.GetHostname()
+	if err != nil {
+		return
+	}
+	if session.BastionHost != hostname {
+		return nil, nil, fmt.Errorf("session %s is handled by instance %s, connect to it to follow the session", sessionID, session.BastionHost)
+	}
+
+	user, err = models.LoadUser(session.UserFrom)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to load account %s", session.UserFrom)
+	}
+
+	if !sessionProcessRunning(session, user) {
+		return nil, nil, fmt.Errorf("session %s is over, it can be replayed with admin session replay", sessionID)
+	}
+
+	return
+}
+
+// sessionProcessRunning returns whether the sb process handling a session is still running.
+// The process must belong to the account, its PID could have been reused otherwise.
+func sessionProcessRunning(session *helpers.SSHSession, user *models.User) bool {
+
+	info, err := os.Stat(fmt.Sprintf("/proc/%d", session.ProcessID))
+	if err != nil {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && strconv.FormatUint(uint64(stat.Uid), 10) == user.User.Uid
+}
+
+// parsePlayerOptions parses the arguments of the replay commands
+func parsePlayerOptions(arguments map[string]string) (options recording.PlayerOptions, err error) {
+
+	options.Speed, err = strconv.ParseFloat(arguments["speed"], 64)
+	if err != nil || options.Speed <= 0 {
+		return options, fmt.Errorf("argument speed should be a positive number")
+	}
+
+	for name, value := range map[string]*time.Duration{
+		"max-idle": &options.MaxIdle,
+		"from":     &options.From,
+		"to":       &options.To,
+	} {
+		if arguments[name] == "" {
+			continue
+		}
+		*value, err = helpers.ParseDuration(arguments[name])
+		if err != nil || *value < 0 {
+			return options, fmt.Errorf("argument %s should be a duration (e.g. 90s, 1h30m)", name)
+		}
+	}
+
+	if options.To > 0 && options.To <= options.From {
+		return options, fmt.Errorf("argument to should be after argument from")
+	}
+
+	return
+}
+
+// writeSessionRecordAsGif converts a session recording to a GIF and writes it to stdout
+func writeSessionRecordAsGif(localFilepath, outputFile string, speed float64, repeat bool) (err error) {
+
+	generator := ttyrec2gif.NewGifGenerator()
+	generator.Speed = speed
+	generator.NoLoop = !repeat
+	err = generator.Generate(localFilepath, outputFile)
+	if err != nil {
+		return errors.Wrap(err, "unable to generate GIF from TTYRec")
+	}
+
+	content, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return
+	}
+
+	err = writeRawToStdout(content)
+	if err != nil {
+		return
+	}
+
+	return os.Remove(outputFile)
+}
+
+// writeSessionRecordAsAsciicast converts a session recording, with its keystrokes if they were recorded,
+// to an asciicast v2 file and writes it to stdout
+func writeSessionRecordAsAsciicast(session *helpers.SSHSession, ttyrecDirectory, workDirectory string) (err error) {
+
+	localFilepath, cleanup, err := fetchSessionRecord(ttyrecDirectory, workDirectory, fmt.Sprintf("%s.ttyrec", session.UniqID))
+	if err != nil {
+		return
+	}
+	defer cleanup()
+
+	output, err := os.Open(localFilepath)
+	if err != nil {
+		return errors.Wrap(err, "file not found")
+	}
+	defer output.Close()
+
+	// The keystrokes are only recorded if the instance is configured to
+	var input io.Reader
+	inputFilepath, cleanupInput, errInput := fetchSessionRecord(ttyrecDirectory, workDirectory, fmt.Sprintf("%s.input.ttyrec", session.UniqID))
+	if errInput == nil {
+		defer cleanupInput()
+		inputFile, errOpen := os.Open(inputFilepath)
+		if errOpen == nil {
+			defer inputFile.Close()
+			input = bufio.NewReader(inputFile)
+		}
+	}
+
+	var content bytes.Buffer
+	err = recording.WriteAsciicast(&content, recording.AsciicastHeader{
+		Width:     session.TerminalWidth,
+		Height:    session.TerminalHeight,
+		Timestamp: session.StartDate.Unix(),
+		Title:     fmt.Sprintf("%s@%s:%s", session.UserTo, session.HostTo, session.PortTo),
+	}, bufio.NewReader(output), input)
+	if err != nil {
+		return
+	}
+
+	return writeRawToStdout(content.Bytes())
+}
+
+// writeSessionRecordAsTtyrec writes a session recording to stdout
+func writeSessionRecordAsTtyrec(localFilepath string) (err error) {
+
+	content, err := ioutil.ReadFile(localFilepath)
+	if err != nil {
+		return errors.Wrap(err, "file not found")
+	}
+
+	return writeRawToStdout(content)
+}
+
+// writeRawToStdout writes binary content to stdout
+func writeRawToStdout(content []byte) (err error) {
+
+	// We set stdout in raw mode to avoid \r\n transformations by ssh -t on client side
+	_, err = term.MakeRaw(syscall.Stdout)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("%s", string(content))
+
+	return
+}
+
+// replayFile replays a ttyrec or an asciicast v2 file
+func replayFile(path string, showInput bool, options recording.PlayerOptions) (err error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "file not found")
+	}
+	defer f.Close()
+
+	// ttyrec files are binary: they can't start with an asciicast header
+	if _, errHeader := recording.ReadAsciicastHeader(bufio.NewReader(f)); errHeader != nil {
+		return replaySessionRecord(path, options)
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	converted, err := ioutil.TempFile("", "sb-replay-")
+	if err != nil {
+		return
+	}
+	defer os.Remove(converted.Name())
+	defer converted.Close()
+
+	w := bufio.NewWriter(converted)
+	err = recording.ConvertAsciicast(f, w, showInput)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return
+	}
+
+	return replaySessionRecord(converted.Name(), options)
+}
+
+// getAuditedSessionRecord returns the local path of any account's session recording, looked up in the global logs database.
+// Recordings fetched from the storage are written to a temporary directory, removed by the returned cleanup function.
+func getAuditedSessionRecord(sessionID string, showInput bool) (localFilepath string, cleanup func() error, err error) {
+
+	sessions, err := models.GetSSHSessions(config.GetGlobalDatabasePath(), &models.SSHSessionsFilter{SessionID: sessionID})
+	if err != nil {
+		return
+	}
+	if len(sessions) == 0 {
+		err = fmt.Errorf("session %s not found", sessionID)
+		return
+	}
+
+	user, err := models.LoadUser(sessions[0].UserFrom)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to load account %s", sessions[0].UserFrom)
+		return
+	}
+
+	workDirectory, err := ioutil.TempDir("", "sb-audit-")
+	if err != nil {
+		return
+	}
+
+	localFilepath, cleanupRecord, err := getSessionRecord(user.GetTtyrecDirectory(), workDirectory, sessionID, showInput)
+	if err != nil {
+		os.RemoveAll(workDirectory)
+		return
+	}
+
+	cleanup = func() error {
+		errCleanup := cleanupRecord()
+		if errCleanup != nil {
+			return errCleanup
+		}
+		return os.RemoveAll(workDirectory)
+	}
+
+	return
+}
+
+// indexSessionRecord indexes the text displayed during a session, for the sessions search
+func indexSessionRecord(db *gorm.DB, session *helpers.SSHSession, localFilepath string) (err error) {
+
+	f, err := os.Open(localFilepath)
+	if err != nil {
+		return errors.Wrap(err, "file not found")
+	}
+	defer f.Close()
+
+	lines, err := recording.ExtractLines(bufio.NewReader(f))
+	if err != nil {
+		return
+	}
+
+	sessionLines := make([]*models.SessionLine, 0, len(lines))
+	for _, line := range lines {
+		sessionLines = append(sessionLines, &models.SessionLine{
+			Elapsed: line.Offset.Milliseconds(),
+			Text:    line.Text,
+		})
+	}
+
+	return models.IndexSession(db, &models.IndexedSession{
+		SessionID: session.UniqID,
+		Account:   session.UserFrom,
+		StartDate: session.StartDate,
+	}, sessionLines)
+}
+
+// indexMissingSessions indexes the recordings of the finished sessions that are not indexed yet, fetching them from
+// the storage if they were offloaded. It returns the sessions that couldn't be indexed.
+func indexMissingSessions(db *gorm.DB, sessions []*helpers.SSHSession) (failed []*helpers.SSHSession, err error) {
+
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.UniqID)
+	}
+	indexed, err := models.GetIndexedSessions(db, sessionIDs)
+	if err != nil {
+		return
+	}
+
+	workDirectory, err := ioutil.TempDir("", "sb-index-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(workDirectory)
+
+	users := make(map[string]*models.User)
+	for _, session := range sessions {
+
+		// The running sessions will be indexed once finished
+		if _, ok := indexed[session.UniqID]; ok || !session.Allowed || session.EndDate.IsZero() {
+			continue
+		}
+
+		user, ok := users[session.UserFrom]
+		if !ok {
+			user, err = models.LoadUser(session.UserFrom)
+			if err != nil {
+				// The account might have been deleted since
+				failed = append(failed, session)
+				err = nil
+				continue
+			}
+			users[session.UserFrom] = user
+		}
+
+		localFilepath, cleanup, errFetch := fetchSessionRecord(user.GetTtyrecDirectory(), workDirectory, fmt.Sprintf("%s.ttyrec", session.UniqID))
+		if errFetch != nil {
+			failed = append(failed, session)
+			continue
+		}
+
+		errIndex := indexSessionRecord(db, session, localFilepath)
+		cleanup()
+		if errIndex != nil {
+			failed = append(failed, session)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/commands"
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/models"
+)
+
+// SessionsSearch describes the sessionsSearch command
+type SessionsSearch struct {
+	pattern *regexp.Regexp
+	filter  *models.SSHSessionsFilter
+	context int
+}
+
+func init() {
+	commands.RegisterCommand("sessions search", func() (c commands.Command, r models.Right, h helpers.Helper, args map[string]commands.Argument) {
+		return new(SessionsSearch), models.Public, helpers.Helper{
+				Header:      "search the text displayed during the recorded SSH sessions",
+				Usage:       "sessions search --pattern REGEX [--account ACCOUNT --since DATE --context LINES]",
+				Description: "search the text displayed during your recorded SSH sessions (sb owners and members of the auditors group can search any account's sessions)",
+				Aliases:     []string{"sessionsSearch"},
+			}, map[string]commands.Argument{
+				"pattern": {
+					Required:    true,
+					Description: "The regular expression to search for (e.g. \"rm -rf\")",
+				},
+				"account": {
+					Required:    false,
+					Description: "Only search the sessions initiated by this account (all accounts for auditors, yours otherwise)",
+				},
+				"since": {
+					Required:    false,
+					Description: "Only search the sessions started after this date (RFC3339, 2006-01-02 or a duration like 7d)",
+				},
+				"context": {
+					Required:     false,
+					Description:  "The number of lines displayed before and after each match",
+					DefaultValue: "1",
+				},
+			}
+	})
+}
+
+// Checks checks whether or not the user can execute this method
+func (c *SessionsSearch) Checks(ct *commands.Context) (err error) {
+
+	c.pattern, err = regexp.Compile(ct.FormattedArguments["pattern"])
+	if err != nil {
+		return fmt.Errorf("argument pattern is not a valid regular expression: %s", err)
+	}
+
+	c.context, err = strconv.Atoi(ct.FormattedArguments["context"])
+	if err != nil || c.context < 0 {
+		return fmt.Errorf("argument context should be a positive integer")
+	}
+
+	c.filter = &models.SSHSessionsFilter{
+		Account: ct.FormattedArguments["account"],
+	}
+
+	// Only the auditors can search the other accounts' sessions
+	if !ct.User.IsAuditor() {
+		if c.filter.Account != "" && c.filter.Account != ct.User.User.Username {
+			return fmt.Errorf("only sb owners and members of the auditors group can search the other accounts' sessions")
+		}
+		c.filter.Account = ct.User.User.Username
+	}
+
+	if ct.FormattedArguments["since"] != "" {
+		c.filter.Since, err = helpers.ParseDate(ct.FormattedArguments["since"], time.Now())
+		if err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+// Execute executes the command
+func (c *SessionsSearch) Execute(ct *commands.Context) (repl models.ReplicationData, cmdError error, err error) {
+	err = commands.Render(c, ct)
+	return
+}
+
+// sessionsSearchResult describes the result of the command
+type sessionsSearchResult struct {
+	Matches []*sessionMatchResult `json:"matches" yaml:"matches"`
+}
+
+// sessionMatchResult describes a line matching the search
+type sessionMatchResult struct {
+	SessionID string    `json:"session_id" yaml:"session_id"`
+	Account   string    `json:"account" yaml:"account"`
+	Date      time.Time `json:"date" yaml:"date"`
+	Line      string    `json:"line" yaml:"line"`
+	Before    []string  `json:"before,omitempty" yaml:"before,omitempty"`
+	After     []string  `json:"after,omitempty" yaml:"after,omitempty"`
+}
+
+// Result returns the lines of the sessions matching the pattern
+func (c *SessionsSearch) Result(ct *commands.Context) (result commands.Result, err error) {
+
+	sessions, err := models.GetSSHSessions(config.GetGlobalDatabasePath(), c.filter)
+	if err != nil {
+		return
+	}
+
+	db, err := models.GetSessionsIndexGormDB(config.GetSessionsIndexDatabasePath())
+	if err != nil {
+		return
+	}
+
+	// The sessions are indexed once finished: the ones that were not (older ones, or offloaded from another instance) are indexed now
+	failed, err := indexMissingSessions(db, sessions)
+	if err != nil {
+		return
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d session(s) couldn't be searched, their recording is unavailable\n", len(failed))
+	}
+
+	searchResult := &sessionsSearchResult{Matches: make([]*sessionMatchResult, 0)}
+	for _, session := range sessions {
+
+		lines, errLines := models.GetSessionLines(db, session.UniqID)
+		if errLines != nil {
+			return nil, errLines
+		}
+
+		for i, line := range lines {
+			if !c.pattern.MatchString(line.Text) {
+				continue
+			}
+
+			match := &sessionMatchResult{
+				SessionID: session.UniqID,
+				Account:   session.UserFrom,
+				Date:      session.StartDate.Add(time.Duration(line.Elapsed) * time.Millisecond).UTC(),
+				Line:      line.Text,
+			}
+			for j := i - c.context; j < i; j++ {
+				if j >= 0 {
+					match.Before = append(match.Before, lines[j].Text)
+				}
+			}
+			for j := i + 1; j <= i+c.context && j < len(lines); j++ {
+				match.After = append(match.After, lines[j].Text)
+			}
+
+			searchResult.Matches = append(searchResult.Matches, match)
+		}
+	}
+
+	return searchResult, nil
+}
+
+func (r *sessionsSearchResult) PrintTable() {
+
+	if len(r.Matches) == 0 {
+		fmt.Println("No recorded SSH session matches your search")
+		return
+	}
+
+	matches := make([]string, 0, len(r.Matches))
+	for _, match := range r.Matches {
+		lines := []string{fmt.Sprintf("Session ID: %s (%s | %s)", match.SessionID, match.Account, match.Date.Format("2006-01-02 15:04:05"))}
+		for _, line := range match.Before {
+			lines = append(lines, fmt.Sprintf("    %s", line))
+		}
+		lines = append(lines, fmt.Sprintf("  > %s", match.Line))
+		for _, line := range match.After {
+			lines = append(lines, fmt.Sprintf("    %s", line))
+		}
+		matches = append(matches, strings.Join(lines, "\n"))
+	}
+
+	fmt.Printf("Here are the %d matching lines:\n%s\n", len(matches), strings.Join(matches, "\n"))
+}
+
+func (c *SessionsSearch) PostExecute(repl models.ReplicationData) (err error) {
+	return
+}
+
+func (c *SessionsSearch) Replicate(repl models.ReplicationData) (err error) {
+	return
+}
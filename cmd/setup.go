@@ -173,6 +173,14 @@ func (c *Setup) _SetSSHDOptions() (err error) {
 	log.Printf("[SETUP     ]   -> Switch %-32s to publickey,keyboard-interactive", "AuthenticationMethods")
 	p.SetParam("AuthenticationMethods", "publickey,keyboard-interactive")
 
+	// The forward command is reached with "ssh -L PORT:SOCKET": the accounts may only forward to unix sockets,
+	// and not to TCP ports, which would let them reach the network of sb without any access
+	log.Printf("[SETUP     ]   -> Switch %-32s to local", "AllowStreamLocalForwarding")
+	p.SetParam("AllowStreamLocalForwarding", "local")
+
+	log.Printf("[SETUP     ]   -> Switch %-32s to no", "AllowTcpForwarding")
+	p.SetParam("AllowTcpForwarding", "no")
+
 	return p.WriteToFile(DefaultSSHDConfigFile)
 }
 
@@ -237,6 +245,36 @@ func (c *Setup) _createSBUser() (err error) {
 		return
 	}
 
+	log.Printf("[SETUP     ] Creating sb's main requests database file")
+	err = exec.Command("touch", fmt.Sprintf("%s/requests.db", homedir)).Run()
+	if err != nil {
+		return
+	}
+
+	log.Printf("[SETUP     ] Creating sb's sessions search index database file")
+	err = exec.Command("touch", fmt.Sprintf("%s/sessions-index.db", homedir)).Run()
+	if err != nil {
+		return
+	}
+
+	log.Printf("[SETUP     ] Creating sb's notifications queue database file")
+	err = exec.Command("touch", fmt.Sprintf("%s/notifications.db", homedir)).Run()
+	if err != nil {
+		return
+	}
+
+	log.Printf("[SETUP     ] Creating sb's audit events queue database file")
+	err = exec.Command("touch", fmt.Sprintf("%s/audit.db", homedir)).Run()
+	if err != nil {
+		return
+	}
+
+	log.Printf("[SETUP     ] Creating sb's host inventory database file")
+	err = exec.Command("touch", fmt.Sprintf("%s/hosts.db", homedir)).Run()
+	if err != nil {
+		return
+	}
+
 	log.Printf("[SETUP     ] Change ownership of %s to %s:%s", homedir, config.GetSBUsername(), config.GetSBUsername())
 	err = exec.Command("chown", "-R", fmt.Sprintf("%s:%s", config.GetSBUsername(), config.GetSBUsername()), homedir).Run()
 	if err != nil {
@@ -249,7 +287,7 @@ func (c *Setup) _createSBUser() (err error) {
 		return
 	}
 
-	for _, file := range []string{"logs.db", "replication.db"} {
+	for _, file := range []string{"logs.db", "replication.db", "requests.db", "sessions-index.db", "notifications.db", "audit.db", "hosts.db"} {
 		log.Printf("[SETUP     ] Change %-35s permissions to 0660", fmt.Sprintf("%s/%s", homedir, file))
 		err = exec.Command("chmod", "0660", fmt.Sprintf("%s/%s", homedir, file)).Run()
 		if err != nil {
@@ -6,17 +6,22 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/inpher/sb/internal/commands"
 	"github.com/inpher/sb/internal/config"
 	"github.com/inpher/sb/internal/helpers"
 	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/recording"
+	"github.com/inpher/sb/internal/sshclient"
 	"github.com/inpher/sb/internal/storage"
 	"github.com/pkg/errors"
-	"maze.io/x/ttyrec"
+	"golang.org/x/term"
 
 	"github.com/fatih/color"
 )
@@ -66,13 +71,52 @@ func (c *Ttyrec) Execute(ct *commands.Context) (repl models.ReplicationData, cmd
 	// We override the currently stored access (which might be an alias) with the final one
 	ct.Log.SetTargetAccess(access)
 
+	// The exports of the recording need the size of the terminal
+	if width, height, errSize := term.GetSize(int(os.Stdin.Fd())); errSize == nil {
+		ct.Log.SetTerminalSize(width, height)
+	}
+
 	// We will provide the ttyrec record path as a replication data for the post exec step
 	repl = models.ReplicationData{
 		"ttyrec-record-path": fmt.Sprintf("%s/%s.ttyrec", ct.User.GetTtyrecDirectory(), ct.Log.UniqID),
 	}
 
+	// mosh-server has to launch the ssh binary, the other sessions go through the native client
+	if ct.FormattedArguments["client"] == "mosh" || config.GetSSHClient() == "system" {
+		cmdError, err = c.executeSystem(ct, access, repl["ttyrec-record-path"])
+	} else {
+		// The keystrokes can only be recorded by the native client, which reads them itself
+		if config.GetRecordInput() {
+			repl["ttyrec-input-record-path"] = fmt.Sprintf("%s/%s.input.ttyrec", ct.User.GetTtyrecDirectory(), ct.Log.UniqID)
+		}
+		cmdError, err = c.executeNative(ct, access, repl["ttyrec-record-path"], repl["ttyrec-input-record-path"])
+	}
+
+	return
+}
+
+// executeSystem connects to the distant host with the ssh binary, recording its output
+func (c *Ttyrec) executeSystem(ct *commands.Context, access *models.Access, ttyrecPath string) (cmdError error, err error) {
+
+	// In CA mode, we get a short-lived certificate for this very access.
+	// mosh-server detaches before ssh reads the ephemeral key, which is removed when we return: mosh sessions use the egress keys.
+	var certificate *egressCertificate
+	egressCAConfig := config.GetEgressCAConfig()
+	if egressCAConfig.Enabled && ct.FormattedArguments["client"] != "mosh" {
+		certificate, err = getEgressCertificate(egressCAConfig.Socket, access, ct.Log.UniqID)
+		if err != nil {
+			if len(ct.AI.KeyFilepathes) == 0 {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Unable to get a certificate, falling back to the egress keys: %s\n", err)
+			err = nil
+		} else {
+			defer os.RemoveAll(certificate.directory)
+		}
+	}
+
 	// Building the SSH command
-	sshCommand, err := c.buildSSHCommand(access, ct.AI.KeyFilepathes, ct.RawArguments)
+	sshCommand, err := c.buildSSHCommand(access, certificate, ct.AI.KeyFilepathes, ct.RawArguments)
 	if err != nil {
 		return
 	}
@@ -119,7 +163,7 @@ func (c *Ttyrec) Execute(ct *commands.Context) (repl models.ReplicationData, cmd
 		}
 		defer f.Close()
 
-		e := ttyrec.NewEncoder(f)
+		e := recording.NewEncoder(f)
 
 		written, err = io.Copy(e, r)
 		if err != nil {
@@ -129,7 +173,7 @@ func (c *Ttyrec) Execute(ct *commands.Context) (repl models.ReplicationData, cmd
 
 		return
 	}(
-		repl["ttyrec-record-path"],
+		ttyrecPath,
 		io.MultiReader(
 			io.TeeReader(stdout, os.Stdout),
 			io.TeeReader(stderr, os.Stderr),
@@ -143,6 +187,9 @@ func (c *Ttyrec) Execute(ct *commands.Context) (repl models.ReplicationData, cmd
 		return
 	}
 
+	terminated, stopTermination := c.onTermination(func() { cmd.Process.Signal(syscall.SIGTERM) })
+	defer stopTermination()
+
 	// Wait until user exits the shell
 	err = cmd.Wait()
 	if err != nil {
@@ -157,6 +204,10 @@ func (c *Ttyrec) Execute(ct *commands.Context) (repl models.ReplicationData, cmd
 		err = nil
 	}
 
+	if c.wasTerminated(ct, terminated) {
+		return nil, nil
+	}
+
 	fmt.Printf("<< Exited shell: %s\n", cmd.ProcessState.String())
 
 	if cmd.ProcessState.ExitCode() > 0 {
@@ -166,12 +217,127 @@ func (c *Ttyrec) Execute(ct *commands.Context) (repl models.ReplicationData, cmd
 	return
 }
 
+// executeNative connects to the distant host with the native SSH client, recording the session as it is displayed.
+// If inputPath is not empty, the keystrokes are recorded there too.
+func (c *Ttyrec) executeNative(ct *commands.Context, access *models.Access, ttyrecPath, inputPath string) (cmdError error, err error) {
+
+	fmt.Printf("... connecting you to the distant host (if it's alive :)) ...\n")
+
+	client, err := newEgressClient(ct, access, config.GetForwardAgent(), true)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	fmt.Printf("---\n")
+
+	f, err := os.Create(ttyrecPath)
+	if err != nil {
+		err = errors.Wrap(err, "unable to open ttyrec file")
+		return
+	}
+	defer f.Close()
+
+	// With a PTY, the distant host merges stdout and stderr: the frames are recorded in the order they are displayed
+	var recorder io.Writer = &lockedWriter{w: recording.NewEncoder(f)}
+
+	// The input recorder watches the output for the echo of the keystrokes, to mask the ones that are not echoed
+	var keystrokes io.Writer
+	if inputPath != "" {
+		inputFile, errInput := os.Create(inputPath)
+		if errInput != nil {
+			err = errors.Wrap(errInput, "unable to open ttyrec input file")
+			return
+		}
+		defer inputFile.Close()
+
+		inputRecorder := recording.NewInputRecorder(inputFile)
+		defer inputRecorder.Close()
+		keystrokes = inputRecorder
+		recorder = io.MultiWriter(recorder, inputRecorder.Output())
+	}
+
+	terminated, stopTermination := c.onTermination(func() { client.Close() })
+	defer stopTermination()
+
+	err = client.RunInteractive(
+		strings.Join(ct.RawArguments, " "),
+		os.Stdin,
+		keystrokes,
+		io.MultiWriter(os.Stdout, recorder),
+		io.MultiWriter(os.Stderr, recorder),
+	)
+
+	if c.wasTerminated(ct, terminated) {
+		return nil, nil
+	}
+
+	exitStatus := sshclient.ExitStatus(err)
+	if exitStatus < 0 {
+		err = errors.Wrap(err, "unable to wait for the session")
+		return
+	}
+
+	fmt.Printf("<< Exited shell: exit status %d\n", exitStatus)
+
+	if exitStatus > 0 {
+		cmdError = errors.Wrap(err, "failed to execute command on distant host")
+	}
+
+	return cmdError, nil
+}
+
+// onTermination calls terminate when an administrator kills the session (admin session kill sends SIGTERM to sb),
+// until stop is called. The returned channel is closed before terminate is called.
+func (c *Ttyrec) onTermination(terminate func()) (terminated chan struct{}, stop func()) {
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+
+	terminated = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			close(terminated)
+			terminate()
+		case <-done:
+		}
+	}()
+
+	return terminated, func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// wasTerminated returns whether the session was killed by an administrator, logging it if so
+func (c *Ttyrec) wasTerminated(ct *commands.Context, terminated chan struct{}) bool {
+
+	select {
+	case <-terminated:
+	default:
+		return false
+	}
+
+	fmt.Printf("\r\n<< Session terminated by an administrator\n")
+	ct.Log.Comment = fmt.Sprintf("Terminated by an administrator on %s", time.Now().Format(time.RFC3339))
+
+	return true
+}
+
 func (c *Ttyrec) Replicate(repl models.ReplicationData) (err error) {
 	return
 }
 
 func (c *Ttyrec) PostExecute(repl models.ReplicationData) (err error) {
 
+	// The recording is indexed for the sessions search before it leaves the local disk
+	errIndex := c.indexRecord(repl["ttyrec-record-path"])
+	if errIndex != nil {
+		fmt.Fprintf(os.Stderr, "Unable to index the session for the search: %s\n", errIndex)
+	}
+
 	// If TTYRecs offloading is enabled, we offload the ttyrec to a storage
 	ttyRecsOffloadingConfig := config.GetTTYRecsOffloadingConfig()
 	if !ttyRecsOffloadingConfig.Enabled {
@@ -184,8 +350,45 @@ func (c *Ttyrec) PostExecute(repl models.ReplicationData) (err error) {
 		return
 	}
 
+	err = c.offloadRecord(rs, repl["ttyrec-record-path"])
+	if err != nil {
+		return
+	}
+
+	// The input recording might not have been created if the session failed early
+	if inputPath := repl["ttyrec-input-record-path"]; inputPath != "" {
+		if _, errStat := os.Stat(inputPath); errStat == nil {
+			err = c.offloadRecord(rs, inputPath)
+		}
+	}
+
+	return
+}
+
+// indexRecord indexes the text displayed during the session, for the sessions search
+func (c *Ttyrec) indexRecord(filename string) (err error) {
+
+	sessionID := strings.TrimSuffix(filepath.Base(filename), ".ttyrec")
+	sessions, err := models.GetSSHSessions(config.GetGlobalDatabasePath(), &models.SSHSessionsFilter{SessionID: sessionID})
+	if err != nil {
+		return
+	}
+	if len(sessions) == 0 {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+
+	db, err := models.GetSessionsIndexGormDB(config.GetSessionsIndexDatabasePath())
+	if err != nil {
+		return
+	}
+
+	return indexSessionRecord(db, sessions[0], filename)
+}
+
+// offloadRecord encrypts a recording, pushes it to the storage and removes it from the local disk
+func (c *Ttyrec) offloadRecord(rs storage.Storage, filename string) (err error) {
+
 	// Let's start by generating the filenames we'll require
-	filename := repl["ttyrec-record-path"]
 	encryptedFilename := fmt.Sprintf("%s.bin", filename)
 
 	fmt.Printf("Starting to push %s to a storage\n", filename)
@@ -263,7 +466,7 @@ func (c *Ttyrec) buildMOSHCommand(clientArguments string) (cmd []string, err err
 	return
 }
 
-func (c *Ttyrec) buildSSHCommand(access *models.Access, keyfilePathes []string, rawArguments []string) (cmd []string, err error) {
+func (c *Ttyrec) buildSSHCommand(access *models.Access, certificate *egressCertificate, keyfilePathes []string, rawArguments []string) (cmd []string, err error) {
 
 	// Set sb environment
 	for _, envVar := range config.GetEnvironmentVarsToForward() {
@@ -282,7 +485,9 @@ func (c *Ttyrec) buildSSHCommand(access *models.Access, keyfilePathes []string,
 		sshPath, access.Host,
 		"-l", access.User,
 		"-p", fmt.Sprintf("%d", access.Port),
-		"-A",
+	}
+	if config.GetForwardAgent() {
+		cmd = append(cmd, "-A")
 	}
 
 	// We push environment variables to forward
@@ -290,6 +495,11 @@ func (c *Ttyrec) buildSSHCommand(access *models.Access, keyfilePathes []string,
 		cmd = append(cmd, "-o", fmt.Sprintf("SendEnv=LC_SB_%s", strings.ToUpper(envVar)))
 	}
 
+	// The certificate comes first, the egress keys remaining usable on hosts not trusting the CA yet
+	if certificate != nil {
+		cmd = append(cmd, "-i", certificate.privateKeyFile, "-o", fmt.Sprintf("CertificateFile=%s", certificate.certificateFile))
+	}
+
 	// We push the private keys to use
 	for _, privateKeyFile := range keyfilePathes {
 		cmd = append(cmd, "-i", privateKeyFile)
@@ -0,0 +1,32 @@
+package auditsink
+
+import (
+	"fmt"
+
+	"github.com/inpher/sb/internal/auditsink/jsonlines"
+	"github.com/inpher/sb/internal/auditsink/syslog"
+	"github.com/inpher/sb/internal/types"
+	"github.com/pkg/errors"
+)
+
+type AuditSink interface {
+	Emit(*types.AuditEvent) error
+}
+
+func GetAuditSink(config *types.AuditSinkConfig) (as AuditSink, err error) {
+
+	switch config.SinkType {
+	case "syslog":
+		as, err = syslog.NewAuditSinkSyslog(config.SinkOptions)
+	case "jsonlines":
+		as, err = jsonlines.NewAuditSinkJSONLines(config.SinkOptions)
+	default:
+		err = fmt.Errorf("audit sink type %s is not implemented", config.SinkType)
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "error while initializing audit sink")
+	}
+
+	return
+}
@@ -0,0 +1,101 @@
+package jsonlines
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/inpher/sb/internal/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 5 * time.Second
+)
+
+// AuditSinkJSONLines emits the audit events as JSON lines, appended to a file or sent to a socket
+type AuditSinkJSONLines struct {
+	path    string
+	network string
+	address string
+}
+
+func NewAuditSinkJSONLines(options *viper.Viper) (as *AuditSinkJSONLines, err error) {
+
+	if options == nil {
+		err = fmt.Errorf("audit.sinks jsonlines options can't be empty")
+		return
+	}
+
+	as = &AuditSinkJSONLines{
+		path:    options.GetString("path"),
+		network: options.GetString("network"),
+		address: options.GetString("address"),
+	}
+
+	switch {
+	case as.path != "" && as.address != "":
+		err = fmt.Errorf("jsonlines.path and jsonlines.address options can't be both set")
+	case as.path != "":
+	case as.address != "":
+		if as.network == "" {
+			as.network = "unix"
+		}
+		if as.network != "unix" && as.network != "tcp" {
+			err = fmt.Errorf("jsonlines.network should be unix or tcp")
+		}
+	default:
+		err = fmt.Errorf("jsonlines.path or jsonlines.address option should be set")
+	}
+
+	return
+}
+
+func (s *AuditSinkJSONLines) Emit(event *types.AuditEvent) (err error) {
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal audit event")
+	}
+	line = append(line, '\n')
+
+	if s.path != "" {
+		return s.appendToFile(line)
+	}
+
+	// The sb processes emit a few events each, possibly hours apart: we don't keep the connection
+	conn, err := net.DialTimeout(s.network, s.address, dialTimeout)
+	if err != nil {
+		return errors.Wrapf(err, "unable to connect to %s", s.address)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err = conn.Write(line); err != nil {
+		return errors.Wrapf(err, "unable to send audit event to %s", s.address)
+	}
+
+	return conn.Close()
+}
+
+// appendToFile appends a line to the file. The sb processes of all the accounts write to it:
+// each line is written at once, in append mode, so that they don't mix.
+func (s *AuditSinkJSONLines) appendToFile(line []byte) (err error) {
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open %s", s.path)
+	}
+
+	_, err = f.Write(line)
+	if err != nil {
+		f.Close()
+		return errors.Wrapf(err, "unable to write to %s", s.path)
+	}
+
+	return f.Close()
+}
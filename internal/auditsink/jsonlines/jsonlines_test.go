@@ -0,0 +1,51 @@
+package jsonlines
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inpher/sb/internal/types"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditSinkJSONLines(t *testing.T) {
+
+	options := viper.New()
+	_, err := NewAuditSinkJSONLines(options)
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.json")
+	options.Set("path", path)
+	options.Set("address", "/run/siem.sock")
+	_, err = NewAuditSinkJSONLines(options)
+	require.Error(t, err)
+
+	options.Set("address", "")
+	as, err := NewAuditSinkJSONLines(options)
+	require.NoError(t, err)
+
+	date := time.Date(2023, 4, 5, 23, 30, 0, 0, time.UTC)
+	for _, action := range []string{"create", "update"} {
+		require.NoError(t, as.Emit(&types.AuditEvent{
+			Action:    action,
+			Date:      date,
+			ID:        "1234",
+			Account:   "john",
+			Allowed:   true,
+			ToHost:    "core",
+			StartDate: date,
+		}))
+	}
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strings.Join([]string{
+		`{"action":"create","date":"2023-04-05T23:30:00Z","instance":"","id":"1234","account":"john","allowed":true,"to_host":"core","start_date":"2023-04-05T23:30:00Z"}`,
+		`{"action":"update","date":"2023-04-05T23:30:00Z","instance":"","id":"1234","account":"john","allowed":true,"to_host":"core","start_date":"2023-04-05T23:30:00Z"}`,
+		``,
+	}, "\n"), string(content))
+}
@@ -0,0 +1,178 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/inpher/sb/internal/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 5 * time.Second
+
+	// The log entries are created before the rights are checked: the events all have the same severity
+	severityNotice = 5
+
+	// RFC 5424 timestamps have at most 6 fractional digits
+	timestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// AuditSinkSyslog emits the audit events as RFC 5424 syslog messages, whose content is the JSON event.
+// Over TCP and TLS, the messages are framed with octet counting (RFC 6587 and RFC 5425).
+type AuditSinkSyslog struct {
+	network   string
+	address   string
+	facility  int
+	appName   string
+	tlsConfig *tls.Config
+}
+
+func NewAuditSinkSyslog(options *viper.Viper) (as *AuditSinkSyslog, err error) {
+
+	if options == nil {
+		err = fmt.Errorf("audit.sinks syslog options can't be empty")
+		return
+	}
+
+	as = &AuditSinkSyslog{
+		network: options.GetString("network"),
+		address: options.GetString("address"),
+		appName: options.GetString("app-name"),
+	}
+
+	if as.address == "" {
+		err = fmt.Errorf("syslog.address option can't be empty")
+		return
+	}
+	if as.appName == "" {
+		as.appName = "sb"
+	}
+
+	facility := options.GetString("facility")
+	if facility == "" {
+		facility = "authpriv"
+	}
+	var ok bool
+	as.facility, ok = facilities[facility]
+	if !ok {
+		err = fmt.Errorf("syslog.facility %s is not a valid syslog facility", facility)
+		return
+	}
+
+	switch as.network {
+	case "":
+		as.network = "udp"
+	case "udp", "tcp":
+	case "tls":
+		as.tlsConfig, err = newTLSConfig(as.address, options.GetString("ca-file"))
+		if err != nil {
+			return
+		}
+	default:
+		err = fmt.Errorf("syslog.network should be udp, tcp or tls")
+		return
+	}
+
+	return
+}
+
+// newTLSConfig returns the TLS configuration to connect to address, trusting the certificates of caFile if it is set
+func newTLSConfig(address, caFile string) (config *tls.Config, err error) {
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		err = errors.Wrapf(err, "invalid syslog.address %s", address)
+		return
+	}
+
+	config = &tls.Config{ServerName: host}
+
+	if caFile != "" {
+		pem, errRead := os.ReadFile(caFile)
+		if errRead != nil {
+			return nil, errors.Wrapf(errRead, "unable to read %s", caFile)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+	}
+
+	return
+}
+
+func (s *AuditSinkSyslog) Emit(event *types.AuditEvent) (err error) {
+
+	message, err := s.format(event)
+	if err != nil {
+		return
+	}
+
+	if s.network != "udp" {
+		message = append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+	}
+
+	// The sb processes emit a few events each, possibly hours apart: we don't keep the connection
+	conn, err := s.dial()
+	if err != nil {
+		return errors.Wrapf(err, "unable to connect to syslog server %s", s.address)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err = conn.Write(message); err != nil {
+		return errors.Wrapf(err, "unable to send message to syslog server %s", s.address)
+	}
+
+	return conn.Close()
+}
+
+func (s *AuditSinkSyslog) dial() (conn net.Conn, err error) {
+
+	if s.tlsConfig != nil {
+		return tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", s.address, s.tlsConfig)
+	}
+
+	return net.DialTimeout(s.network, s.address, dialTimeout)
+}
+
+// format returns the RFC 5424 message of an event
+func (s *AuditSinkSyslog) format(event *types.AuditEvent) (message []byte, err error) {
+
+	content, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal audit event")
+	}
+
+	hostname := event.Instance
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	message = []byte(fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		s.facility*8+severityNotice,
+		event.Date.Format(timestampLayout),
+		hostname,
+		s.appName,
+		os.Getpid(),
+		event.Action,
+		content,
+	))
+
+	return
+}
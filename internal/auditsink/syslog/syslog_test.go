@@ -0,0 +1,77 @@
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/inpher/sb/internal/types"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditSinkSyslog(t *testing.T) {
+
+	options := viper.New()
+	_, err := NewAuditSinkSyslog(options)
+	require.Error(t, err)
+
+	options.Set("address", "127.0.0.1:514")
+	options.Set("facility", "nope")
+	_, err = NewAuditSinkSyslog(options)
+	require.Error(t, err)
+
+	event := &types.AuditEvent{
+		Action:    "create",
+		Date:      time.Date(2023, 4, 5, 23, 30, 0, 0, time.UTC),
+		Instance:  "sb-1",
+		ID:        "1234",
+		Account:   "john",
+		Allowed:   false,
+		StartDate: time.Date(2023, 4, 5, 23, 30, 0, 0, time.UTC),
+	}
+	expected := fmt.Sprintf(`<85>1 2023-04-05T23:30:00.000000Z sb-1 sb %d create - {"action":"create","date":"2023-04-05T23:30:00Z","instance":"sb-1","id":"1234","account":"john","allowed":false,"start_date":"2023-04-05T23:30:00Z"}`, os.Getpid())
+
+	// Over UDP, a datagram is a message
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	options.Set("address", listener.LocalAddr().String())
+	options.Set("facility", "authpriv")
+	as, err := NewAuditSinkSyslog(options)
+	require.NoError(t, err)
+	require.NoError(t, as.Emit(event))
+
+	buffer := make([]byte, 4096)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buffer)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(buffer[:n]))
+
+	// Over TCP, the messages are framed with their length
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcpListener.Close()
+
+	options.Set("network", "tcp")
+	options.Set("address", tcpListener.Addr().String())
+	as, err = NewAuditSinkSyslog(options)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, as.Emit(event))
+
+		conn, err := tcpListener.Accept()
+		require.NoError(t, err)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		line, err := bufio.NewReader(conn).ReadString('}')
+		require.NoError(t, err)
+		require.Regexp(t, "^"+regexp.QuoteMeta(fmt.Sprintf("%d %s", len(expected), expected))+"$", line)
+		conn.Close()
+	}
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/inpher/sb/internal/config"
 	"github.com/inpher/sb/internal/helpers"
 	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/notification"
 	"github.com/inpher/sb/internal/types"
+
+	"gorm.io/gorm"
 )
 
 var (
@@ -42,7 +45,8 @@ func IsAPublicCommand(command string) bool {
 }
 
 func IsReplicableCommand(command string) bool {
-	if command == "setup" || command == "backup" || command == "restore" {
+	switch getCommandName(command) {
+	case "setup", "backup", "restore", "replication join":
 		return false
 	}
 	return true
@@ -56,6 +60,11 @@ func BuildAndExecuteSBCommand(log *models.Log, user *models.User, args ...string
 		return
 	}
 
+	name := getCommandName(args[0])
+	if name == "ttyrec" {
+		notify(notification.EventSessionStart, ct)
+	}
+
 	// If replication is enabled, let's start by getting a handler on the replication database,
 	// as it's crucial we check we can get it to push the replication data after the action
 	dbHandler, err := models.GetReplicationGormDB(config.GetReplicationDatabasePath())
@@ -69,11 +78,23 @@ func BuildAndExecuteSBCommand(log *models.Log, user *models.User, args ...string
 		return
 	}
 
+	if cmdErr == nil && notification.IsNotifiable(name) {
+		notify(name, ct)
+	}
+
 	// If replication is enabled, let's save the data to the replication database
 	// This process also handles the PostExecute() part of the command
 	if (config.GetReplicationQueueConfig().Enabled || config.GetTTYRecsOffloadingConfig().Enabled) &&
 		IsReplicableCommand(args[0]) {
 
+		if sc, ok := bc.(SplitCommand); ok && cmdErr == nil {
+			err = saveSplitReplicationEntries(dbHandler, sc, replicationData)
+			if err != nil {
+				return
+			}
+			return cmdErr
+		}
+
 		var repl *models.Replication
 
 		repl, err = models.NewReplicationEntry(args[0], replicationData)
@@ -81,7 +102,15 @@ func BuildAndExecuteSBCommand(log *models.Log, user *models.User, args ...string
 			return
 		}
 
-		err = repl.Save(dbHandler)
+		var objectKey string
+		if vc, ok := bc.(VersionedCommand); ok && cmdErr == nil {
+			objectKey = vc.ObjectKey(replicationData)
+		}
+		if objectKey != "" {
+			err = models.StampReplicationEntry(dbHandler, repl, objectKey)
+		} else {
+			err = repl.Save(dbHandler)
+		}
 		if err != nil {
 			return
 		}
@@ -91,6 +120,39 @@ func BuildAndExecuteSBCommand(log *models.Log, user *models.User, args ...string
 	return cmdErr
 }
 
+// saveSplitReplicationEntries saves a versioned replication entry for each object a SplitCommand acted on
+func saveSplitReplicationEntries(db *gorm.DB, sc SplitCommand, replicationData models.ReplicationData) (err error) {
+
+	action, objects, err := sc.SplitReplicationData(replicationData)
+	if err != nil {
+		return
+	}
+
+	cmd, _, _, _, err := GetCommand(action)
+	if err != nil {
+		return
+	}
+	vc, ok := cmd.(VersionedCommand)
+	if !ok {
+		return fmt.Errorf("command %s is not versioned", action)
+	}
+
+	for _, data := range objects {
+
+		repl, err := models.NewReplicationEntry(action, data)
+		if err != nil {
+			return err
+		}
+
+		err = models.StampReplicationEntry(db, repl, vc.ObjectKey(data))
+		if err != nil {
+			return err
+		}
+	}
+
+	return
+}
+
 // buildArgumentsList constructs a map[string]string from the arguments lists
 func buildArgumentsList(trustedArguments map[string]Argument, args []string) (arguments map[string]string, rest []string, err error) {
 
@@ -189,6 +251,25 @@ func GetCommand(commandName string) (cmd Command, rights models.Right, helpers h
 	return
 }
 
+// getCommandName returns the name of a command, given its name or one of its aliases
+func getCommandName(commandName string) string {
+
+	if _, ok := commands[commandName]; ok {
+		return commandName
+	}
+
+	for name, commandFactory := range commands {
+		_, _, helpers, _ := commandFactory()
+		for _, alias := range helpers.Aliases {
+			if alias == commandName {
+				return name
+			}
+		}
+	}
+
+	return commandName
+}
+
 // BuildSBCommand builds the command
 func BuildSBCommand(log *models.Log, user *models.User, args ...string) (bc Command, ct *Context, err error) {
 
@@ -206,6 +287,9 @@ func BuildSBCommand(log *models.Log, user *models.User, args ...string) (bc Comm
 	// Log the command we used
 	log.SetCommand(args[0])
 
+	// Add the global arguments the command supports (--output, ...)
+	AddGlobalArguments(bc, cas)
+
 	// Let's start by displaying the helper if user asked for it
 	if len(args) > 1 && (args[1] == "help" || args[1] == "?") {
 		DisplayHelpers(commandHlprs, cas)
@@ -283,6 +367,11 @@ func BuildSBCommand(log *models.Log, user *models.User, args ...string) (bc Comm
 			log.SetAllowed(false)
 			return bc, ct, fmt.Errorf("user is not an owner of the group")
 		}
+	case models.Auditor:
+		if !user.IsAuditor() && user.User.Uid != "0" {
+			log.SetAllowed(false)
+			return bc, ct, fmt.Errorf("user is not an auditor")
+		}
 	case models.SBOwner:
 		if !user.IsOwnerOfGroup("owners") && user.User.Uid != "0" {
 			log.SetAllowed(false)
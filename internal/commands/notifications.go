@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/inpher/sb/internal/models"
+	"github.com/inpher/sb/internal/notification"
+)
+
+// notify queues the notification of an event triggered by a command, for the webhooks that subscribed to it
+func notify(event string, ct *Context) {
+
+	notificationEvent := ct.Log.NotificationEvent(event)
+	notificationEvent.Arguments = ct.FormattedArguments
+
+	host := ""
+	if event == notification.EventSessionStart {
+		host = ct.BA.Host
+		notificationEvent.Text = fmt.Sprintf("%s started an SSH session to %s on %s", ct.Log.LocalUsername, notificationEvent.Target, ct.Log.BastionHost)
+	} else {
+		arguments := make([]string, 0, len(ct.FormattedArguments))
+		for name, value := range ct.FormattedArguments {
+			arguments = append(arguments, fmt.Sprintf("--%s %s", name, value))
+		}
+		sort.Strings(arguments)
+		notificationEvent.Text = fmt.Sprintf("%s ran %s on %s", ct.Log.LocalUsername, strings.TrimSpace(fmt.Sprintf("%s %s", event, strings.Join(arguments, " "))), ct.Log.BastionHost)
+	}
+
+	err := models.QueueNotification(notificationEvent, host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: unable to queue notification: %s\n", err)
+	}
+}
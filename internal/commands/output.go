@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by the global --output argument
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// Result describes the typed result of a command, rendered according to the --output argument
+type Result interface {
+	// PrintTable prints the result in a human-readable way
+	PrintTable()
+}
+
+// ResultCommand describes the commands returning a typed result: they accept the global --output argument
+type ResultCommand interface {
+	Result(ct *Context) (Result, error)
+}
+
+// AddGlobalArguments adds the arguments handled for every command supporting them to the command's arguments
+func AddGlobalArguments(cmd Command, arguments map[string]Argument) {
+
+	if _, ok := cmd.(ResultCommand); ok {
+		if _, exists := arguments["output"]; !exists {
+			arguments["output"] = Argument{
+				Description:   "The output format",
+				AllowedValues: []string{OutputTable, OutputJSON, OutputYAML},
+				DefaultValue:  OutputTable,
+			}
+		}
+	}
+}
+
+// Render computes the result of the command and prints it in the format requested with the --output argument
+func Render(cmd ResultCommand, ct *Context) (err error) {
+
+	result, err := cmd.Result(ct)
+	if err != nil {
+		return
+	}
+
+	switch ct.FormattedArguments["output"] {
+	case OutputJSON:
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "unable to json.Marshal result")
+		}
+		fmt.Println(string(out))
+	case OutputYAML:
+		out, err := yaml.Marshal(result)
+		if err != nil {
+			return errors.Wrap(err, "unable to yaml.Marshal result")
+		}
+		fmt.Print(string(out))
+	default:
+		result.PrintTable()
+	}
+
+	return
+}
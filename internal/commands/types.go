@@ -13,6 +13,18 @@ type Command interface {
 	Replicate(repl models.ReplicationData) error
 }
 
+// VersionedCommand describes the replicated commands acting on a single object: their actions carry the vector
+// clock of the object, so that the concurrent actions of several instances are resolved the same way everywhere
+type VersionedCommand interface {
+	ObjectKey(repl models.ReplicationData) string
+}
+
+// SplitCommand describes the replicated commands acting on several objects at once: their action is replicated as
+// the action of a VersionedCommand on each object, so that every object is versioned as if it was acted on alone
+type SplitCommand interface {
+	SplitReplicationData(repl models.ReplicationData) (action string, objects []models.ReplicationData, err error)
+}
+
 type Context struct {
 	User               *models.User
 	Log                *models.Log
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/inpher/sb/internal/types"
 	"github.com/spf13/viper"
@@ -40,16 +41,67 @@ func init() {
 
 			// Commands configuration
 			viper.SetDefault("commands.ssh_command", "ttyrec")
+			viper.SetDefault("commands.ssh_client", "system")
+			viper.SetDefault("commands.forward_agent", true)
+			viper.SetDefault("commands.record_input", false)
+
+			// Egress authentication configuration
+			viper.SetDefault("egress.ca.enabled", false)
+			viper.SetDefault("egress.ca.private-key", "/etc/sb/egress_ca")
+			viper.SetDefault("egress.ca.socket", "/run/sb-ca.sock")
+			viper.SetDefault("egress.ca.validity", "5m")
+			viper.SetDefault("egress.ca.source-addresses", []string{})
+			viper.SetDefault("egress.ca.host-bound-principals", false)
+
+			// Running sessions control configuration
+			viper.SetDefault("sessions.control-socket", "/run/sb-sessions.sock")
+
+			// Port forwarding configuration
+			viper.SetDefault("forwarding.max-duration", "8h")
 
 			// Replication configuration
 			viper.SetDefault("replication.enabled", false)
 			viper.SetDefault("replication.queue.type", "")
 			viper.SetDefault("replication.queue.googlepubsub.project", "")
 			viper.SetDefault("replication.queue.googlepubsub.topic", "")
+			viper.SetDefault("replication.queue.nats.url", "nats://127.0.0.1:4222")
+			viper.SetDefault("replication.queue.nats.credentials", "")
+			viper.SetDefault("replication.queue.nats.stream", "sb")
+			viper.SetDefault("replication.queue.nats.subject", "sb.replication")
+			viper.SetDefault("replication.queue.nats.ack-wait", "30s")
+			viper.SetDefault("replication.queue.redis.address", "")
+			viper.SetDefault("replication.queue.redis.username", "")
+			viper.SetDefault("replication.queue.redis.password", "")
+			viper.SetDefault("replication.queue.redis.db", 0)
+			viper.SetDefault("replication.queue.redis.tls", false)
+			viper.SetDefault("replication.queue.redis.stream", "sb")
+			viper.SetDefault("replication.queue.redis.max-length", 0)
+			viper.SetDefault("replication.verify-interval", "1h")
+			viper.SetDefault("replication.max-attempts", 5)
+			viper.SetDefault("replication.applied-retention", "720h")
+
+			// Audit sinks configuration (a list of sinks, see the documentation)
+			viper.SetDefault("audit.sinks", []interface{}{})
+
+			// Notifications configuration (a list of webhooks, see the documentation)
+			viper.SetDefault("notifications.enabled", false)
+			viper.SetDefault("notifications.max-attempts", 10)
+			viper.SetDefault("notifications.webhooks", []interface{}{})
+
+			// Metrics configuration
+			viper.SetDefault("metrics.enabled", false)
+			viper.SetDefault("metrics.listen", "127.0.0.1:9742")
 
 			// TTYrecs offloading configuration
 			viper.SetDefault("ttyrecsoffloading.enabled", false)
 			viper.SetDefault("ttyrecsoffloading.storage.type", "")
+			viper.SetDefault("ttyrecsoffloading.storage.azureblob.account-name", "")
+			viper.SetDefault("ttyrecsoffloading.storage.azureblob.account-key", "")
+			viper.SetDefault("ttyrecsoffloading.storage.azureblob.container", "")
+			viper.SetDefault("ttyrecsoffloading.storage.azureblob.prefix", "")
+			viper.SetDefault("ttyrecsoffloading.storage.azureblob.endpoint-url", "")
+			viper.SetDefault("ttyrecsoffloading.storage.filesystem.directory", "")
+			viper.SetDefault("ttyrecsoffloading.storage.filesystem.path-layout", "")
 			viper.SetDefault("ttyrecsoffloading.storage.gcs.bucket", "")
 			viper.SetDefault("ttyrecsoffloading.storage.gcs.objects-base-path", "")
 			viper.SetDefault("ttyrecsoffloading.storage.gcs.endpoint-url", "")
@@ -84,6 +136,27 @@ func GetSSHCommand() string {
 	return viper.GetString("commands.ssh_command")
 }
 
+// GetSSHClient returns the SSH client to connect to the distant hosts with: system (default) or native
+func GetSSHClient() string {
+	if viper.GetString("commands.ssh_client") == "native" {
+		return "native"
+	}
+	return "system"
+}
+
+// GetForwardAgent returns whether the agent of the accounts is forwarded to the distant hosts
+func GetForwardAgent() bool {
+	if !viper.IsSet("commands.forward_agent") {
+		return true
+	}
+	return viper.GetBool("commands.forward_agent")
+}
+
+// GetRecordInput returns whether the keystrokes of the sessions are recorded alongside their output
+func GetRecordInput() bool {
+	return viper.GetBool("commands.record_input")
+}
+
 // GetMOSHPortsRange returns the MOSH server ports range
 func GetMOSHPortsRange() string {
 	return viper.GetString("general.mosh_ports_range")
@@ -119,6 +192,31 @@ func GetGlobalDatabasePath() string {
 	return fmt.Sprintf("%s/logs.db", GetSBUserHome())
 }
 
+// GetRequestsDatabasePath returns the global requests database path
+func GetRequestsDatabasePath() string {
+	return fmt.Sprintf("%s/requests.db", GetSBUserHome())
+}
+
+// GetSessionsIndexDatabasePath returns the global sessions search index database path
+func GetSessionsIndexDatabasePath() string {
+	return fmt.Sprintf("%s/sessions-index.db", GetSBUserHome())
+}
+
+// GetNotificationsDatabasePath returns the path of the queue of the notifications to deliver
+func GetNotificationsDatabasePath() string {
+	return fmt.Sprintf("%s/notifications.db", GetSBUserHome())
+}
+
+// GetAuditDatabasePath returns the path of the queue of the audit events to emit
+func GetAuditDatabasePath() string {
+	return fmt.Sprintf("%s/audit.db", GetSBUserHome())
+}
+
+// GetHostsDatabasePath returns the path of the host inventory
+func GetHostsDatabasePath() string {
+	return fmt.Sprintf("%s/hosts.db", GetSBUserHome())
+}
+
 // GetReplicationDatabasePath returns the global database path
 func GetReplicationDatabasePath() string {
 	return fmt.Sprintf("%s/replication.db", GetSBUserHome())
@@ -133,12 +231,31 @@ func GetReplicationEnabled() bool {
 	return viper.GetBool("replication.enabled")
 }
 
+// defaultReplicationMaxAttempts is used when replication.max-attempts is not a positive number
+const defaultReplicationMaxAttempts = 5
+
+// defaultReplicationAppliedRetention is used when replication.applied-retention is not a positive duration
+const defaultReplicationAppliedRetention = 30 * 24 * time.Hour
+
 func GetReplicationQueueConfig() *types.ReplicationQueueConfig {
-	return &types.ReplicationQueueConfig{
-		Enabled:      viper.GetBool("replication.enabled"),
-		QueueType:    viper.GetString("replication.queue.type"),
-		QueueOptions: viper.Sub(fmt.Sprintf("replication.queue.%s", viper.GetString("replication.queue.type"))),
+
+	replicationQueueConfig := &types.ReplicationQueueConfig{
+		Enabled:          viper.GetBool("replication.enabled"),
+		QueueType:        viper.GetString("replication.queue.type"),
+		QueueOptions:     viper.Sub(fmt.Sprintf("replication.queue.%s", viper.GetString("replication.queue.type"))),
+		VerifyInterval:   viper.GetDuration("replication.verify-interval"),
+		MaxAttempts:      viper.GetInt("replication.max-attempts"),
+		AppliedRetention: viper.GetDuration("replication.applied-retention"),
 	}
+
+	if replicationQueueConfig.MaxAttempts <= 0 {
+		replicationQueueConfig.MaxAttempts = defaultReplicationMaxAttempts
+	}
+	if replicationQueueConfig.AppliedRetention <= 0 {
+		replicationQueueConfig.AppliedRetention = defaultReplicationAppliedRetention
+	}
+
+	return replicationQueueConfig
 }
 
 func GetTTYRecsOffloadingConfig() *types.TTYRecsOffloadingConfig {
@@ -148,3 +265,114 @@ func GetTTYRecsOffloadingConfig() *types.TTYRecsOffloadingConfig {
 		StorageOptions: viper.Sub(fmt.Sprintf("ttyrecsoffloading.storage.%s", viper.GetString("ttyrecsoffloading.storage.type"))),
 	}
 }
+
+// GetAuditSinksConfig returns the configuration of the sinks the log entries are emitted to
+func GetAuditSinksConfig() (sinks []*types.AuditSinkConfig) {
+
+	var entries []map[string]interface{}
+	if err := viper.UnmarshalKey("audit.sinks", &entries); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid audit.sinks configuration: %s\n", err)
+		return
+	}
+
+	for _, entry := range entries {
+		options := viper.New()
+		options.MergeConfigMap(entry)
+		sinks = append(sinks, &types.AuditSinkConfig{
+			SinkType:    options.GetString("type"),
+			SinkOptions: options,
+		})
+	}
+
+	return
+}
+
+// defaultNotificationsMaxAttempts is used when notifications.max-attempts is not a positive number
+const defaultNotificationsMaxAttempts = 10
+
+// GetNotificationsConfig returns the configuration of the webhooks the notifications are sent to, by name
+func GetNotificationsConfig() *types.NotificationsConfig {
+
+	notificationsConfig := &types.NotificationsConfig{
+		Enabled:     viper.GetBool("notifications.enabled"),
+		MaxAttempts: viper.GetInt("notifications.max-attempts"),
+		Webhooks:    make(map[string]*types.WebhookConfig),
+	}
+	if notificationsConfig.MaxAttempts <= 0 {
+		notificationsConfig.MaxAttempts = defaultNotificationsMaxAttempts
+	}
+
+	var webhooks []*types.WebhookConfig
+	if err := viper.UnmarshalKey("notifications.webhooks", &webhooks); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid notifications.webhooks configuration: %s\n", err)
+		return notificationsConfig
+	}
+	for _, webhook := range webhooks {
+		notificationsConfig.Webhooks[webhook.Name] = webhook
+	}
+
+	return notificationsConfig
+}
+
+// defaultMetricsListen is used when metrics.listen is not set
+const defaultMetricsListen = "127.0.0.1:9742"
+
+// GetMetricsConfig returns the configuration of the HTTP listener of the daemon exposing the Prometheus metrics
+func GetMetricsConfig() *types.MetricsConfig {
+
+	listen := viper.GetString("metrics.listen")
+	if listen == "" {
+		listen = defaultMetricsListen
+	}
+
+	return &types.MetricsConfig{
+		Enabled: viper.GetBool("metrics.enabled"),
+		Listen:  listen,
+	}
+}
+
+// defaultEgressCAValidity is used when egress.ca.validity is not a valid duration
+const defaultEgressCAValidity = 5 * time.Minute
+
+func GetEgressCAConfig() *types.EgressCAConfig {
+
+	validity, err := time.ParseDuration(viper.GetString("egress.ca.validity"))
+	if err != nil || validity <= 0 {
+		validity = defaultEgressCAValidity
+	}
+
+	socket := viper.GetString("egress.ca.socket")
+	if socket == "" {
+		socket = "/run/sb-ca.sock"
+	}
+
+	return &types.EgressCAConfig{
+		Enabled:             viper.GetBool("egress.ca.enabled"),
+		PrivateKey:          viper.GetString("egress.ca.private-key"),
+		Socket:              socket,
+		Validity:            validity,
+		SourceAddresses:     viper.GetStringSlice("egress.ca.source-addresses"),
+		HostBoundPrincipals: viper.GetBool("egress.ca.host-bound-principals"),
+	}
+}
+
+// GetSessionsControlSocket returns the unix socket the daemon listens on to terminate the running sessions
+func GetSessionsControlSocket() string {
+	socket := viper.GetString("sessions.control-socket")
+	if socket == "" {
+		socket = "/run/sb-sessions.sock"
+	}
+	return socket
+}
+
+// defaultForwardingMaxDuration is used when forwarding.max-duration is not a valid duration
+const defaultForwardingMaxDuration = 8 * time.Hour
+
+// GetForwardingMaxDuration returns the maximum duration of a port forwarding session
+func GetForwardingMaxDuration() time.Duration {
+	d, err := time.ParseDuration(viper.GetString("forwarding.max-duration"))
+	if err != nil || d <= 0 {
+		return defaultForwardingMaxDuration
+	}
+	return d
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"math/rand"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -369,6 +370,74 @@ func GetHostname() (hostname string, err error) {
 	return os.Hostname()
 }
 
+// ParseDuration parses a duration like time.ParseDuration does, but also accepts days (d) and weeks (w) units
+func ParseDuration(s string) (d time.Duration, err error) {
+
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	for unit, multiplier := range units {
+		if strings.HasSuffix(s, unit) {
+			n, errConv := strconv.Atoi(strings.TrimSuffix(s, unit))
+			if errConv != nil {
+				return d, fmt.Errorf("invalid duration %s", s)
+			}
+			return time.Duration(n) * multiplier, nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}
+
+// ParseDate parses a date given either as RFC3339, as a day (2006-01-02), or as a duration before now (e.g. 12h, 7d)
+func ParseDate(s string, now time.Time) (date time.Time, err error) {
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		date, err = time.Parse(layout, s)
+		if err == nil {
+			return
+		}
+	}
+
+	d, err := ParseDuration(s)
+	if err != nil {
+		return date, fmt.Errorf("invalid date %s: expected a RFC3339 date, a day (2006-01-02) or a duration (e.g. 12h, 7d)", s)
+	}
+
+	return now.Add(-d), nil
+}
+
+// ParseExpiration computes an expiration date from either a duration (--expires-in) or a RFC3339 date (--expires-at)
+// A zero time is returned if none is provided
+func ParseExpiration(expiresIn, expiresAt string, from time.Time) (expiration time.Time, err error) {
+
+	if expiresIn != "" && expiresAt != "" {
+		return expiration, fmt.Errorf("--expires-in and --expires-at can't be used together")
+	}
+
+	if expiresIn != "" {
+		d, errDuration := ParseDuration(expiresIn)
+		if errDuration != nil || d <= 0 {
+			return expiration, fmt.Errorf("--expires-in should be a positive duration (e.g. 12h, 14d, 2w)")
+		}
+		return from.Add(d), nil
+	}
+
+	if expiresAt != "" {
+		expiration, err = time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return expiration, fmt.Errorf("--expires-at should be a RFC3339 date (e.g. 2006-01-02T15:04:05Z)")
+		}
+		if !expiration.After(from) {
+			return expiration, fmt.Errorf("--expires-at should be in the future")
+		}
+	}
+
+	return
+}
+
 func DecryptFile(filepathIn, filepathOut, decryptionKey string) (err error) {
 
 	var file *os.File
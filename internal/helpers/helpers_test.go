@@ -2,7 +2,11 @@ package helpers
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -177,3 +181,77 @@ func TestParseArgumentsSBArguments(t *testing.T) {
 	}
 
 }
+
+func TestParseExpiration(t *testing.T) {
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	expiration, err := ParseExpiration("", "", now)
+	require.NoError(t, err, "There was an unexpected error parsing an empty expiration")
+	require.True(t, expiration.IsZero(), "An empty expiration should return a zero time")
+
+	expiration, err = ParseExpiration("14d", "", now)
+	require.NoError(t, err, "There was an unexpected error parsing a duration in days")
+	require.Equal(t, now.Add(14*24*time.Hour), expiration, "The expiration in days was badly computed")
+
+	expiration, err = ParseExpiration("2w", "", now)
+	require.NoError(t, err, "There was an unexpected error parsing a duration in weeks")
+	require.Equal(t, now.Add(14*24*time.Hour), expiration, "The expiration in weeks was badly computed")
+
+	expiration, err = ParseExpiration("90m", "", now)
+	require.NoError(t, err, "There was an unexpected error parsing a standard duration")
+	require.Equal(t, now.Add(90*time.Minute), expiration, "The standard expiration was badly computed")
+
+	expiration, err = ParseExpiration("", "2024-01-15T12:00:00Z", now)
+	require.NoError(t, err, "There was an unexpected error parsing a date")
+	require.Equal(t, now.Add(14*24*time.Hour), expiration, "The expiration date was badly parsed")
+
+	_, err = ParseExpiration("14d", "2024-01-15T12:00:00Z", now)
+	require.Error(t, err, "Using both options should return an error")
+
+	_, err = ParseExpiration("-1h", "", now)
+	require.Error(t, err, "A negative duration should return an error")
+
+	_, err = ParseExpiration("", "2023-12-31T12:00:00Z", now)
+	require.Error(t, err, "A date in the past should return an error")
+
+	_, err = ParseExpiration("", "tomorrow", now)
+	require.Error(t, err, "An invalid date should return an error")
+}
+
+func TestParseDate(t *testing.T) {
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	date, err := ParseDate("2024-01-01T08:00:00Z", now)
+	require.NoError(t, err, "There was an unexpected error parsing a RFC3339 date")
+	require.Equal(t, time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), date, "The RFC3339 date was badly parsed")
+
+	date, err = ParseDate("2024-01-01", now)
+	require.NoError(t, err, "There was an unexpected error parsing a day")
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), date, "The day was badly parsed")
+
+	date, err = ParseDate("7d", now)
+	require.NoError(t, err, "There was an unexpected error parsing a duration")
+	require.Equal(t, time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC), date, "The duration was badly computed")
+
+	_, err = ParseDate("yesterday", now)
+	require.Error(t, err, "An invalid date should return an error")
+}
+
+func TestTerminateProcess(t *testing.T) {
+
+	cmd := exec.Command("sleep", "60")
+	require.NoError(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	uid := strconv.Itoa(os.Getuid())
+
+	// Only a process of the specified user is terminated, and never the init or a process group
+	require.Error(t, TerminateProcess(strconv.Itoa(os.Getuid()+1), cmd.Process.Pid))
+	require.Error(t, TerminateProcess(uid, -1))
+	require.Error(t, TerminateProcess(uid, 1))
+
+	require.NoError(t, TerminateProcess(uid, cmd.Process.Pid))
+	require.Error(t, cmd.Wait())
+}
@@ -0,0 +1,70 @@
+package helpers
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// CancelableReader reads from a file until it's canceled. It waits for the file to be readable before reading it,
+// so that once canceled, nothing more is read: the input typed afterwards stays for the next reader of the file
+// (in interactive mode, the prompt of sb).
+type CancelableReader struct {
+	file     *os.File
+	cancelR  *os.File
+	cancelW  *os.File
+	mutex    sync.Mutex
+	canceled bool
+}
+
+// NewCancelableReader returns a CancelableReader of a file
+func NewCancelableReader(file *os.File) (r *CancelableReader, err error) {
+
+	cancelR, cancelW, err := os.Pipe()
+	if err != nil {
+		return
+	}
+
+	return &CancelableReader{file: file, cancelR: cancelR, cancelW: cancelW}, nil
+}
+
+// Read reads from the file once it's readable, or returns io.EOF once canceled
+func (r *CancelableReader) Read(p []byte) (n int, err error) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.canceled {
+		return 0, io.EOF
+	}
+
+	fds := []unix.PollFd{
+		{Fd: int32(r.file.Fd()), Events: unix.POLLIN},
+		{Fd: int32(r.cancelR.Fd()), Events: unix.POLLIN},
+	}
+	for {
+		_, err = unix.Poll(fds, -1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if fds[1].Revents != 0 {
+			r.canceled = true
+			r.cancelR.Close()
+			return 0, io.EOF
+		}
+		if fds[0].Revents != 0 {
+			return r.file.Read(p)
+		}
+	}
+}
+
+// Cancel stops the reads: a pending one returns io.EOF without reading the file
+func (r *CancelableReader) Cancel() {
+	r.cancelW.Close()
+}
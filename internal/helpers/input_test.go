@@ -0,0 +1,44 @@
+package helpers
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelableReader(t *testing.T) {
+
+	file, input, err := os.Pipe()
+	require.NoError(t, err)
+	defer file.Close()
+	defer input.Close()
+
+	r, err := NewCancelableReader(file)
+	require.NoError(t, err)
+
+	io.WriteString(input, "a")
+	read := make([]byte, 1)
+	n, err := r.Read(read)
+	require.NoError(t, err)
+	require.Equal(t, "a", string(read[:n]))
+
+	// A pending read returns without reading anything once canceled
+	done := make(chan error)
+	go func() {
+		_, err := r.Read(read)
+		done <- err
+	}()
+	r.Cancel()
+	select {
+	case err = <-done:
+		require.Equal(t, io.EOF, err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Read() still pending once canceled")
+	}
+
+	_, err = r.Read(read)
+	require.Equal(t, io.EOF, err)
+}
@@ -20,6 +20,12 @@ type SSHSession struct {
 	PortTo    string
 	UserTo    string
 	Allowed   bool
+
+	TerminalWidth  int
+	TerminalHeight int
+
+	BastionHost string
+	ProcessID   int
 }
 
 func (s *SSHSession) String() (str string) {
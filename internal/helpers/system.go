@@ -11,16 +11,19 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"os/user"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/inpher/sb/internal/config"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/sys/unix"
 )
 
 var (
@@ -152,6 +155,7 @@ func CreateHomeSkeleton(homedir string, username string, homeType string) (err e
 		pathConfigurations = append(pathConfigurations,
 			pathConfiguration{action: "/bin/mkdir", path: ".ssh", chmod: "0755", chown: fmt.Sprintf("%s:%s", username, username)},
 			pathConfiguration{action: "/bin/mkdir", path: "ttyrecs", chmod: "0755", chown: fmt.Sprintf("%s:%s", username, username)},
+			pathConfiguration{action: "/bin/mkdir", path: "forwards", chmod: "0700", chown: fmt.Sprintf("%s:%s", username, username)},
 			pathConfiguration{action: "/usr/bin/touch", path: "accesses.db", chmod: "0640", chown: fmt.Sprintf("%s:%s", username, username)},
 			pathConfiguration{action: "/usr/bin/touch", path: "logs.db", chmod: "0640", chown: fmt.Sprintf("%s:%s", username, username)},
 			pathConfiguration{action: "/usr/bin/touch", path: ".ssh/authorized_keys", chmod: "0640", chown: fmt.Sprintf("%s:%s", username, username)},
@@ -480,6 +484,63 @@ func GenerateNewEgressKey(algo string, size string, passphrase string, username
 	return
 }
 
+// TerminateProcess sends SIGTERM to a process, if it belongs to the system user with the specified uid.
+// The process is referred to by a pidfd, so that the signal can't reach another process that reused its PID.
+func TerminateProcess(uid string, pid int) (err error) {
+
+	if pid <= 1 {
+		return fmt.Errorf("invalid PID %d", pid)
+	}
+
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open process %d", pid)
+	}
+	defer unix.Close(pidfd)
+
+	// If the process exited meanwhile, the signal sent through the pidfd fails instead of reaching the new owner of the PID
+	info, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	if err != nil {
+		return errors.Wrapf(err, "unable to stat process %d", pid)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || strconv.FormatUint(uint64(stat.Uid), 10) != uid {
+		return fmt.Errorf("process %d doesn't belong to uid %s", pid, uid)
+	}
+
+	err = unix.PidfdSendSignal(pidfd, unix.SIGTERM, nil, 0)
+	if err != nil {
+		return errors.Wrapf(err, "unable to terminate process %d", pid)
+	}
+
+	return
+}
+
+// GetPeerUID returns the uid of the process connected to a unix socket, as the kernel tells it (SO_PEERCRED)
+func GetPeerUID(conn *net.UnixConn) (uid uint32, err error) {
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		err = errors.Wrap(err, "unable to get raw connection")
+		return
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err == nil {
+		err = credErr
+	}
+	if err != nil {
+		err = errors.Wrap(err, "unable to get peer credentials")
+		return
+	}
+
+	return cred.Uid, nil
+}
+
 // GetEtcGroupFilePath returns /etc/group or an other specifically set filepath
 func GetEtcGroupFilePath() string {
 	if etcGroupFilePath != "" {
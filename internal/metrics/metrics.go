@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The types of the metrics
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+// Metric describes a metric exposed in the Prometheus text format
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []*Sample
+}
+
+// Sample describes a value of a metric, with its labels
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector returns the current value of the metrics
+type Collector func() ([]*Metric, error)
+
+// Write writes the metrics in the Prometheus text format
+func Write(w io.Writer, metrics []*Metric) (err error) {
+
+	for _, m := range metrics {
+		_, err = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.Name, escape(m.Help, false), m.Name, m.Type)
+		if err != nil {
+			return
+		}
+
+		for _, s := range m.Samples {
+			_, err = fmt.Fprintf(w, "%s%s %s\n", m.Name, formatLabels(s.Labels), strconv.FormatFloat(s.Value, 'g', -1, 64))
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// NewServer returns the HTTP server exposing the metrics on /metrics
+func NewServer(address string, collect Collector) *http.Server {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+
+		metrics, err := collect()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// The metrics are rendered first, so that an error is never sent after a partial body
+		var body bytes.Buffer
+		err = Write(&body, metrics)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(body.Bytes())
+	})
+
+	return &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// ListenAndServe exposes the metrics on the address until the server fails
+func ListenAndServe(address string, collect Collector) (err error) {
+	err = NewServer(address, collect).ListenAndServe()
+	return errors.Wrapf(err, "unable to serve the metrics on %s", address)
+}
+
+func formatLabels(labels map[string]string) string {
+
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escape(labels[name], true)))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
+
+// escape escapes the backslashes and the line feeds of a help text or a label value, and the double quotes of the latter
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
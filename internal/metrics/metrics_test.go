@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+
+	metrics := []*Metric{
+		{
+			Name: "sb_pending",
+			Help: "The pending entries",
+			Type: Gauge,
+			Samples: []*Sample{
+				{Value: 3},
+			},
+		},
+		{
+			Name: "sb_errors_total",
+			Help: "The errors\nper instance",
+			Type: Counter,
+			Samples: []*Sample{
+				{Labels: map[string]string{"instance": "sb1", "step": `push "1"`}, Value: 1.5},
+				{Labels: map[string]string{"instance": `sb\2`}, Value: 0},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, metrics))
+	require.Equal(t, `# HELP sb_pending The pending entries
+# TYPE sb_pending gauge
+sb_pending 3
+# HELP sb_errors_total The errors\nper instance
+# TYPE sb_errors_total counter
+sb_errors_total{instance="sb1",step="push \"1\""} 1.5
+sb_errors_total{instance="sb\\2"} 0
+`, out.String())
+}
+
+func TestServer(t *testing.T) {
+
+	server := NewServer("127.0.0.1:0", func() ([]*Metric, error) {
+		return []*Metric{{Name: "sb_up", Help: "Up", Type: Gauge, Samples: []*Sample{{Value: 1}}}}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	res := recorder.Result()
+	require.Equal(t, 200, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, "# HELP sb_up Up\n# TYPE sb_up gauge\nsb_up 1\n", string(body))
+}
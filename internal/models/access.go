@@ -5,6 +5,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/inpher/sb/internal/helpers"
@@ -23,6 +24,11 @@ type Access struct {
 	Port    int    `gorm:"type:varchar(5);unique_index:host_user_prefix_port"`
 	Comment string `gorm:"type:text"`
 	IP      net.IP `gorm:"-"`
+
+	ExpiresAt         time.Time `gorm:"type:datetime"` // Zero value means the access never expires
+	ForwardingAllowed bool      `gorm:"type:bool"`     // Whether the access can be used for port forwarding
+
+	Selector string `gorm:"type:text"` // Label selector like "env=staging AND team=db": the access is granted to the matching hosts of the inventory
 }
 
 // BeforeCreate will set a UUID if not present
@@ -95,8 +101,21 @@ func BuildSBAccess(host, user, port, alias string, strictHostCheck bool) (ba *Ac
 
 		typeGiven = "HOST"
 
+		// Names and aliases of the inventory stand for its canonical name
+		inventoryHost, err := FindInventoryHost(host)
+		if err != nil {
+			return ba, err
+		}
+		if inventoryHost != nil {
+			host = inventoryHost.Name
+		}
+
 		// OK, maybe it's a host, we will try to resolve it
 		ips, err := net.LookupIP(host)
+		if err != nil && inventoryHost != nil && len(inventoryHost.IPs()) > 0 {
+			// The inventory knows its addresses
+			ips, err = inventoryHost.IPs(), nil
+		}
 		switch {
 		case err == nil:
 			for _, ip := range ips {
@@ -172,6 +191,31 @@ func BuildSBAccess(host, user, port, alias string, strictHostCheck bool) (ba *Ac
 	return
 }
 
+// buildSelectorAccess builds an access granted to the hosts of the inventory matching a label selector
+func buildSelectorAccess(selector, user, port string) (ba *Access, err error) {
+
+	selector, err = NormalizeSelector(selector)
+	if err != nil {
+		return
+	}
+
+	var intPort int
+	if port != "" {
+		intPort, err = strconv.Atoi(port)
+		if err != nil {
+			return ba, fmt.Errorf("port is invalid")
+		}
+	}
+
+	ba = &Access{
+		Selector: selector,
+		User:     user,
+		Port:     intPort,
+	}
+
+	return
+}
+
 // BuildSBAccessFromUserInput deserializes a 'user@host:port' string into a SBAccess struct
 func BuildSBAccessFromUserInput(access string) (ba *Access, err error) {
 
@@ -180,6 +224,21 @@ func BuildSBAccessFromUserInput(access string) (ba *Access, err error) {
 		return
 	}
 
+	// The inventory provides the canonical name, and the user and port to use when they're not given
+	inventoryHost, err := FindInventoryHost(host)
+	if err != nil {
+		return
+	}
+	if inventoryHost != nil {
+		host = inventoryHost.Name
+		if user == "" {
+			user = inventoryHost.User
+		}
+		if port == 0 {
+			port = inventoryHost.Port
+		}
+	}
+
 	// The alias variable is always empty from user input
 	// It is only defined when a user builds an access to store in database
 	ba, err = BuildSBAccess(host, user, strconv.Itoa(port), "", false)
@@ -221,6 +280,39 @@ func LoadSBAccess(host, user, port string, db *gorm.DB) (ba *Access, err error)
 	return
 }
 
+// LoadSelectorAccess loads an access granted with a label selector stored in database
+func LoadSelectorAccess(selector, user, port string, db *gorm.DB) (ba *Access, err error) {
+
+	a, err := buildSelectorAccess(selector, user, port)
+	if err != nil {
+		return
+	}
+
+	ba = &Access{}
+	err = db.Where(&a).First(&ba).Error
+
+	return
+}
+
+// DeleteExpiredAccesses removes all the expired accesses from the provided database and returns them
+func DeleteExpiredAccesses(db *gorm.DB) (accesses []*Access, err error) {
+
+	// Dates are stored as UTC strings, so we compare with UTC dates
+	err = db.Where("expires_at > ? AND expires_at <= ?", time.Time{}, time.Now().UTC()).Find(&accesses).Error
+	if err != nil {
+		return
+	}
+
+	for _, a := range accesses {
+		err = a.Delete(db)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
 // Delete removes the access from the provided database
 func (ba *Access) Delete(db *gorm.DB) (err error) {
 
@@ -245,6 +337,11 @@ func (ba *Access) Equals(a *Access) bool {
 	return true
 }
 
+// IsExpired returns true if the access had an expiration date that is now past
+func (ba *Access) IsExpired() bool {
+	return !ba.ExpiresAt.IsZero() && !time.Now().Before(ba.ExpiresAt)
+}
+
 // Save saves the access in the provided database
 func (ba *Access) Save(db *gorm.DB) (err error) {
 	return db.Save(ba).Error
@@ -253,7 +350,17 @@ func (ba *Access) Save(db *gorm.DB) (err error) {
 // String returns a pretty print display of the access
 func (ba *Access) String() string {
 	green := color.New(color.FgGreen).SprintFunc()
-	return fmt.Sprintf("%s: %-20s | %s: %-20s | %s: %-20s | %s: %-10s | %s: %-5d", green("Prefix"), ba.Prefix, green("Host"), ba.Host, green("Alias"), ba.Alias, green("User"), ba.User, green("Port"), ba.Port)
+	str := fmt.Sprintf("%s: %-20s | %s: %-20s | %s: %-20s | %s: %-10s | %s: %-5d", green("Prefix"), ba.Prefix, green("Host"), ba.Host, green("Alias"), ba.Alias, green("User"), ba.User, green("Port"), ba.Port)
+	if ba.Selector != "" {
+		str = fmt.Sprintf("%s: %-66s | %s: %-10s | %s: %-5d", green("Selector"), ba.Selector, green("User"), ba.User, green("Port"), ba.Port)
+	}
+	if !ba.ExpiresAt.IsZero() {
+		str += fmt.Sprintf(" | %s: %s", green("Expires"), ba.ExpiresAt.Format(time.RFC3339))
+	}
+	if ba.ForwardingAllowed {
+		str += fmt.Sprintf(" | %s", green("Forwarding allowed"))
+	}
+	return str
 }
 
 // ShortString returns a pretty print short display of the access
@@ -265,6 +372,9 @@ func (ba *Access) ShortString() string {
 	if ba.Host != "" {
 		host = ba.Host
 	}
+	if ba.Selector != "" {
+		host = fmt.Sprintf("[%s]", ba.Selector)
+	}
 	if ba.Port != 0 {
 		port = fmt.Sprintf(":%d", ba.Port)
 	}
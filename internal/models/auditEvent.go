@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/notification"
+	"github.com/inpher/sb/internal/types"
+	"github.com/pkg/errors"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// QueuedAuditEvent is an audit event waiting to be emitted to a sink by the daemon
+type QueuedAuditEvent struct {
+	ID          uint      `gorm:"primaryKey"`
+	Sink        int       `gorm:"type:integer"` // The index of the sink in the configuration
+	Event       string    `gorm:"type:text"`    // The JSON audit event
+	CreatedAt   time.Time `gorm:"type:datetime"`
+	Attempts    int       `gorm:"type:integer"`  // The number of failed attempts
+	NextAttempt time.Time `gorm:"type:datetime"` // When to try to emit it next
+	LastError   string    `gorm:"type:text"`     // Why the last attempt failed
+}
+
+// GetAuditGormDB returns a DB handler
+func GetAuditGormDB(database string) (db *gorm.DB, err error) {
+
+	// We open the DB
+	db, err = gorm.Open(sqlite.Open(database), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to connect to audit database %s", database)
+		return
+	}
+
+	// Migrate the schema (this will create table or alter table if needed)
+	db.AutoMigrate(&QueuedAuditEvent{})
+
+	return
+}
+
+// QueueAuditEvent queues an event for each configured audit sink
+func QueueAuditEvent(db *gorm.DB, sinks int, event *types.AuditEvent) (err error) {
+
+	if sinks == 0 {
+		return
+	}
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal audit event")
+	}
+
+	events := make([]*QueuedAuditEvent, 0, sinks)
+	for sink := 0; sink < sinks; sink++ {
+		events = append(events, &QueuedAuditEvent{
+			Sink:        sink,
+			Event:       string(eventJSON),
+			NextAttempt: time.Now(),
+		})
+	}
+
+	err = db.Create(events).Error
+	if err != nil {
+		err = errors.Wrapf(err, "unable to queue %s audit event", event.Action)
+	}
+
+	return
+}
+
+// GetQueuedAuditEvents returns the queued audit events, the oldest first
+func GetQueuedAuditEvents(db *gorm.DB, limit int) (events []*QueuedAuditEvent, err error) {
+
+	err = db.Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		err = errors.Wrap(err, "unable to get the audit events to emit")
+	}
+
+	return
+}
+
+// AuditEvent returns the queued audit event
+func (e *QueuedAuditEvent) AuditEvent() (event *types.AuditEvent, err error) {
+
+	event = new(types.AuditEvent)
+	err = json.Unmarshal([]byte(e.Event), event)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to unmarshal audit event %d", e.ID)
+	}
+
+	return
+}
+
+// Delete removes the audit event from the queue
+func (e *QueuedAuditEvent) Delete(db *gorm.DB) (err error) {
+	return db.Delete(e).Error
+}
+
+// IsDue returns whether the audit event should be emitted now
+func (e *QueuedAuditEvent) IsDue() bool {
+	return !e.NextAttempt.After(time.Now())
+}
+
+// Postpone records a failed attempt, and schedules the next one
+func (e *QueuedAuditEvent) Postpone(db *gorm.DB, attemptError error) (err error) {
+	e.Attempts++
+	e.LastError = attemptError.Error()
+	e.NextAttempt = time.Now().Add(notification.RetryDelay(e.Attempts))
+	return db.Save(e).Error
+}
+
+// queueAuditEvent queues the audit event in the audit database of sb, for the daemon to emit it
+func queueAuditEvent(event *types.AuditEvent) (err error) {
+
+	sinks := len(config.GetAuditSinksConfig())
+	if sinks == 0 {
+		return
+	}
+
+	db, err := GetAuditGormDB(config.GetAuditDatabasePath())
+	if err != nil {
+		return
+	}
+
+	return QueueAuditEvent(db, sinks, event)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/inpher/sb/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditEventsQueue(t *testing.T) {
+
+	db, err := GetAuditGormDB(filepath.Join(t.TempDir(), "audit.db"))
+	require.NoError(t, err)
+
+	// One event is queued for each sink
+	require.NoError(t, QueueAuditEvent(db, 2, &types.AuditEvent{Action: "create", Account: "t1000"}))
+
+	events, err := GetQueuedAuditEvents(db, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, 0, events[0].Sink)
+	require.Equal(t, 1, events[1].Sink)
+	require.True(t, events[0].IsDue())
+
+	event, err := events[0].AuditEvent()
+	require.NoError(t, err)
+	require.Equal(t, "create", event.Action)
+	require.Equal(t, "t1000", event.Account)
+
+	// A failed event is retried later
+	require.NoError(t, events[0].Postpone(db, errors.New("connection refused")))
+	var postponed QueuedAuditEvent
+	require.NoError(t, db.First(&postponed, events[0].ID).Error)
+	require.Equal(t, 1, postponed.Attempts)
+	require.Equal(t, "connection refused", postponed.LastError)
+	require.True(t, postponed.NextAttempt.After(time.Now()))
+	require.False(t, postponed.IsDue())
+
+	require.NoError(t, events[1].Delete(db))
+	events, err = GetQueuedAuditEvents(db, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	// Without sinks, nothing is queued
+	require.NoError(t, QueueAuditEvent(db, 0, &types.AuditEvent{Action: "update"}))
+	events, err = GetQueuedAuditEvents(db, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/inpher/sb/internal/helpers"
 
@@ -76,13 +77,42 @@ func GetGroup(groupName string) (group *Group, err error) {
 }
 
 // AddAccess adds an access to the group
-func (bg *Group) AddAccess(host, user, port, alias, comment string, db ...*gorm.DB) (ba *Access, err error) {
+func (bg *Group) AddAccess(host, user, port, alias, comment string, expiresAt time.Time, forwardingAllowed bool, db ...*gorm.DB) (ba *Access, err error) {
 	ba, err = BuildSBAccess(host, user, port, alias, true)
 	if err != nil {
 		return
 	}
 
 	ba.Comment = comment
+	ba.ExpiresAt = expiresAt.UTC()
+	ba.ForwardingAllowed = forwardingAllowed
+
+	var dbHandler *gorm.DB
+	if len(db) > 0 {
+		dbHandler = db[0]
+	} else {
+		dbHandler, err = GetAccessGormDB(bg.getDatabaseAccessFilePath())
+		if err != nil {
+			return
+		}
+	}
+
+	err = ba.Save(dbHandler)
+
+	return
+}
+
+// AddSelectorAccess grants the group an access to all the hosts of the inventory matching a label selector
+func (bg *Group) AddSelectorAccess(selector, user, port, comment string, expiresAt time.Time, forwardingAllowed bool, db ...*gorm.DB) (ba *Access, err error) {
+
+	ba, err = buildSelectorAccess(selector, user, port)
+	if err != nil {
+		return
+	}
+
+	ba.Comment = comment
+	ba.ExpiresAt = expiresAt.UTC()
+	ba.ForwardingAllowed = forwardingAllowed
 
 	var dbHandler *gorm.DB
 	if len(db) > 0 {
@@ -164,6 +194,29 @@ func (bg *Group) DeleteAccess(host, user, port string, db ...*gorm.DB) (ba *Acce
 	return
 }
 
+// DeleteSelectorAccess removes an access granted with a label selector from the group
+func (bg *Group) DeleteSelectorAccess(selector, user, port string, db ...*gorm.DB) (ba *Access, err error) {
+
+	var dbHandler *gorm.DB
+	if len(db) > 0 {
+		dbHandler = db[0]
+	} else {
+		dbHandler, err = GetAccessGormDB(bg.getDatabaseAccessFilePath())
+		if err != nil {
+			return
+		}
+	}
+
+	ba, err = LoadSelectorAccess(selector, user, port, dbHandler)
+	if err != nil {
+		return
+	}
+
+	err = ba.Delete(dbHandler)
+
+	return
+}
+
 // DisplayPubKeys pretty displays the public key
 func (bg *Group) DisplayPubKeys(keyType string) (str string, keys []helpers.PublicKey, err error) {
 
@@ -272,6 +325,22 @@ func (bg *Group) OverrideKeyFilesRootDir(path string) {
 	bg.OverriddenKeyFilesRootDir = path
 }
 
+// PurgeExpiredAccesses deletes the expired accesses from the group access database
+func (bg *Group) PurgeExpiredAccesses(db ...*gorm.DB) (accesses []*Access, err error) {
+
+	var dbHandler *gorm.DB
+	if len(db) > 0 {
+		dbHandler = db[0]
+	} else {
+		dbHandler, err = GetAccessGormDB(bg.getDatabaseAccessFilePath())
+		if err != nil {
+			return
+		}
+	}
+
+	return DeleteExpiredAccesses(dbHandler)
+}
+
 // getDatabaseAccessFilePath returns the filepath of the private authorized accesses
 func (bg *Group) getDatabaseAccessFilePath() string {
 	if bg.OverriddenDatabaseAccessFilePath != "" {
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/inpher/sb/internal/helpers"
 
@@ -167,11 +168,11 @@ func TestAddAccessNoHandler(t *testing.T) {
 	group.OverrideDatabaseAccessFilePath(":memory:")
 
 	// Add an access without a DB handler
-	_, err = group.AddAccess("test.com", "root", "22", "test", "Added for tests")
+	_, err = group.AddAccess("test.com", "root", "22", "test", "Added for tests", time.Time{}, false)
 	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
 
 	// Add an access that fails a DB handler
-	_, err = group.AddAccess("NOT_A_VALID_HOST", "root", "22", "test", "Added for tests")
+	_, err = group.AddAccess("NOT_A_VALID_HOST", "root", "22", "test", "Added for tests", time.Time{}, false)
 	require.Error(t, err, fmt.Errorf("host is neither an IP, a prefix or a resolvable host"), "An error should have occurred when calling AddAccess")
 }
 
@@ -215,7 +216,7 @@ func TestGroupAccesses(t *testing.T) {
 	db, err := GetAccessGormDB(":memory:")
 	require.NoError(t, err, "An unexpected error occurred when getting the database handler")
 
-	ba, err := group.AddAccess("test.com", "root", "22", "test", "Added for tests", db)
+	ba, err := group.AddAccess("test.com", "root", "22", "test", "Added for tests", time.Time{}, false, db)
 	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
 	// We reset the data we don't store in database for comparaison later
 	ba.IP = nil
@@ -0,0 +1,457 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/inpher/sb/internal/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Host describes a host of the inventory
+type Host struct {
+	Name        string `gorm:"PRIMARY_KEY"`      // The canonical name of the host
+	Aliases     string `gorm:"type:text"`        // Other names of the host, separated by commas
+	Addresses   string `gorm:"type:text"`        // The IPs of the host, separated by commas, used when its name doesn't resolve
+	Port        int    `gorm:"type:integer"`     // The SSH port used when the user doesn't provide one (0 if not set)
+	User        string `gorm:"type:varchar(50)"` // The remote user used when the user doesn't provide one
+	Tags        string `gorm:"type:text"`        // The labels of the host, as sorted key=value pairs separated by commas
+	Description string `gorm:"type:text"`
+}
+
+var (
+	hostnamePattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+	listSeparator     = regexp.MustCompile(`[\s,]+`)
+	labelPattern      = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._/-]*)=([A-Za-z0-9._/-]*)$`)
+	tagsSeparator     = regexp.MustCompile(`\s*,\s*`)
+	selectorSeparator = regexp.MustCompile(`(?i)\s*,\s*|\s+AND\s+`)
+)
+
+// GetHostsGormDB returns a DB handler
+func GetHostsGormDB(database string) (db *gorm.DB, err error) {
+
+	// We open the DB
+	db, err = gorm.Open(sqlite.Open(database), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to connect to hosts database %s", database)
+		return
+	}
+
+	// Migrate the schema (this will create table or alter table if needed)
+	db.AutoMigrate(&Host{})
+
+	return
+}
+
+// GetHost returns a host of the inventory, or nil if it is not in it
+func GetHost(db *gorm.DB, name string) (host *Host, err error) {
+
+	var hosts []*Host
+	err = db.Where("name = ?", name).Limit(1).Find(&hosts).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get host %s", name)
+	}
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+
+	return hosts[0], nil
+}
+
+// GetAllHosts returns the hosts of the inventory, sorted by name
+func GetAllHosts(db *gorm.DB) (hosts []*Host, err error) {
+
+	err = db.Order("name").Find(&hosts).Error
+	if err != nil {
+		err = errors.Wrap(err, "unable to get the hosts")
+	}
+
+	return
+}
+
+// FindHost returns the host of the inventory with this name or alias, or nil if there is none
+func FindHost(db *gorm.DB, nameOrAlias string) (host *Host, err error) {
+
+	host, err = GetHost(db, nameOrAlias)
+	if err != nil || host != nil {
+		return
+	}
+
+	hosts, err := GetAllHosts(db)
+	if err != nil {
+		return
+	}
+	for _, h := range hosts {
+		for _, alias := range h.AliasesList() {
+			if alias == nameOrAlias {
+				return h, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// FindInventoryHost returns the host of the inventory with this name or alias, or nil if there is none
+// (or if there is no inventory on this instance)
+func FindInventoryHost(nameOrAlias string) (host *Host, err error) {
+
+	if nameOrAlias == "" {
+		return
+	}
+
+	// We don't create the inventory just to look into it
+	if _, errStat := os.Stat(config.GetHostsDatabasePath()); errStat != nil {
+		return
+	}
+
+	db, err := GetHostsGormDB(config.GetHostsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	return FindHost(db, nameOrAlias)
+}
+
+// GetInventoryLabels returns the labels of a host in the inventory (none if it is not in it)
+func GetInventoryLabels(nameOrAlias string) (labels map[string]string, err error) {
+
+	host, err := FindInventoryHost(nameOrAlias)
+	if err != nil || host == nil {
+		return make(map[string]string), err
+	}
+
+	return host.Labels(), nil
+}
+
+// CheckInventoryConflicts checks that the names and aliases of hosts, added to the inventory or replacing
+// its entries, don't belong to two different hosts
+func CheckInventoryConflicts(db *gorm.DB, hosts []*Host) (err error) {
+
+	existingHosts, err := GetAllHosts(db)
+	if err != nil {
+		return
+	}
+
+	replaced := make(map[string]bool)
+	for _, h := range hosts {
+		if replaced[h.Name] {
+			return fmt.Errorf("host %s is defined twice", h.Name)
+		}
+		replaced[h.Name] = true
+	}
+
+	owners := make(map[string]string)
+	for _, h := range append(existingHosts, hosts...) {
+		if replaced[h.Name] && !containsHost(hosts, h) {
+			continue
+		}
+		for _, name := range append([]string{h.Name}, h.AliasesList()...) {
+			if owner, exists := owners[name]; exists && owner != h.Name {
+				return fmt.Errorf("%s is already a name of host %s", name, owner)
+			}
+			owners[name] = h.Name
+		}
+	}
+
+	return
+}
+
+// SaveHosts adds hosts to the inventory, or replaces them, at once
+func SaveHosts(db *gorm.DB, hosts []*Host) (err error) {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, h := range hosts {
+			if err := h.Save(tx); err != nil {
+				return errors.Wrapf(err, "unable to save host %s", h.Name)
+			}
+		}
+		return nil
+	})
+}
+
+// ParseHostsCSV parses hosts from CSV data: the first line names the columns, among name (required), aliases,
+// addresses, port, user, tags and description. Aliases and addresses are separated by commas or spaces.
+func ParseHostsCSV(r io.Reader) (hosts []*Host, err error) {
+
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read CSV")
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the CSV data is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, column := range records[0] {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case "name", "aliases", "addresses", "port", "user", "tags", "description":
+			columns[column] = i
+		default:
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("the CSV data has no name column")
+	}
+
+	for line, record := range records[1:] {
+		field := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		host := &Host{
+			Name:        field("name"),
+			Aliases:     field("aliases"),
+			Addresses:   field("addresses"),
+			User:        field("user"),
+			Tags:        field("tags"),
+			Description: field("description"),
+		}
+		if field("port") != "" {
+			host.Port, err = strconv.Atoi(field("port"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: port is invalid", line+2)
+			}
+		}
+
+		if err = host.Normalize(); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line+2, err)
+		}
+		hosts = append(hosts, host)
+	}
+
+	return
+}
+
+// hostYAML describes a host of the inventory in YAML data
+type hostYAML struct {
+	Name        string            `yaml:"name"`
+	Aliases     []string          `yaml:"aliases"`
+	Addresses   []string          `yaml:"addresses"`
+	Port        int               `yaml:"port"`
+	User        string            `yaml:"user"`
+	Tags        map[string]string `yaml:"tags"`
+	Description string            `yaml:"description"`
+}
+
+// ParseHostsYAML parses hosts from YAML data: a list of hosts, with their name (required), aliases, addresses,
+// port, user, tags and description
+func ParseHostsYAML(r io.Reader) (hosts []*Host, err error) {
+
+	var entries []*hostYAML
+	if err = yaml.NewDecoder(r).Decode(&entries); err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "unable to read YAML")
+	}
+
+	for i, entry := range entries {
+		if entry == nil {
+			continue
+		}
+
+		host := &Host{
+			Name:        entry.Name,
+			Aliases:     strings.Join(entry.Aliases, ","),
+			Addresses:   strings.Join(entry.Addresses, ","),
+			Port:        entry.Port,
+			User:        entry.User,
+			Tags:        joinLabels(entry.Tags, ","),
+			Description: entry.Description,
+		}
+
+		if err = host.Normalize(); err != nil {
+			return nil, fmt.Errorf("host #%d: %s", i+1, err)
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+func containsHost(hosts []*Host, host *Host) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete removes the host from the inventory
+func (h *Host) Delete(db *gorm.DB) (err error) {
+	return db.Delete(h).Error
+}
+
+// Save saves the host in the inventory
+func (h *Host) Save(db *gorm.DB) (err error) {
+	return db.Save(h).Error
+}
+
+// Normalize checks the properties of the host, and puts its lists in canonical form
+func (h *Host) Normalize() (err error) {
+
+	if !hostnamePattern.MatchString(h.Name) {
+		return fmt.Errorf("%q is not a valid host name", h.Name)
+	}
+
+	aliases := make([]string, 0)
+	for _, alias := range splitList(h.Aliases) {
+		if !hostnamePattern.MatchString(alias) {
+			return fmt.Errorf("%q is not a valid alias", alias)
+		}
+		if alias != h.Name {
+			aliases = append(aliases, alias)
+		}
+	}
+	h.Aliases = strings.Join(aliases, ",")
+
+	addresses := make([]string, 0)
+	for _, address := range splitList(h.Addresses) {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return fmt.Errorf("%q is not a valid IP address", address)
+		}
+		addresses = append(addresses, ip.String())
+	}
+	h.Addresses = strings.Join(addresses, ",")
+
+	if h.Port < 0 || h.Port > 65535 {
+		return fmt.Errorf("port %d is invalid", h.Port)
+	}
+
+	h.Tags, err = NormalizeTags(h.Tags)
+
+	return
+}
+
+// AliasesList returns the aliases of the host
+func (h *Host) AliasesList() []string {
+	return splitList(h.Aliases)
+}
+
+// AddressesList returns the addresses of the host
+func (h *Host) AddressesList() []string {
+	return splitList(h.Addresses)
+}
+
+// IPs returns the addresses of the host, parsed
+func (h *Host) IPs() (ips []net.IP) {
+	for _, address := range splitList(h.Addresses) {
+		if ip := net.ParseIP(address); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return
+}
+
+// Labels returns the tags of the host
+func (h *Host) Labels() map[string]string {
+	labels, _ := parseLabels(h.Tags, tagsSeparator)
+	return labels
+}
+
+// NormalizeTags checks tags like "env=prod,team=db", and returns them sorted
+func NormalizeTags(tags string) (normalized string, err error) {
+
+	labels, err := parseLabels(tags, tagsSeparator)
+	if err != nil {
+		return
+	}
+
+	return joinLabels(labels, ","), nil
+}
+
+// NormalizeSelector checks a label selector like "env=staging AND team=db" (or "env=staging,team=db"),
+// and returns it sorted, with AND separators
+func NormalizeSelector(selector string) (normalized string, err error) {
+
+	labels, err := parseLabels(selector, selectorSeparator)
+	if err != nil {
+		return
+	}
+	if len(labels) == 0 {
+		return "", fmt.Errorf("the selector can't be empty")
+	}
+
+	return joinLabels(labels, " AND "), nil
+}
+
+// MatchesSelector returns whether labels have all the key=value pairs of a selector
+func MatchesSelector(selector string, labels map[string]string) bool {
+
+	expected, err := parseLabels(selector, selectorSeparator)
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	for key, value := range expected {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitList(s string) (items []string) {
+
+	items = make([]string, 0)
+	for _, item := range listSeparator.Split(s, -1) {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return
+}
+
+func parseLabels(s string, separator *regexp.Regexp) (labels map[string]string, err error) {
+
+	labels = make(map[string]string)
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+
+	for _, label := range separator.Split(s, -1) {
+		matches := labelPattern.FindStringSubmatch(label)
+		if matches == nil {
+			return nil, fmt.Errorf("%q is not a valid key=value label", label)
+		}
+		if _, exists := labels[matches[1]]; exists {
+			return nil, fmt.Errorf("label %s is defined twice", matches[1])
+		}
+		labels[matches[1]] = matches[2]
+	}
+
+	return
+}
+
+func joinLabels(labels map[string]string, separator string) string {
+
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, separator)
+}
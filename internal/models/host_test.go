@@ -0,0 +1,219 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	osuser "os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTagsAndSelectors(t *testing.T) {
+
+	tags, err := NormalizeTags(" team=db , env=prod")
+	require.NoError(t, err)
+	require.Equal(t, "env=prod,team=db", tags)
+
+	_, err = NormalizeTags("env=prod,env=staging")
+	require.Error(t, err, "A label can't be defined twice")
+
+	_, err = NormalizeTags("env")
+	require.Error(t, err, "A tag should be a key=value pair")
+
+	selector, err := NormalizeSelector("team=db and env=staging")
+	require.NoError(t, err)
+	require.Equal(t, "env=staging AND team=db", selector)
+
+	selector, err = NormalizeSelector("team=db,env=staging")
+	require.NoError(t, err)
+	require.Equal(t, "env=staging AND team=db", selector)
+
+	_, err = NormalizeSelector("")
+	require.Error(t, err, "A selector can't be empty")
+
+	_, err = NormalizeSelector("env=staging OR team=db")
+	require.Error(t, err, "Only AND is supported")
+
+	labels := map[string]string{"env": "staging", "team": "db", "os": "debian"}
+	require.True(t, MatchesSelector("env=staging AND team=db", labels))
+	require.True(t, MatchesSelector("env=staging", labels))
+	require.False(t, MatchesSelector("env=prod AND team=db", labels))
+	require.False(t, MatchesSelector("env=staging AND region=eu", labels))
+}
+
+func TestHasAccessWithSelector(t *testing.T) {
+
+	// Guess the working directory
+	_, filename, _, _ := runtime.Caller(0)
+	homeDir := filepath.Dir(filename)
+
+	sbHome := t.TempDir()
+	previousHome := viper.GetString("general.sb_user_home")
+	viper.Set("general.sb_user_home", sbHome)
+	defer viper.Set("general.sb_user_home", previousHome)
+
+	hostsDB, err := GetHostsGormDB(filepath.Join(sbHome, "hosts.db"))
+	require.NoError(t, err)
+	require.NoError(t, (&Host{Name: "db1.staging.invalid", Tags: "env=staging,team=db"}).Save(hostsDB))
+	require.NoError(t, (&Host{Name: "db1.prod.invalid", Tags: "env=prod,team=db"}).Save(hostsDB))
+
+	hosts, err := GetAllHosts(hostsDB)
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+
+	user := &User{
+		User: &osuser.User{
+			Uid:      "1000",
+			Gid:      "1000",
+			Username: "testuser",
+			Name:     "Test User",
+			HomeDir:  fmt.Sprintf("%s/test_assets/user", homeDir),
+		},
+		Groups: map[string]*Group{
+			"dba": {
+				Name:       "dba",
+				SystemName: "bg_dba",
+				Member:     true,
+			},
+		},
+	}
+	user.Groups["dba"].OverrideDatabaseAccessFilePath(filepath.Join(sbHome, "dba.db"))
+	user.Groups["dba"].OverrideKeyFilesRootDir(fmt.Sprintf("%s/.ssh", user.User.HomeDir))
+
+	ba, err := user.Groups["dba"].AddSelectorAccess("team=db AND env=staging", "deploy", "22", "Added for tests", time.Time{}, false)
+	require.NoError(t, err)
+	require.Equal(t, "env=staging AND team=db", ba.Selector)
+
+	db, err := GetAccessGormDB(":memory:")
+	require.NoError(t, err)
+
+	staging, _ := BuildSBAccess("db1.staging.invalid", "deploy", "22", "", false)
+	accessInfo, err := user.HasAccess(staging, db)
+	require.NoError(t, err)
+	require.True(t, accessInfo.Authorized, "Access should be granted by the selector")
+	require.Equal(t, []*Source{{Type: "group", Group: "dba"}}, accessInfo.Sources)
+
+	prod, _ := BuildSBAccess("db1.prod.invalid", "deploy", "22", "", false)
+	accessInfo, err = user.HasAccess(prod, db)
+	require.NoError(t, err)
+	require.False(t, accessInfo.Authorized, "Access should not be granted to hosts not matching the selector")
+
+	otherUser, _ := BuildSBAccess("db1.staging.invalid", "root", "22", "", false)
+	accessInfo, err = user.HasAccess(otherUser, db)
+	require.NoError(t, err)
+	require.False(t, accessInfo.Authorized, "Access should not be granted to another user")
+
+	unknown, _ := BuildSBAccess("unknown.invalid", "deploy", "22", "", false)
+	accessInfo, err = user.HasAccess(unknown, db)
+	require.NoError(t, err)
+	require.False(t, accessInfo.Authorized, "Access should not be granted to hosts outside of the inventory")
+
+	_, err = user.Groups["dba"].DeleteSelectorAccess("env=staging,team=db", "deploy", "22")
+	require.NoError(t, err)
+
+	accessInfo, err = user.HasAccess(staging, db)
+	require.NoError(t, err)
+	require.False(t, accessInfo.Authorized, "Access should not be granted anymore")
+}
+
+func TestParseHosts(t *testing.T) {
+
+	hosts, err := ParseHostsCSV(strings.NewReader(`name,aliases,addresses,port,user,tags,description
+db1.example.com,"db1, db1.staging",10.0.0.1,2222,deploy,"team=db,env=staging",Staging database
+web1.example.com,,,,,,
+`))
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	require.Equal(t, &Host{
+		Name:        "db1.example.com",
+		Aliases:     "db1,db1.staging",
+		Addresses:   "10.0.0.1",
+		Port:        2222,
+		User:        "deploy",
+		Tags:        "env=staging,team=db",
+		Description: "Staging database",
+	}, hosts[0])
+	require.Equal(t, &Host{Name: "web1.example.com"}, hosts[1])
+
+	_, err = ParseHostsCSV(strings.NewReader("name,address\ndb1.example.com,10.0.0.1\n"))
+	require.Error(t, err, "Unknown columns should be rejected")
+
+	_, err = ParseHostsCSV(strings.NewReader("name,addresses\ndb1.example.com,db1\n"))
+	require.Error(t, err, "Addresses should be IPs")
+
+	yamlHosts, err := ParseHostsYAML(strings.NewReader(`
+- name: db1.example.com
+  aliases: [db1, db1.staging]
+  addresses: [10.0.0.1]
+  port: 2222
+  user: deploy
+  tags:
+    team: db
+    env: staging
+  description: Staging database
+- name: web1.example.com
+`))
+	require.NoError(t, err)
+	require.Equal(t, hosts, yamlHosts)
+
+	_, err = ParseHostsYAML(strings.NewReader("- name: 'db1 example'\n"))
+	require.Error(t, err, "Names should be valid host names")
+}
+
+func TestInventory(t *testing.T) {
+
+	sbHome := t.TempDir()
+	previousHome := viper.GetString("general.sb_user_home")
+	viper.Set("general.sb_user_home", sbHome)
+	defer viper.Set("general.sb_user_home", previousHome)
+
+	// Without inventory, nothing changes
+	ba, err := BuildSBAccessFromUserInput("db1")
+	require.NoError(t, err)
+	require.Equal(t, "db1", ba.Host)
+	_, err = os.Stat(filepath.Join(sbHome, "hosts.db"))
+	require.True(t, os.IsNotExist(err), "The inventory shouldn't be created when looking into it")
+
+	db, err := GetHostsGormDB(filepath.Join(sbHome, "hosts.db"))
+	require.NoError(t, err)
+
+	db1 := &Host{Name: "db1.example.invalid", Aliases: "db1", Addresses: "10.0.0.1", Port: 2222, User: "deploy"}
+	require.NoError(t, CheckInventoryConflicts(db, []*Host{db1}))
+	require.NoError(t, SaveHosts(db, []*Host{db1}))
+
+	// The aliases can't be shared, but a host can be replaced
+	require.Error(t, CheckInventoryConflicts(db, []*Host{{Name: "db2.example.invalid", Aliases: "db1"}}))
+	require.Error(t, CheckInventoryConflicts(db, []*Host{{Name: "db1"}}))
+	require.Error(t, CheckInventoryConflicts(db, []*Host{{Name: "db2.example.invalid"}, {Name: "db2.example.invalid"}}))
+	require.NoError(t, CheckInventoryConflicts(db, []*Host{{Name: "db1.example.invalid", Aliases: "db1,db1.eu"}}))
+
+	host, err := FindHost(db, "db1")
+	require.NoError(t, err)
+	require.Equal(t, "db1.example.invalid", host.Name)
+
+	// The users connect to the canonical name, with the default user and port, and the addresses of the inventory
+	ba, err = BuildSBAccessFromUserInput("db1")
+	require.NoError(t, err)
+	require.Equal(t, "db1.example.invalid", ba.Host)
+	require.Equal(t, "deploy", ba.User)
+	require.Equal(t, 2222, ba.Port)
+	require.Equal(t, "10.0.0.1/32", ba.Prefix)
+
+	ba, err = BuildSBAccessFromUserInput("root@db1:22")
+	require.NoError(t, err)
+	require.Equal(t, "db1.example.invalid", ba.Host)
+	require.Equal(t, "root", ba.User)
+	require.Equal(t, 22, ba.Port)
+
+	// Accesses can be granted to the inventory entries, even if their names don't resolve
+	ba, err = BuildSBAccess("db1", "root", "22", "", true)
+	require.NoError(t, err)
+	require.Equal(t, "db1.example.invalid", ba.Host)
+	require.Equal(t, "10.0.0.1/32", ba.Prefix)
+}
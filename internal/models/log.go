@@ -11,6 +11,8 @@ import (
 
 	"github.com/inpher/sb/internal/config"
 	"github.com/inpher/sb/internal/helpers"
+	"github.com/inpher/sb/internal/notification"
+	"github.com/inpher/sb/internal/types"
 	"github.com/pkg/errors"
 
 	"github.com/glebarez/sqlite" // Blank import
@@ -40,6 +42,11 @@ type Log struct {
 	PortTo string `gorm:"type:varchar(5)"`   // The port the user wanted to connect to
 	UserTo string `gorm:"type:varchar(100)"` // The user to connect to the distant host
 
+	TerminalWidth  int `gorm:"type:integer"` // The width of the user's terminal when the session started
+	TerminalHeight int `gorm:"type:integer"` // The height of the user's terminal when the session started
+
+	ProcessID int `gorm:"type:integer"` // The PID of the sb process handling the session on BastionHost
+
 	Allowed bool `gorm:"type:varchar(1)"` // Did we allow the connection?
 
 	// Ignored helpers: not saved to database
@@ -69,9 +76,14 @@ func NewLog(username string, databases []string, arguments []string) (log *Log)
 		log.BastionPort = sshConnectionEnv[3]
 	}
 
+	// The running sessions are watched and killed on the instance handling them
+	log.BastionHost, _ = helpers.GetHostname()
+	log.ProcessID = os.Getpid()
+
 	log.Databases = databases
 
 	log.insert(true)
+	log.emitAuditEvent("create")
 
 	if config.GetReplicationEnabled() {
 		err := log.PushReplication(true)
@@ -83,8 +95,61 @@ func NewLog(username string, databases []string, arguments []string) (log *Log)
 	return
 }
 
+// emitAuditEvent queues the log entry for the daemon to emit it to the configured audit sinks, so that an unavailable
+// sink doesn't slow the actions down. The replicated entries are not emitted: the instance handling the action does it.
+func (l *Log) emitAuditEvent(action string) {
+
+	err := queueAuditEvent(l.auditEvent(action))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: unable to queue audit event: %s\n", err)
+	}
+}
+
+// auditEvent returns the audit event of the log entry
+func (l *Log) auditEvent(action string) (event *types.AuditEvent) {
+
+	event = &types.AuditEvent{
+		Action:    action,
+		Date:      time.Now().UTC(),
+		Instance:  l.BastionHost,
+		ID:        l.UniqID,
+		Account:   l.LocalUsername,
+		Command:   l.Command,
+		Arguments: l.Arguments,
+		Allowed:   l.Allowed,
+		Comment:   l.Comment,
+		FromHost:  l.HostFrom,
+		FromPort:  l.PortFrom,
+		ToUser:    l.UserTo,
+		ToHost:    l.HostTo,
+		ToPort:    l.PortTo,
+		StartDate: l.SessionStartDate.UTC(),
+	}
+	if !l.SessionEndDate.IsZero() {
+		endDate := l.SessionEndDate.UTC()
+		event.EndDate = &endDate
+	}
+
+	return
+}
+
+// SSHSessionsFilter describes the criteria used to select SSH sessions
+type SSHSessionsFilter struct {
+	SessionID string    // Only select the session with this ID
+	Account   string    // Only select the sessions initiated by this local account
+	Host      string    // Only select the sessions to this distant host
+	Since     time.Time // Only select the sessions started after this date
+	Until     time.Time // Only select the sessions started before this date
+	Limit     int       // Maximum number of sessions to return (0 means no limit)
+}
+
 // GetLastSSHSessions returns the last SSH sessions
 func GetLastSSHSessions(database string, limit int) (sessions []*helpers.SSHSession, err error) {
+	return GetSSHSessions(database, &SSHSessionsFilter{Limit: limit})
+}
+
+// GetSSHSessions returns the SSH sessions matching the filter, most recent first
+func GetSSHSessions(database string, filter *SSHSessionsFilter) (sessions []*helpers.SSHSession, err error) {
 
 	var logs []*Log
 
@@ -104,8 +169,29 @@ func GetLastSSHSessions(database string, limit int) (sessions []*helpers.SSHSess
 	// Migrate the schema (this will create table or alter table if needed)
 	db.AutoMigrate(&Log{})
 
+	// Build the query from the filter
+	query := db.Where("command = ?", "ttyrec")
+	if filter.SessionID != "" {
+		query = query.Where("uniq_id = ?", filter.SessionID)
+	}
+	if filter.Account != "" {
+		query = query.Where("local_username = ?", filter.Account)
+	}
+	if filter.Host != "" {
+		query = query.Where("host_to = ?", filter.Host)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("session_start_date >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("session_start_date <= ?", filter.Until.UTC())
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
 	// Select
-	err = db.Where("command = ?", "ttyrec").Order("session_start_date desc").Limit(limit).Find(&logs).Error
+	err = query.Order("session_start_date desc").Find(&logs).Error
 	if err != nil {
 		return
 	}
@@ -124,6 +210,12 @@ func GetLastSSHSessions(database string, limit int) (sessions []*helpers.SSHSess
 			PortTo:    log.PortTo,
 			UserTo:    log.UserTo,
 			Allowed:   log.Allowed,
+
+			TerminalWidth:  log.TerminalWidth,
+			TerminalHeight: log.TerminalHeight,
+
+			BastionHost: log.BastionHost,
+			ProcessID:   log.ProcessID,
 		})
 	}
 
@@ -142,6 +234,7 @@ func (l *Log) Save() (err error) {
 	if err != nil {
 		return
 	}
+	l.emitAuditEvent("update")
 
 	if config.GetReplicationEnabled() {
 		return l.PushReplication(false)
@@ -152,9 +245,41 @@ func (l *Log) Save() (err error) {
 // SetAllowed sets whether or not the command was allowed by sb in the log and saves it
 func (l *Log) SetAllowed(allowed bool) error {
 	l.Allowed = allowed
+
+	if !allowed {
+		event := l.NotificationEvent(notification.EventDenied)
+		if event.Target != "" {
+			event.Text = fmt.Sprintf("%s was denied the access to %s on %s", l.LocalUsername, event.Target, l.BastionHost)
+		} else {
+			event.Text = fmt.Sprintf("%s was denied the command %s on %s", l.LocalUsername, l.Command, l.BastionHost)
+		}
+		if err := QueueNotification(event, l.HostTo); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: unable to queue notification: %s\n", err)
+		}
+	}
+
 	return l.Save()
 }
 
+// NotificationEvent returns a notification event about the log entry, without its description
+func (l *Log) NotificationEvent(name string) (event *types.NotificationEvent) {
+
+	event = &types.NotificationEvent{
+		Event:    name,
+		Date:     time.Now().UTC(),
+		Instance: l.BastionHost,
+		ID:       l.UniqID,
+		Account:  l.LocalUsername,
+		FromHost: l.HostFrom,
+		Command:  l.Command,
+	}
+	if l.HostTo != "" {
+		event.Target = fmt.Sprintf("%s@%s:%s", l.UserTo, l.HostTo, l.PortTo)
+	}
+
+	return
+}
+
 func (l *Log) PushReplication(new bool) (err error) {
 
 	action := "log"
@@ -196,6 +321,13 @@ func (l *Log) SetTargetAccess(ba *Access) error {
 	return l.Save()
 }
 
+// SetTerminalSize sets the size of the user's terminal in the log and saves it
+func (l *Log) SetTerminalSize(width, height int) error {
+	l.TerminalWidth = width
+	l.TerminalHeight = height
+	return l.Save()
+}
+
 // insert saves the object in database (insert or update depending on the passed boolean)
 func (l *Log) insert(insert bool) (err error) {
 
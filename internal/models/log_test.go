@@ -43,7 +43,7 @@ func TestLogLastSSHSessions(t *testing.T) {
 
 	// Build a valid path for tests
 	_, filename, _, _ := runtime.Caller(0)
-	logsDatabase := fmt.Sprintf("%s/test_assets/log/sshsessions_test.db", filepath.Dir(filename))
+	logsDatabase := copyTestAsset(t, fmt.Sprintf("%s/test_assets/log/sshsessions_test.db", filepath.Dir(filename)))
 
 	expectedSessions := []*helpers.SSHSession{
 		{
@@ -94,3 +94,44 @@ func TestLogLastSSHSessions(t *testing.T) {
 	}
 
 }
+
+func TestLogSSHSessionsFilter(t *testing.T) {
+
+	// Build a valid path for tests
+	_, filename, _, _ := runtime.Caller(0)
+	logsDatabase := copyTestAsset(t, fmt.Sprintf("%s/test_assets/log/sshsessions_test.db", filepath.Dir(filename)))
+
+	sessions, err := GetSSHSessions(logsDatabase, &SSHSessionsFilter{Account: "test", Host: "meow.com"})
+	require.NoError(t, err, "An unexpected error occurred while calling GetSSHSessions")
+	require.Equal(t, 3, len(sessions), "GetSSHSessions should return all the sessions to meow.com")
+
+	sessions, err = GetSSHSessions(logsDatabase, &SSHSessionsFilter{Account: "unknown"})
+	require.NoError(t, err, "An unexpected error occurred while calling GetSSHSessions")
+	require.Equal(t, 0, len(sessions), "GetSSHSessions shouldn't return sessions of other accounts")
+
+	sessions, err = GetSSHSessions(logsDatabase, &SSHSessionsFilter{
+		Since: time.Date(2020, 05, 07, 12, 51, 0, 0, time.UTC),
+		Until: time.Date(2020, 05, 07, 12, 52, 10, 0, time.UTC),
+	})
+	require.NoError(t, err, "An unexpected error occurred while calling GetSSHSessions")
+	require.Equal(t, 2, len(sessions), "GetSSHSessions should only return the sessions started in the time range")
+	require.Equal(t, "c628b55c-0baf-45a8-84ce-a3ba5ddf11ee", sessions[0].UniqID, "GetSSHSessions should return the most recent session first")
+
+	sessions, err = GetSSHSessions(logsDatabase, &SSHSessionsFilter{SessionID: "95c6e39c-e510-4182-a623-eec2837a97a2"})
+	require.NoError(t, err, "An unexpected error occurred while calling GetSSHSessions")
+	require.Equal(t, 1, len(sessions), "GetSSHSessions should return the requested session")
+	require.Equal(t, "test", sessions[0].UserFrom, "GetSSHSessions should return the account that initiated the session")
+}
+
+// copyTestAsset copies a test asset in a temporary directory, so that the tests don't modify it (when migrating a
+// database for instance), and returns the path of the copy
+func copyTestAsset(t *testing.T, path string) string {
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	copyPath := filepath.Join(t.TempDir(), filepath.Base(path))
+	require.NoError(t, os.WriteFile(copyPath, content, 0600))
+
+	return copyPath
+}
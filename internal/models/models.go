@@ -26,6 +26,24 @@ func GetAccessGormDB(database string) (db *gorm.DB, err error) {
 	return
 }
 
+// GetRequestsGormDB returns a DB handler
+func GetRequestsGormDB(database string) (db *gorm.DB, err error) {
+
+	// We open the DB
+	db, err = gorm.Open(sqlite.Open(database), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to connect to requests database %s", database)
+		return
+	}
+
+	// Migrate the schema (this will create table or alter table if needed)
+	db.AutoMigrate(&Request{})
+
+	return
+}
+
 // GetReplicationGormDB returns a DB handler
 func GetReplicationGormDB(database string) (db *gorm.DB, err error) {
 
@@ -39,7 +57,7 @@ func GetReplicationGormDB(database string) (db *gorm.DB, err error) {
 	}
 
 	// Migrate the schema (this will create table or alter table if needed)
-	db.AutoMigrate(&Replication{})
+	db.AutoMigrate(&Replication{}, &ObjectVersion{}, &ReplicationConflict{}, &StateVerification{}, &AppliedReplicationEntry{}, &ReplicationDeadLetter{}, &ReplicationInstanceStatus{}, &ReplicationJoin{}, &SnapshotChunk{}, &BufferedReplicationEntry{})
 
 	return
 }
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/notification"
+	"github.com/inpher/sb/internal/types"
+	"github.com/pkg/errors"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Notification is a notification waiting to be delivered to a webhook by the daemon
+type Notification struct {
+	ID          uint      `gorm:"primaryKey"`
+	Webhook     string    `gorm:"type:varchar(100)"` // The name of the webhook to deliver to
+	Event       string    `gorm:"type:text"`         // The JSON notification event
+	CreatedAt   time.Time `gorm:"type:datetime"`
+	Attempts    int       `gorm:"type:integer"`        // The number of failed attempts
+	NextAttempt time.Time `gorm:"type:datetime;index"` // When to try to deliver it next
+	LastError   string    `gorm:"type:text"`           // Why the last attempt failed
+}
+
+// GetNotificationsGormDB returns a DB handler
+func GetNotificationsGormDB(database string) (db *gorm.DB, err error) {
+
+	// We open the DB
+	db, err = gorm.Open(sqlite.Open(database), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to connect to notifications database %s", database)
+		return
+	}
+
+	// Migrate the schema (this will create table or alter table if needed)
+	db.AutoMigrate(&Notification{})
+
+	return
+}
+
+// QueueNotification queues an event for the webhooks that subscribed to it, if the notifications are enabled
+func QueueNotification(event *types.NotificationEvent, host string) (err error) {
+
+	notificationsConfig := config.GetNotificationsConfig()
+	if !notificationsConfig.Enabled {
+		return
+	}
+
+	var hostLabels map[string]string
+	notifications := make([]*Notification, 0)
+	for name, webhook := range notificationsConfig.Webhooks {
+		if !notification.IsSubscribed(webhook, event.Event) {
+			continue
+		}
+
+		// The events about a distant host are only sent to the webhooks whose hosts selector matches it
+		if webhook.Hosts != "" && host != "" {
+			if hostLabels == nil {
+				hostLabels, err = GetInventoryLabels(host)
+				if err != nil {
+					return
+				}
+			}
+			if !MatchesSelector(webhook.Hosts, hostLabels) {
+				continue
+			}
+		}
+
+		notifications = append(notifications, &Notification{Webhook: name})
+	}
+	if len(notifications) == 0 {
+		return
+	}
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal notification")
+	}
+
+	db, err := GetNotificationsGormDB(config.GetNotificationsDatabasePath())
+	if err != nil {
+		return
+	}
+
+	for _, n := range notifications {
+		n.Event = string(eventJSON)
+		n.NextAttempt = time.Now()
+	}
+
+	err = db.Create(notifications).Error
+	if err != nil {
+		err = errors.Wrapf(err, "unable to queue %s notification", event.Event)
+	}
+
+	return
+}
+
+// GetDueNotifications returns the notifications to deliver now, the oldest first
+func GetDueNotifications(db *gorm.DB, limit int) (notifications []*Notification, err error) {
+
+	err = db.Where("next_attempt <= ?", time.Now()).Order("id").Limit(limit).Find(&notifications).Error
+	if err != nil {
+		err = errors.Wrap(err, "unable to get the notifications to deliver")
+	}
+
+	return
+}
+
+// Delete removes the notification from the queue
+func (n *Notification) Delete(db *gorm.DB) (err error) {
+	return db.Delete(n).Error
+}
+
+// Postpone records a failed attempt, and schedules the next one
+func (n *Notification) Postpone(db *gorm.DB, attemptError error) (err error) {
+	n.Attempts++
+	n.LastError = attemptError.Error()
+	n.NextAttempt = time.Now().Add(notification.RetryDelay(n.Attempts))
+	return db.Save(n).Error
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/inpher/sb/internal/notification"
+	"github.com/inpher/sb/internal/types"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationsQueue(t *testing.T) {
+
+	db, err := GetNotificationsGormDB(filepath.Join(t.TempDir(), "notifications.db"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.Create(&Notification{Webhook: "slack", Event: "{}", NextAttempt: time.Now()}).Error)
+
+	notifications, err := GetDueNotifications(db, 10)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+
+	// A failed notification is retried later
+	require.NoError(t, notifications[0].Postpone(db, errors.New("webhook slack answered 500 Internal Server Error")))
+	due, err := GetDueNotifications(db, 10)
+	require.NoError(t, err)
+	require.Empty(t, due)
+
+	var postponed Notification
+	require.NoError(t, db.First(&postponed).Error)
+	require.Equal(t, 1, postponed.Attempts)
+	require.True(t, postponed.NextAttempt.After(time.Now()))
+
+	require.NoError(t, postponed.Delete(db))
+	var count int64
+	require.NoError(t, db.Model(&Notification{}).Count(&count).Error)
+	require.Zero(t, count)
+}
+
+func TestQueueNotificationHosts(t *testing.T) {
+
+	sbHome := t.TempDir()
+	previousHome := viper.GetString("general.sb_user_home")
+	viper.Set("general.sb_user_home", sbHome)
+	defer viper.Set("general.sb_user_home", previousHome)
+	viper.Set("notifications.enabled", true)
+	defer viper.Set("notifications.enabled", false)
+	viper.Set("notifications.webhooks", []map[string]interface{}{
+		{"name": "all", "url": "https://all.example.invalid", "events": []string{"*"}},
+		{"name": "prod", "url": "https://prod.example.invalid", "events": []string{"*"}, "hosts": "env=prod"},
+	})
+	defer viper.Set("notifications.webhooks", []interface{}{})
+
+	hostsDB, err := GetHostsGormDB(filepath.Join(sbHome, "hosts.db"))
+	require.NoError(t, err)
+	require.NoError(t, SaveHosts(hostsDB, []*Host{
+		{Name: "db1.example.invalid", Tags: "env=prod"},
+		{Name: "db2.example.invalid", Tags: "env=staging"},
+	}))
+
+	db, err := GetNotificationsGormDB(filepath.Join(sbHome, "notifications.db"))
+	require.NoError(t, err)
+	webhooks := func() (names []string) {
+		var notifications []*Notification
+		require.NoError(t, db.Order("webhook").Find(&notifications).Error)
+		require.NoError(t, db.Where("1 = 1").Delete(&Notification{}).Error)
+		for _, n := range notifications {
+			names = append(names, n.Webhook)
+		}
+		return
+	}
+
+	// The hosts selector only keeps the sessions to the matching hosts
+	require.NoError(t, QueueNotification(&types.NotificationEvent{Event: notification.EventSessionStart}, "db1.example.invalid"))
+	require.Equal(t, []string{"all", "prod"}, webhooks())
+	require.NoError(t, QueueNotification(&types.NotificationEvent{Event: notification.EventSessionStart}, "db2.example.invalid"))
+	require.Equal(t, []string{"all"}, webhooks())
+	require.NoError(t, QueueNotification(&types.NotificationEvent{Event: notification.EventSessionStart}, "unknown.example.invalid"))
+	require.Equal(t, []string{"all"}, webhooks())
+
+	// The events that are not about a distant host are not filtered
+	require.NoError(t, QueueNotification(&types.NotificationEvent{Event: "group owner add"}, ""))
+	require.Equal(t, []string{"all", "prod"}, webhooks())
+}
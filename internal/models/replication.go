@@ -23,12 +23,16 @@ type Replication struct {
 	Instance     string
 	Action       string
 	Data         string
+	ObjectKey    string // The object the action applies to, for the versioned commands
+	Clock        string // The vector clock of the object after the action, for the versioned commands
 }
 
 type ReplicationData map[string]string
 
 func (r *Replication) BeforeCreate(tx *gorm.DB) (err error) {
-	r.UniqID = uuid.New().String()
+	if r.UniqID == "" {
+		r.UniqID = uuid.New().String()
+	}
 	return
 }
 
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// VectorClock counts, for each instance, the actions it made on an object
+type VectorClock map[string]uint64
+
+// The orderings of two vector clocks
+const (
+	ClockEqual      = iota // Both clocks are the same
+	ClockBefore            // The clock happened before the other one
+	ClockAfter             // The clock happened after the other one
+	ClockConcurrent        // The clocks come from concurrent actions
+)
+
+// ParseVectorClock parses a vector clock, as stored in the replication entries
+func ParseVectorClock(s string) (vc VectorClock, err error) {
+
+	vc = make(VectorClock)
+	if s == "" {
+		return
+	}
+
+	err = json.Unmarshal([]byte(s), &vc)
+	if err != nil {
+		err = errors.Wrapf(err, "invalid vector clock %s", s)
+	}
+
+	return
+}
+
+// String returns the vector clock, as stored in the replication entries
+func (vc VectorClock) String() string {
+	// json.Marshal sorts the keys, so that equal clocks have the same representation
+	s, _ := json.Marshal(vc)
+	return string(s)
+}
+
+// Compare returns how the clock is ordered with another one
+func (vc VectorClock) Compare(other VectorClock) int {
+
+	before, after := false, false
+	for instance := range vc.Merge(other) {
+		switch {
+		case vc[instance] < other[instance]:
+			before = true
+		case vc[instance] > other[instance]:
+			after = true
+		}
+	}
+
+	switch {
+	case before && after:
+		return ClockConcurrent
+	case before:
+		return ClockBefore
+	case after:
+		return ClockAfter
+	}
+	return ClockEqual
+}
+
+// Merge returns the clock of the instance that has seen the actions of both clocks
+func (vc VectorClock) Merge(other VectorClock) VectorClock {
+
+	merged := make(VectorClock)
+	for _, clock := range []VectorClock{vc, other} {
+		for instance, counter := range clock {
+			if counter > merged[instance] {
+				merged[instance] = counter
+			}
+		}
+	}
+
+	return merged
+}
+
+// Weight returns the number of actions counted by the clock
+func (vc VectorClock) Weight() (weight uint64) {
+	for _, counter := range vc {
+		weight += counter
+	}
+	return
+}
+
+// ObjectVersion is the version of an object on this instance: the clock of all the actions applied to it,
+// and the action that determined its state
+type ObjectVersion struct {
+	ObjectKey string    `gorm:"PRIMARY_KEY"`
+	Clock     string    `gorm:"type:text"`         // The merged clock of the actions applied to the object
+	Action    string    `gorm:"type:varchar(100)"` // The action that determined the state of the object
+	Instance  string    `gorm:"type:varchar(255)"` // The instance that made this action
+	EntryID   string    `gorm:"type:varchar(36)"`  // The replication entry of this action
+	Weight    uint64    `gorm:"type:integer"`      // The weight of the clock of this action, to order the concurrent actions
+	UpdatedAt time.Time `gorm:"type:datetime"`
+}
+
+// ReplicationConflict records that concurrent actions were made on the same object by different instances,
+// and which one was kept
+type ReplicationConflict struct {
+	ID             uint      `gorm:"primaryKey"`
+	ObjectKey      string    `gorm:"type:text;index"`
+	DetectedAt     time.Time `gorm:"type:datetime"`
+	WinnerAction   string    `gorm:"type:varchar(100)"`
+	WinnerInstance string    `gorm:"type:varchar(255)"`
+	WinnerEntryID  string    `gorm:"type:varchar(36)"`
+	WinnerClock    string    `gorm:"type:text"`
+	LoserAction    string    `gorm:"type:varchar(100)"`
+	LoserInstance  string    `gorm:"type:varchar(255)"`
+	LoserEntryID   string    `gorm:"type:varchar(36)"`
+	LoserClock     string    `gorm:"type:text"`
+}
+
+// getObjectVersion returns the version of an object, or nil if no versioned action was applied to it yet
+func getObjectVersion(db *gorm.DB, objectKey string) (version *ObjectVersion, err error) {
+
+	var versions []*ObjectVersion
+	err = db.Where("object_key = ?", objectKey).Limit(1).Find(&versions).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get the version of %s", objectKey)
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	return versions[0], nil
+}
+
+// StampReplicationEntry versions an action made on this instance, and saves its replication entry: the clock of
+// the object is incremented for this instance, and carried by the entry.
+// Both happen in the same transaction, so that concurrent actions on the object never get the same clock.
+func StampReplicationEntry(db *gorm.DB, entry *Replication, objectKey string) (err error) {
+
+	if entry.UniqID == "" {
+		entry.UniqID = uuid.New().String()
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+
+		// Writing the entry first takes the write lock of the database before the version is read
+		entry.ObjectKey = objectKey
+		if err := tx.Save(entry).Error; err != nil {
+			return errors.Wrap(err, "unable to save the replication entry")
+		}
+
+		version, err := getObjectVersion(tx, objectKey)
+		if err != nil {
+			return err
+		}
+
+		clock := make(VectorClock)
+		if version != nil {
+			if clock, err = ParseVectorClock(version.Clock); err != nil {
+				return err
+			}
+		}
+		clock[entry.Instance]++
+
+		entry.Clock = clock.String()
+		if err := tx.Save(entry).Error; err != nil {
+			return errors.Wrap(err, "unable to save the replication entry")
+		}
+
+		return tx.Save(&ObjectVersion{
+			ObjectKey: objectKey,
+			Clock:     entry.Clock,
+			Action:    entry.Action,
+			Instance:  entry.Instance,
+			EntryID:   entry.UniqID,
+			Weight:    clock.Weight(),
+			UpdatedAt: time.Now(),
+		}).Error
+	})
+}
+
+// ResolveReplicationEntry decides whether a versioned action received from another instance has to be applied:
+//   - if it follows all the actions applied to the object, it is applied
+//   - if it precedes them (or was already applied), it is ignored
+//   - if it is concurrent to them, the action with the heaviest clock wins (then the one of the greatest instance
+//     name, then the greatest entry ID), so that all the instances keep the same action: the conflict is returned
+func ResolveReplicationEntry(db *gorm.DB, entry *Replication) (apply bool, conflict *ReplicationConflict, err error) {
+
+	// Entries from older instances, and of the commands that aren't versioned, are always applied
+	if entry.ObjectKey == "" {
+		return true, nil, nil
+	}
+
+	received, err := ParseVectorClock(entry.Clock)
+	if err != nil {
+		return
+	}
+
+	version, err := getObjectVersion(db, entry.ObjectKey)
+	if err != nil || version == nil {
+		return err == nil, nil, err
+	}
+
+	local, err := ParseVectorClock(version.Clock)
+	if err != nil {
+		return
+	}
+
+	switch received.Compare(local) {
+	case ClockAfter:
+		return true, nil, nil
+	case ClockBefore, ClockEqual:
+		return false, nil, nil
+	}
+
+	conflict = &ReplicationConflict{
+		ObjectKey:      entry.ObjectKey,
+		DetectedAt:     time.Now(),
+		WinnerAction:   version.Action,
+		WinnerInstance: version.Instance,
+		WinnerEntryID:  version.EntryID,
+		WinnerClock:    version.Clock,
+		LoserAction:    entry.Action,
+		LoserInstance:  entry.Instance,
+		LoserEntryID:   entry.UniqID,
+		LoserClock:     entry.Clock,
+	}
+
+	if compareRanks(received.Weight(), entry.Instance, entry.UniqID, version.Weight, version.Instance, version.EntryID) > 0 {
+		apply = true
+		conflict.WinnerAction, conflict.LoserAction = conflict.LoserAction, conflict.WinnerAction
+		conflict.WinnerInstance, conflict.LoserInstance = conflict.LoserInstance, conflict.WinnerInstance
+		conflict.WinnerEntryID, conflict.LoserEntryID = conflict.LoserEntryID, conflict.WinnerEntryID
+		conflict.WinnerClock, conflict.LoserClock = conflict.LoserClock, conflict.WinnerClock
+	}
+
+	return
+}
+
+// CommitReplicationEntry records that a versioned action received from another instance was handled, as decided
+// by ResolveReplicationEntry: the clock of the object now includes it, and the conflict is kept for review
+func CommitReplicationEntry(db *gorm.DB, entry *Replication, applied bool, conflict *ReplicationConflict) (err error) {
+
+	if entry.ObjectKey == "" {
+		return
+	}
+
+	received, err := ParseVectorClock(entry.Clock)
+	if err != nil {
+		return
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+
+		version, err := getObjectVersion(tx, entry.ObjectKey)
+		if err != nil {
+			return err
+		}
+
+		local := make(VectorClock)
+		if version != nil {
+			if local, err = ParseVectorClock(version.Clock); err != nil {
+				return err
+			}
+		}
+
+		if applied || version == nil {
+			version = &ObjectVersion{
+				ObjectKey: entry.ObjectKey,
+				Action:    entry.Action,
+				Instance:  entry.Instance,
+				EntryID:   entry.UniqID,
+				Weight:    received.Weight(),
+			}
+		}
+		version.Clock = local.Merge(received).String()
+		version.UpdatedAt = time.Now()
+
+		if err := tx.Save(version).Error; err != nil {
+			return errors.Wrapf(err, "unable to save the version of %s", entry.ObjectKey)
+		}
+
+		if conflict != nil {
+			if err := tx.Create(conflict).Error; err != nil {
+				return errors.Wrapf(err, "unable to record the conflict on %s", entry.ObjectKey)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetReplicationConflicts returns the recorded conflicts, the latest first
+func GetReplicationConflicts(db *gorm.DB, since time.Time, limit int) (conflicts []*ReplicationConflict, err error) {
+
+	err = db.Where("detected_at >= ?", since).Order("detected_at DESC").Limit(limit).Find(&conflicts).Error
+	if err != nil {
+		err = errors.Wrap(err, "unable to get the replication conflicts")
+	}
+
+	return
+}
+
+// ObjectKey builds the key of an object from its type and the fields identifying it
+func ObjectKey(objectType string, fields ...string) string {
+	return fmt.Sprintf("%s:%s", objectType, strings.Join(fields, ":"))
+}
+
+// AccessObjectKey builds the key of an access from its normalized form, so that the different ways to write the
+// same access (10.0.0.1 or 10.0.0.1/32, a host name in any case) are the same object. The host names are not
+// resolved: the key must be the same on every instance, whatever their DNS answers.
+func AccessObjectKey(objectType, owner, host, user, port string) string {
+
+	target := strings.ToLower(host)
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			target = fmt.Sprintf("%s/32", ip)
+		} else {
+			target = fmt.Sprintf("%s/128", ip)
+		}
+	} else if _, ipNet, err := net.ParseCIDR(host); err == nil {
+		target = ipNet.String()
+	}
+
+	if intPort, err := strconv.Atoi(port); err == nil {
+		port = strconv.Itoa(intPort)
+	}
+
+	return ObjectKey(objectType, owner, user, target, port)
+}
+
+// compareRanks orders concurrent actions by the weight of their clock, then their instance, then their entry ID
+func compareRanks(weight uint64, instance, entryID string, otherWeight uint64, otherInstance, otherEntryID string) int {
+
+	switch {
+	case weight != otherWeight:
+		if weight > otherWeight {
+			return 1
+		}
+		return -1
+	case instance != otherInstance:
+		return strings.Compare(instance, otherInstance)
+	}
+
+	return strings.Compare(entryID, otherEntryID)
+}
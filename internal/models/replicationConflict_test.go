@@ -0,0 +1,120 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestVectorClock(t *testing.T) {
+
+	a := VectorClock{"sb1": 2, "sb2": 1}
+	require.Equal(t, ClockEqual, a.Compare(VectorClock{"sb1": 2, "sb2": 1}))
+	require.Equal(t, ClockAfter, a.Compare(VectorClock{"sb1": 1, "sb2": 1}))
+	require.Equal(t, ClockBefore, a.Compare(VectorClock{"sb1": 2, "sb2": 1, "sb3": 1}))
+	require.Equal(t, ClockConcurrent, a.Compare(VectorClock{"sb1": 1, "sb2": 2}))
+
+	require.Equal(t, VectorClock{"sb1": 2, "sb2": 2, "sb3": 1}, a.Merge(VectorClock{"sb2": 2, "sb3": 1}))
+	require.Equal(t, uint64(3), a.Weight())
+
+	parsed, err := ParseVectorClock(a.String())
+	require.NoError(t, err)
+	require.Equal(t, a, parsed)
+}
+
+// replicate handles a replication entry as the daemon does, and returns whether it was applied
+func replicate(t *testing.T, db *gorm.DB, entry *Replication) (applied bool, conflict *ReplicationConflict) {
+	applied, conflict, err := ResolveReplicationEntry(db, entry)
+	require.NoError(t, err)
+	require.NoError(t, CommitReplicationEntry(db, entry, applied, conflict))
+	return
+}
+
+func TestReplicationConflicts(t *testing.T) {
+
+	sb1, err := GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+	sb2, err := GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+
+	key := ObjectKey("group access", "dba", "deploy", "db1", "22")
+
+	// sb1 grants the access, sb2 receives it
+	add := &Replication{Instance: "sb1", Action: "group access add"}
+	require.NoError(t, StampReplicationEntry(sb1, add, key))
+	require.Equal(t, `{"sb1":1}`, add.Clock)
+
+	// The entry is saved with its clock, to be published
+	var saved Replication
+	require.NoError(t, sb1.Where("uniq_id = ?", add.UniqID).First(&saved).Error)
+	require.Equal(t, add.Clock, saved.Clock)
+	require.Equal(t, key, saved.ObjectKey)
+
+	applied, conflict := replicate(t, sb2, add)
+	require.True(t, applied)
+	require.Nil(t, conflict)
+
+	// A redelivery is ignored
+	applied, conflict = replicate(t, sb2, add)
+	require.False(t, applied)
+	require.Nil(t, conflict)
+
+	// Then both instances act on the access at the same time
+	remove := &Replication{Instance: "sb2", Action: "group access remove"}
+	require.NoError(t, StampReplicationEntry(sb2, remove, key))
+	require.Equal(t, `{"sb1":1,"sb2":1}`, remove.Clock)
+
+	addAgain := &Replication{Instance: "sb1", Action: "group access add"}
+	require.NoError(t, StampReplicationEntry(sb1, addAgain, key))
+	require.Equal(t, `{"sb1":2}`, addAgain.Clock)
+
+	// Both clocks weigh 2: sb2 wins over sb1 on both instances
+	applied, conflict = replicate(t, sb1, remove)
+	require.True(t, applied)
+	require.NotNil(t, conflict)
+	require.Equal(t, "group access remove", conflict.WinnerAction)
+	require.Equal(t, "group access add", conflict.LoserAction)
+
+	applied, conflict = replicate(t, sb2, addAgain)
+	require.False(t, applied)
+	require.NotNil(t, conflict)
+	require.Equal(t, "sb2", conflict.WinnerInstance)
+	require.Equal(t, "sb1", conflict.LoserInstance)
+
+	// The next action follows both of them
+	last := &Replication{Instance: "sb1", Action: "group access add"}
+	require.NoError(t, StampReplicationEntry(sb1, last, key))
+	require.Equal(t, `{"sb1":3,"sb2":1}`, last.Clock)
+	applied, conflict = replicate(t, sb2, last)
+	require.True(t, applied)
+	require.Nil(t, conflict)
+
+	conflicts, err := GetReplicationConflicts(sb2, time.Time{}, 10)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	require.Equal(t, key, conflicts[0].ObjectKey)
+
+	// The entries of the commands that aren't versioned are always applied
+	applied, conflict = replicate(t, sb2, &Replication{Instance: "sb1", Action: "group create"})
+	require.True(t, applied)
+	require.Nil(t, conflict)
+}
+
+func TestAccessObjectKey(t *testing.T) {
+
+	// The different ways to write the same access are the same object
+	key := AccessObjectKey("group access", "dba", "10.0.0.1", "deploy", "22")
+	require.Equal(t, "group access:dba:deploy:10.0.0.1/32:22", key)
+	require.Equal(t, key, AccessObjectKey("group access", "dba", "10.0.0.1/32", "deploy", "022"))
+	require.NotEqual(t, key, AccessObjectKey("group access", "dba", "10.0.0.1", "deploy", "2222"))
+
+	require.Equal(t, AccessObjectKey("self access", "alice", "localhost", "root", "22"), AccessObjectKey("self access", "alice", "LOCALHOST", "root", "22"))
+	require.Equal(t, "group access:dba:deploy:10.0.0.0/8:22", AccessObjectKey("group access", "dba", "10.1.2.3/8", "deploy", "22"))
+	require.Equal(t, "group access:dba:deploy:2001:db8::1/128:22", AccessObjectKey("group access", "dba", "2001:DB8:0::1", "deploy", "22"))
+
+	// The host names are not resolved, so that every instance builds the same key
+	require.Equal(t, "self access:alice:root:db1.example.invalid:22", AccessObjectKey("self access", "alice", "DB1.example.invalid", "root", "22"))
+}
@@ -0,0 +1,264 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// The steps of the replication a dead letter failed at
+const (
+	DeadLetterConsume = "consume" // The entry of another instance could not be applied on this one
+	DeadLetterPublish = "publish" // The PostExecute step of an entry of this instance failed, so it was not published
+)
+
+// The bounds of the delay between two attempts to handle a replication entry, doubled after each failed attempt
+const (
+	FirstReplicationRetryDelay = time.Second
+	MaxReplicationRetryDelay   = time.Minute
+)
+
+// AppliedReplicationEntry records an entry of another instance applied on this one, so that it's never applied twice.
+// Until it is applied, it counts the failed attempts to apply it, the queue delivering it again after each of them.
+type AppliedReplicationEntry struct {
+	UniqID        string    `gorm:"PRIMARY_KEY"`
+	Instance      string    `gorm:"type:varchar(255)"`
+	Action        string    `gorm:"type:varchar(255)"`
+	Applied       bool      `gorm:"index"`
+	AppliedAt     time.Time `gorm:"type:datetime"`
+	Attempts      int       `gorm:"type:integer"` // The failed attempts to apply the entry
+	LastError     string    `gorm:"type:text"`
+	LastAttemptAt time.Time `gorm:"type:datetime;index"` // When the entry was last applied or failed to be
+}
+
+// ReplicationDeadLetter is a replication entry put aside after failing too many times
+type ReplicationDeadLetter struct {
+	ID             uint      `gorm:"primaryKey"`
+	Step           string    `gorm:"type:varchar(20)"` // consume or publish
+	UniqID         string    `gorm:"type:varchar(36);index"`
+	CreationDate   time.Time `gorm:"type:datetime"`
+	Instance       string    `gorm:"type:varchar(255)"`
+	Action         string    `gorm:"type:varchar(255)"`
+	Data           string    `gorm:"type:text"`
+	ObjectKey      string    `gorm:"type:text"`
+	Clock          string    `gorm:"type:text"`
+	Attempts       int       `gorm:"type:integer"`
+	LastError      string    `gorm:"type:text"`
+	FailedAt       time.Time `gorm:"type:datetime"`
+	RetryRequested bool      // Whether the daemon has to try it again
+}
+
+// IsReplicationEntryApplied returns whether an entry of another instance was already applied on this one
+func IsReplicationEntryApplied(db *gorm.DB, uniqID string) (applied bool, err error) {
+
+	var count int64
+	err = db.Model(&AppliedReplicationEntry{}).Where("uniq_id = ? AND applied = ?", uniqID, true).Count(&count).Error
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to check whether entry %s was applied", uniqID)
+	}
+
+	return count > 0, nil
+}
+
+// MarkReplicationEntryApplied records that an entry of another instance was applied on this one
+func MarkReplicationEntryApplied(db *gorm.DB, entry *Replication) (err error) {
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+
+		applied, err := getAppliedReplicationEntry(tx, entry)
+		if err != nil {
+			return err
+		}
+
+		applied.Applied = true
+		applied.AppliedAt = now
+		applied.LastAttemptAt = now
+
+		return tx.Save(applied).Error
+	})
+	if err != nil {
+		err = errors.Wrapf(err, "unable to record that entry %s was applied", entry.UniqID)
+	}
+
+	return
+}
+
+// RecordReplicationEntryFailure records a failed attempt to apply an entry of another instance, and returns how many
+// attempts failed so far
+func RecordReplicationEntryFailure(db *gorm.DB, entry *Replication, attemptError error) (attempts int, err error) {
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+
+		applied, err := getAppliedReplicationEntry(tx, entry)
+		if err != nil {
+			return err
+		}
+
+		applied.Attempts++
+		applied.LastError = attemptError.Error()
+		applied.LastAttemptAt = time.Now()
+		attempts = applied.Attempts
+
+		return tx.Save(applied).Error
+	})
+	if err != nil {
+		err = errors.Wrapf(err, "unable to record the failed attempt to apply entry %s", entry.UniqID)
+	}
+
+	return
+}
+
+// getAppliedReplicationEntry returns the record of an entry of another instance, or a new one if it was never attempted
+func getAppliedReplicationEntry(db *gorm.DB, entry *Replication) (applied *AppliedReplicationEntry, err error) {
+
+	applied = new(AppliedReplicationEntry)
+	res := db.Where("uniq_id = ?", entry.UniqID).Limit(1).Find(applied)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		applied = &AppliedReplicationEntry{
+			UniqID:   entry.UniqID,
+			Instance: entry.Instance,
+			Action:   entry.Action,
+		}
+	}
+
+	return
+}
+
+// PurgeAppliedReplicationEntries forgets the entries last applied or attempted before a date, and returns how many
+// were forgotten
+func PurgeAppliedReplicationEntries(db *gorm.DB, before time.Time) (purged int64, err error) {
+
+	res := db.Where("last_attempt_at < ?", before).Delete(&AppliedReplicationEntry{})
+	if res.Error != nil {
+		return 0, errors.Wrap(res.Error, "unable to purge the applied entries")
+	}
+
+	return res.RowsAffected, nil
+}
+
+// ReplicationRetryDelay returns the delay before the next attempt to handle a replication entry, after the failed ones
+func ReplicationRetryDelay(attempts int) time.Duration {
+	delay := FirstReplicationRetryDelay
+	for i := 1; i < attempts && delay < MaxReplicationRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > MaxReplicationRetryDelay {
+		delay = MaxReplicationRetryDelay
+	}
+	return delay
+}
+
+// ReplicationRetryError is returned by the consumers of the replication queue when an entry failed,
+// for the queue to deliver it again once the delay elapsed
+type ReplicationRetryError struct {
+	Err   error
+	Delay time.Duration
+}
+
+func (e *ReplicationRetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReplicationRetryError) Unwrap() error {
+	return e.Err
+}
+
+// GetReplicationRetryDelay returns the delay before delivering an entry again after the error of its consumer
+func GetReplicationRetryDelay(err error) time.Duration {
+	var retryErr *ReplicationRetryError
+	if errors.As(err, &retryErr) {
+		return retryErr.Delay
+	}
+	return FirstReplicationRetryDelay
+}
+
+// NewReplicationDeadLetter builds the dead letter of an entry that failed at a step of the replication
+func NewReplicationDeadLetter(step string, entry *Replication, attempts int, lastError error) *ReplicationDeadLetter {
+	return &ReplicationDeadLetter{
+		Step:         step,
+		UniqID:       entry.UniqID,
+		CreationDate: entry.CreationDate,
+		Instance:     entry.Instance,
+		Action:       entry.Action,
+		Data:         entry.Data,
+		ObjectKey:    entry.ObjectKey,
+		Clock:        entry.Clock,
+		Attempts:     attempts,
+		LastError:    lastError.Error(),
+		FailedAt:     time.Now(),
+	}
+}
+
+// GetReplicationDeadLetters returns the dead letters, the oldest first
+func GetReplicationDeadLetters(db *gorm.DB) (deadLetters []*ReplicationDeadLetter, err error) {
+
+	err = db.Order("id").Find(&deadLetters).Error
+	if err != nil {
+		err = errors.Wrap(err, "unable to get the dead letters")
+	}
+
+	return
+}
+
+// GetReplicationDeadLetter returns a dead letter by its ID
+func GetReplicationDeadLetter(db *gorm.DB, id uint) (deadLetter *ReplicationDeadLetter, err error) {
+
+	deadLetter = new(ReplicationDeadLetter)
+	res := db.Where("id = ?", id).Limit(1).Find(deadLetter)
+	if res.Error != nil {
+		return nil, errors.Wrapf(res.Error, "unable to get dead letter %d", id)
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.Errorf("dead letter %d not found", id)
+	}
+
+	return
+}
+
+// GetRetryRequestedDeadLetters returns the dead letters the daemon has to try again, the oldest first
+func GetRetryRequestedDeadLetters(db *gorm.DB) (deadLetters []*ReplicationDeadLetter, err error) {
+
+	err = db.Where("retry_requested = ?", true).Order("id").Find(&deadLetters).Error
+	if err != nil {
+		err = errors.Wrap(err, "unable to get the dead letters to retry")
+	}
+
+	return
+}
+
+// Entry rebuilds the replication entry of the dead letter
+func (dl *ReplicationDeadLetter) Entry() *Replication {
+	return &Replication{
+		UniqID:       dl.UniqID,
+		CreationDate: dl.CreationDate,
+		Instance:     dl.Instance,
+		Action:       dl.Action,
+		Data:         dl.Data,
+		ObjectKey:    dl.ObjectKey,
+		Clock:        dl.Clock,
+	}
+}
+
+// Save saves the dead letter in the provided database
+func (dl *ReplicationDeadLetter) Save(db *gorm.DB) (err error) {
+	return db.Save(dl).Error
+}
+
+// Delete removes the dead letter from the provided database
+func (dl *ReplicationDeadLetter) Delete(db *gorm.DB) (err error) {
+	return db.Delete(dl).Error
+}
+
+// Postpone records another failed attempt, and waits for a new retry request
+func (dl *ReplicationDeadLetter) Postpone(db *gorm.DB, attemptError error) (err error) {
+	dl.Attempts++
+	dl.LastError = attemptError.Error()
+	dl.FailedAt = time.Now()
+	dl.RetryRequested = false
+	return db.Save(dl).Error
+}
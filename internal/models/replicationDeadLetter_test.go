@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicationRetryDelay(t *testing.T) {
+
+	require.Equal(t, time.Second, ReplicationRetryDelay(1))
+	require.Equal(t, 2*time.Second, ReplicationRetryDelay(2))
+	require.Equal(t, 16*time.Second, ReplicationRetryDelay(5))
+	require.Equal(t, time.Minute, ReplicationRetryDelay(100))
+
+	// The consumers tell the queue when to deliver a failed entry again
+	err := fmt.Errorf("applying: %w", &ReplicationRetryError{Err: errors.New("failed"), Delay: 8 * time.Second})
+	require.Equal(t, 8*time.Second, GetReplicationRetryDelay(err))
+	require.Equal(t, "applying: failed", err.Error())
+	require.Equal(t, FirstReplicationRetryDelay, GetReplicationRetryDelay(errors.New("failed")))
+}
+
+func TestAppliedReplicationEntries(t *testing.T) {
+
+	db, err := GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+
+	entry := &Replication{UniqID: "e1", Instance: "sb2", Action: "group access add"}
+
+	applied, err := IsReplicationEntryApplied(db, entry.UniqID)
+	require.NoError(t, err)
+	require.False(t, applied)
+
+	// The failed attempts are counted until the entry is applied
+	for expected := 1; expected <= 2; expected++ {
+		attempts, err := RecordReplicationEntryFailure(db, entry, errors.New("boom"))
+		require.NoError(t, err)
+		require.Equal(t, expected, attempts)
+	}
+	applied, err = IsReplicationEntryApplied(db, entry.UniqID)
+	require.NoError(t, err)
+	require.False(t, applied)
+
+	require.NoError(t, MarkReplicationEntryApplied(db, entry))
+	applied, err = IsReplicationEntryApplied(db, entry.UniqID)
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	// Only the applied entries are part of the watermark of a snapshot
+	_, err = RecordReplicationEntryFailure(db, &Replication{UniqID: "e2", Instance: "sb2"}, errors.New("boom"))
+	require.NoError(t, err)
+	watermark, err := GetReplicationWatermark(db, "sb1")
+	require.NoError(t, err)
+	require.Len(t, watermark.Applied, 1)
+	require.Equal(t, entry.UniqID, watermark.Applied[0].UniqID)
+	require.Equal(t, 2, watermark.Applied[0].Attempts)
+
+	purged, err := PurgeAppliedReplicationEntries(db, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, purged)
+
+	purged, err = PurgeAppliedReplicationEntries(db, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), purged)
+
+	applied, err = IsReplicationEntryApplied(db, entry.UniqID)
+	require.NoError(t, err)
+	require.False(t, applied)
+}
+
+func TestReplicationDeadLetters(t *testing.T) {
+
+	db, err := GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+
+	entry := &Replication{UniqID: "e1", Instance: "sb2", Action: "group access add", Data: "data", ObjectKey: "group-access:ops", Clock: "sb2:1"}
+	dl := NewReplicationDeadLetter(DeadLetterConsume, entry, 5, errors.New("boom"))
+	require.NoError(t, dl.Save(db))
+	require.NoError(t, NewReplicationDeadLetter(DeadLetterPublish, &Replication{UniqID: "e2", Action: "account create"}, 5, errors.New("boom")).Save(db))
+
+	deadLetters, err := GetReplicationDeadLetters(db)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 2)
+	require.Equal(t, entry, deadLetters[0].Entry())
+	require.Equal(t, "boom", deadLetters[0].LastError)
+
+	retry, err := GetRetryRequestedDeadLetters(db)
+	require.NoError(t, err)
+	require.Empty(t, retry)
+
+	dl.RetryRequested = true
+	require.NoError(t, dl.Save(db))
+	retry, err = GetRetryRequestedDeadLetters(db)
+	require.NoError(t, err)
+	require.Len(t, retry, 1)
+
+	// A failed retry waits for a new request
+	require.NoError(t, retry[0].Postpone(db, errors.New("boom again")))
+	dl, err = GetReplicationDeadLetter(db, dl.ID)
+	require.NoError(t, err)
+	require.Equal(t, 6, dl.Attempts)
+	require.Equal(t, "boom again", dl.LastError)
+	require.False(t, dl.RetryRequested)
+
+	require.NoError(t, dl.Delete(db))
+	_, err = GetReplicationDeadLetter(db, dl.ID)
+	require.Error(t, err)
+}
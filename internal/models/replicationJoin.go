@@ -0,0 +1,290 @@
+package models
+
+import (
+	"encoding/base64"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// The states of a join
+const (
+	JoinRequested = "requested" // The snapshot was requested from the peer
+	JoinReceiving = "receiving" // The chunks of the snapshot are being received
+	JoinDone      = "done"      // The snapshot was restored, and the entries received meanwhile were applied
+	JoinFailed    = "failed"
+)
+
+// ReplicationJoin describes the bootstrap of this instance from the snapshot of a peer
+type ReplicationJoin struct {
+	RequestID   string    `gorm:"PRIMARY_KEY"`
+	Peer        string    `gorm:"type:varchar(255)"`
+	State       string    `gorm:"type:varchar(20)"`
+	RequestedAt time.Time `gorm:"type:datetime"`
+	Chunks      int       `gorm:"type:integer"` // The number of chunks of the snapshot, once known
+	Received    int       `gorm:"type:integer"`
+	SnapshotAt  time.Time `gorm:"type:datetime"` // When the peer took the snapshot
+	CompletedAt time.Time `gorm:"type:datetime"`
+	Error       string    `gorm:"type:text"`
+}
+
+// SnapshotChunk is a part of the snapshot of a peer, received through the replication queue
+type SnapshotChunk struct {
+	RequestID string `gorm:"primaryKey"`
+	Index     int    `gorm:"primaryKey;autoIncrement:false"`
+	Data      string `gorm:"type:text"` // The base64 content of the chunk
+}
+
+// BufferedReplicationEntry is an entry of another instance received during a join, applied once the snapshot is restored
+type BufferedReplicationEntry struct {
+	ID           uint      `gorm:"primaryKey"`
+	UniqID       string    `gorm:"type:varchar(36)"`
+	CreationDate time.Time `gorm:"type:datetime"`
+	Instance     string    `gorm:"type:varchar(255)"`
+	Action       string    `gorm:"type:varchar(255)"`
+	Data         string    `gorm:"type:text"`
+	ObjectKey    string    `gorm:"type:text"`
+	Clock        string    `gorm:"type:text"`
+}
+
+// ReplicationWatermark describes what the snapshot of an instance contains: the entries of the other instances it
+// applied, its own entries created until the snapshot, and the versions of the objects
+type ReplicationWatermark struct {
+	Instance   string                     `json:"instance"`
+	SnapshotAt time.Time                  `json:"snapshot_at"`
+	Applied    []*AppliedReplicationEntry `json:"applied"`
+	Versions   []*ObjectVersion           `json:"versions"`
+}
+
+// GetActiveReplicationJoin returns the join in progress, if any
+func GetActiveReplicationJoin(db *gorm.DB) (join *ReplicationJoin, err error) {
+
+	var joins []*ReplicationJoin
+	err = db.Where("state IN ?", []string{JoinRequested, JoinReceiving}).Order("requested_at DESC").Limit(1).Find(&joins).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get the join in progress")
+	}
+	if len(joins) == 0 {
+		return nil, nil
+	}
+
+	return joins[0], nil
+}
+
+// GetReplicationJoin returns a join by its request ID
+func GetReplicationJoin(db *gorm.DB, requestID string) (join *ReplicationJoin, err error) {
+
+	join = new(ReplicationJoin)
+	res := db.Where("request_id = ?", requestID).Limit(1).Find(join)
+	if res.Error != nil {
+		return nil, errors.Wrapf(res.Error, "unable to get join %s", requestID)
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return
+}
+
+// Save saves the join in the provided database
+func (j *ReplicationJoin) Save(db *gorm.DB) (err error) {
+	return db.Save(j).Error
+}
+
+// Fail records why the join failed
+func (j *ReplicationJoin) Fail(db *gorm.DB, joinError error) (err error) {
+	j.State = JoinFailed
+	j.Error = joinError.Error()
+	j.CompletedAt = time.Now()
+	return db.Save(j).Error
+}
+
+// SaveSnapshotChunk records a chunk of the snapshot of the join, and returns whether the snapshot is complete
+func SaveSnapshotChunk(db *gorm.DB, join *ReplicationJoin, index, total int, data string) (complete bool, err error) {
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+
+		err := tx.Save(&SnapshotChunk{RequestID: join.RequestID, Index: index, Data: data}).Error
+		if err != nil {
+			return err
+		}
+
+		var received int64
+		err = tx.Model(&SnapshotChunk{}).Where("request_id = ?", join.RequestID).Count(&received).Error
+		if err != nil {
+			return err
+		}
+
+		join.State = JoinReceiving
+		join.Chunks = total
+		join.Received = int(received)
+
+		return tx.Save(join).Error
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to save chunk %d of the snapshot", index)
+	}
+
+	return join.Received >= join.Chunks, nil
+}
+
+// WriteSnapshot writes the snapshot of the join, from its chunks
+func WriteSnapshot(db *gorm.DB, requestID string, w io.Writer) (err error) {
+
+	rows, err := db.Model(&SnapshotChunk{}).Where("request_id = ?", requestID).Order("`index`").Rows()
+	if err != nil {
+		return errors.Wrap(err, "unable to read the chunks of the snapshot")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chunk SnapshotChunk
+		err = db.ScanRows(rows, &chunk)
+		if err != nil {
+			return errors.Wrap(err, "unable to read a chunk of the snapshot")
+		}
+
+		data, err := base64.StdEncoding.DecodeString(chunk.Data)
+		if err != nil {
+			return errors.Wrapf(err, "unable to decode chunk %d of the snapshot", chunk.Index)
+		}
+
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// DeleteSnapshotChunks removes the chunks of the snapshot of a join
+func DeleteSnapshotChunks(db *gorm.DB, requestID string) (err error) {
+	return db.Where("request_id = ?", requestID).Delete(&SnapshotChunk{}).Error
+}
+
+// BufferReplicationEntry keeps an entry of another instance to apply it once the join is done
+func BufferReplicationEntry(db *gorm.DB, entry *Replication) (err error) {
+
+	err = db.Create(&BufferedReplicationEntry{
+		UniqID:       entry.UniqID,
+		CreationDate: entry.CreationDate,
+		Instance:     entry.Instance,
+		Action:       entry.Action,
+		Data:         entry.Data,
+		ObjectKey:    entry.ObjectKey,
+		Clock:        entry.Clock,
+	}).Error
+	if err != nil {
+		err = errors.Wrapf(err, "unable to buffer entry %s", entry.UniqID)
+	}
+
+	return
+}
+
+// GetBufferedReplicationEntries returns the buffered entries, in the order they were received
+func GetBufferedReplicationEntries(db *gorm.DB) (entries []*BufferedReplicationEntry, err error) {
+
+	err = db.Order("id").Find(&entries).Error
+	if err != nil {
+		err = errors.Wrap(err, "unable to get the buffered entries")
+	}
+
+	return
+}
+
+// Entry rebuilds the replication entry
+func (b *BufferedReplicationEntry) Entry() *Replication {
+	return &Replication{
+		UniqID:       b.UniqID,
+		CreationDate: b.CreationDate,
+		Instance:     b.Instance,
+		Action:       b.Action,
+		Data:         b.Data,
+		ObjectKey:    b.ObjectKey,
+		Clock:        b.Clock,
+	}
+}
+
+// Delete removes the buffered entry from the provided database
+func (b *BufferedReplicationEntry) Delete(db *gorm.DB) (err error) {
+	return db.Delete(b).Error
+}
+
+// GetReplicationWatermark returns the watermark of a snapshot of this instance taken now
+func GetReplicationWatermark(db *gorm.DB, instance string) (watermark *ReplicationWatermark, err error) {
+
+	watermark = &ReplicationWatermark{
+		Instance:   instance,
+		SnapshotAt: time.Now(),
+	}
+
+	err = db.Where("applied = ?", true).Find(&watermark.Applied).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get the applied entries")
+	}
+
+	// The entries of this instance not published yet will reach the peer after the snapshot
+	var pending []*Replication
+	err = db.Find(&pending).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get the pending entries")
+	}
+	for _, entry := range pending {
+		watermark.Applied = append(watermark.Applied, &AppliedReplicationEntry{
+			UniqID:        entry.UniqID,
+			Instance:      entry.Instance,
+			Action:        entry.Action,
+			Applied:       true,
+			AppliedAt:     watermark.SnapshotAt,
+			LastAttemptAt: watermark.SnapshotAt,
+		})
+	}
+
+	err = db.Find(&watermark.Versions).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get the versions of the objects")
+	}
+
+	return
+}
+
+// Covers returns whether the snapshot already contains an entry
+func (w *ReplicationWatermark) Covers(entry *Replication) bool {
+	return entry.Instance == w.Instance && !entry.CreationDate.After(w.SnapshotAt)
+}
+
+// ImportReplicationWatermark records the entries applied by the peer as applied on this instance, and replaces
+// the versions of the objects by the ones of the peer
+func ImportReplicationWatermark(db *gorm.DB, watermark *ReplicationWatermark) (err error) {
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+
+		for _, applied := range watermark.Applied {
+			err := tx.Save(applied).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.Where("1 = 1").Delete(&ObjectVersion{}).Error
+		if err != nil {
+			return err
+		}
+		for _, version := range watermark.Versions {
+			err = tx.Create(version).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = errors.Wrap(err, "unable to import the watermark of the snapshot")
+	}
+
+	return
+}
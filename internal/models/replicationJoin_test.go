@@ -0,0 +1,95 @@
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicationJoin(t *testing.T) {
+
+	donor, err := GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+	joiner, err := GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+
+	join, err := GetActiveReplicationJoin(joiner)
+	require.NoError(t, err)
+	require.Nil(t, join)
+
+	join = &ReplicationJoin{RequestID: "r1", Peer: "sb1", State: JoinRequested, RequestedAt: time.Now()}
+	require.NoError(t, join.Save(joiner))
+
+	active, err := GetActiveReplicationJoin(joiner)
+	require.NoError(t, err)
+	require.Equal(t, "r1", active.RequestID)
+
+	// The donor applied an entry of sb2, and has one of its own to publish
+	require.NoError(t, MarkReplicationEntryApplied(donor, &Replication{UniqID: "sb2-1", Instance: "sb2", Action: "account create"}))
+	require.NoError(t, (&Replication{UniqID: "sb1-2", Instance: "sb1", Action: "group create"}).Save(donor))
+	require.NoError(t, donor.Create(&ObjectVersion{ObjectKey: "group:ops", Clock: "sb1:1", Instance: "sb1", EntryID: "sb1-1"}).Error)
+
+	watermark, err := GetReplicationWatermark(donor, "sb1")
+	require.NoError(t, err)
+	require.Len(t, watermark.Applied, 2)
+	require.Len(t, watermark.Versions, 1)
+
+	require.True(t, watermark.Covers(&Replication{Instance: "sb1", CreationDate: watermark.SnapshotAt.Add(-time.Second)}))
+	require.False(t, watermark.Covers(&Replication{Instance: "sb1", CreationDate: watermark.SnapshotAt.Add(time.Second)}))
+	require.False(t, watermark.Covers(&Replication{Instance: "sb2", CreationDate: watermark.SnapshotAt.Add(-time.Second)}))
+
+	// The chunks can be received in any order
+	snapshot := []byte("a snapshot split in three chunks")
+	chunks := [][]byte{snapshot[:10], snapshot[10:20], snapshot[20:]}
+	for i, index := range []int{2, 0, 1} {
+		complete, err := SaveSnapshotChunk(joiner, join, index, 3, base64.StdEncoding.EncodeToString(chunks[index]))
+		require.NoError(t, err)
+		require.Equal(t, i == 2, complete)
+		require.Equal(t, JoinReceiving, join.State)
+		require.Equal(t, i+1, join.Received)
+	}
+
+	var restored bytes.Buffer
+	require.NoError(t, WriteSnapshot(joiner, "r1", &restored))
+	require.Equal(t, snapshot, restored.Bytes())
+
+	require.NoError(t, DeleteSnapshotChunks(joiner, "r1"))
+	restored.Reset()
+	require.NoError(t, WriteSnapshot(joiner, "r1", &restored))
+	require.Zero(t, restored.Len())
+
+	// The entries received during the join are kept in order
+	require.NoError(t, BufferReplicationEntry(joiner, &Replication{UniqID: "sb2-1", Instance: "sb2", Action: "account create"}))
+	require.NoError(t, BufferReplicationEntry(joiner, &Replication{UniqID: "sb2-2", Instance: "sb2", Action: "group create", Data: "data"}))
+	buffered, err := GetBufferedReplicationEntries(joiner)
+	require.NoError(t, err)
+	require.Len(t, buffered, 2)
+	require.Equal(t, "sb2-2", buffered[1].Entry().UniqID)
+	require.Equal(t, "data", buffered[1].Entry().Data)
+
+	require.NoError(t, ImportReplicationWatermark(joiner, watermark))
+	for id, expected := range map[string]bool{"sb2-1": true, "sb1-2": true, "sb2-2": false} {
+		applied, err := IsReplicationEntryApplied(joiner, id)
+		require.NoError(t, err)
+		require.Equal(t, expected, applied, id)
+	}
+
+	version, err := getObjectVersion(joiner, "group:ops")
+	require.NoError(t, err)
+	require.Equal(t, "sb1:1", version.Clock)
+
+	require.NoError(t, buffered[0].Delete(joiner))
+	buffered, err = GetBufferedReplicationEntries(joiner)
+	require.NoError(t, err)
+	require.Len(t, buffered, 1)
+
+	join.State = JoinDone
+	require.NoError(t, join.Save(joiner))
+	active, err = GetActiveReplicationJoin(joiner)
+	require.NoError(t, err)
+	require.Nil(t, active)
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReplicationInstanceStatus describes the replication of the entries of an instance, as seen by this one:
+// the entries pushed for this instance, the entries consumed for the other ones
+type ReplicationInstanceStatus struct {
+	Instance         string    `gorm:"PRIMARY_KEY"`
+	Pushed           int64     `gorm:"type:integer"`
+	LastPushAt       time.Time `gorm:"type:datetime"`
+	PushErrors       int64     `gorm:"type:integer"` // The failed PostExecute steps and pushes to the queue
+	Consumed         int64     `gorm:"type:integer"`
+	LastConsumeAt    time.Time `gorm:"type:datetime"`
+	LastConsumedDate time.Time `gorm:"type:datetime"` // When the last consumed entry was created, to compute the lag
+	ConsumeErrors    int64     `gorm:"type:integer"`  // The failed attempts to apply an entry
+	LastError        string    `gorm:"type:text"`
+	LastErrorAt      time.Time `gorm:"type:datetime"`
+}
+
+// ReplicationStatus describes the state of the replication on this instance
+type ReplicationStatus struct {
+	Pending       int64     // The entries of this instance waiting to be post-executed and published
+	OldestPending time.Time // When the oldest of them was created (zero without pending entries)
+	DeadLetters   int64
+	Instances     []*ReplicationInstanceStatus
+}
+
+// Lag returns the delay between the creation of the last entry of the instance consumed by this one, and its consumption
+func (s *ReplicationInstanceStatus) Lag() time.Duration {
+	if s.LastConsumeAt.IsZero() || s.LastConsumedDate.IsZero() {
+		return 0
+	}
+	return s.LastConsumeAt.Sub(s.LastConsumedDate)
+}
+
+// RecordReplicationPush records an attempt to publish an entry of this instance
+func RecordReplicationPush(db *gorm.DB, instance string, pushError error) (err error) {
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	if pushError == nil {
+		updates["pushed"] = gorm.Expr("pushed + 1")
+		updates["last_push_at"] = now
+	} else {
+		updates["push_errors"] = gorm.Expr("push_errors + 1")
+		updates["last_error"] = pushError.Error()
+		updates["last_error_at"] = now
+	}
+
+	return recordReplicationInstance(db, instance, updates)
+}
+
+// RecordReplicationConsume records an attempt to apply an entry of another instance
+func RecordReplicationConsume(db *gorm.DB, entry *Replication, consumeError error) (err error) {
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	if consumeError == nil {
+		updates["consumed"] = gorm.Expr("consumed + 1")
+		updates["last_consume_at"] = now
+		updates["last_consumed_date"] = entry.CreationDate
+	} else {
+		updates["consume_errors"] = gorm.Expr("consume_errors + 1")
+		updates["last_error"] = consumeError.Error()
+		updates["last_error_at"] = now
+	}
+
+	return recordReplicationInstance(db, entry.Instance, updates)
+}
+
+func recordReplicationInstance(db *gorm.DB, instance string, updates map[string]interface{}) (err error) {
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ReplicationInstanceStatus{Instance: instance}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&ReplicationInstanceStatus{}).Where("instance = ?", instance).Updates(updates).Error
+	})
+	if err != nil {
+		err = errors.Wrapf(err, "unable to record the replication status of %s", instance)
+	}
+
+	return
+}
+
+// GetReplicationStatus returns the state of the replication on this instance
+func GetReplicationStatus(db *gorm.DB) (status *ReplicationStatus, err error) {
+
+	status = new(ReplicationStatus)
+
+	err = db.Model(&Replication{}).Count(&status.Pending).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to count the pending entries")
+	}
+
+	if status.Pending > 0 {
+		var oldest Replication
+		err = db.Order("creation_date ASC").Limit(1).Find(&oldest).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get the oldest pending entry")
+		}
+		status.OldestPending = oldest.CreationDate
+	}
+
+	err = db.Model(&ReplicationDeadLetter{}).Count(&status.DeadLetters).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to count the dead letters")
+	}
+
+	err = db.Order("instance").Find(&status.Instances).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get the replication status of the instances")
+	}
+
+	return
+}
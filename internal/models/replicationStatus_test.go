@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicationStatus(t *testing.T) {
+
+	db, err := GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+
+	status, err := GetReplicationStatus(db)
+	require.NoError(t, err)
+	require.Zero(t, status.Pending)
+	require.True(t, status.OldestPending.IsZero())
+	require.Empty(t, status.Instances)
+
+	oldest := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, (&Replication{Instance: "sb1", Action: "account create", CreationDate: oldest}).Save(db))
+	require.NoError(t, (&Replication{Instance: "sb1", Action: "group create"}).Save(db))
+	require.NoError(t, NewReplicationDeadLetter(DeadLetterConsume, &Replication{UniqID: "e0", Instance: "sb2"}, 5, errors.New("boom")).Save(db))
+
+	require.NoError(t, RecordReplicationPush(db, "sb1", nil))
+	require.NoError(t, RecordReplicationPush(db, "sb1", errors.New("queue unavailable")))
+	require.NoError(t, RecordReplicationPush(db, "sb1", nil))
+
+	created := time.Now().Add(-2 * time.Second)
+	require.NoError(t, RecordReplicationConsume(db, &Replication{UniqID: "e1", Instance: "sb2"}, errors.New("boom")))
+	require.NoError(t, RecordReplicationConsume(db, &Replication{UniqID: "e1", Instance: "sb2", CreationDate: created}, nil))
+
+	status, err = GetReplicationStatus(db)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), status.Pending)
+	require.True(t, oldest.Equal(status.OldestPending))
+	require.Equal(t, int64(1), status.DeadLetters)
+	require.Len(t, status.Instances, 2)
+
+	sb1, sb2 := status.Instances[0], status.Instances[1]
+	require.Equal(t, "sb1", sb1.Instance)
+	require.Equal(t, int64(2), sb1.Pushed)
+	require.Equal(t, int64(1), sb1.PushErrors)
+	require.Equal(t, "queue unavailable", sb1.LastError)
+	require.False(t, sb1.LastPushAt.IsZero())
+	require.Zero(t, sb1.Consumed)
+	require.Zero(t, sb1.Lag())
+
+	require.Equal(t, "sb2", sb2.Instance)
+	require.Equal(t, int64(1), sb2.Consumed)
+	require.Equal(t, int64(1), sb2.ConsumeErrors)
+	require.Equal(t, "boom", sb2.LastError)
+	require.InDelta(t, 2*time.Second, sb2.Lag(), float64(time.Second))
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Types of requests
+const (
+	RequestTypeAccess = "access" // A request for a group access, decided by the group's ACL keepers
+	RequestTypeMember = "member" // A request for a group membership, decided by the group's gate keepers
+)
+
+// requestCommands are the commands run once a request of each type is approved
+var requestCommands = map[string]string{
+	RequestTypeAccess: "group access add",
+	RequestTypeMember: "group member add",
+}
+
+// Statuses of requests
+const (
+	RequestStatusPending  = "pending"
+	RequestStatusApproved = "approved"
+	RequestStatusDenied   = "denied"
+)
+
+// Request describes a request waiting for the approval of a group's ACL keepers or gate keepers
+type Request struct {
+	UniqID          string    `gorm:"PRIMARY_KEY"`
+	Type            string    `gorm:"type:varchar(10);index"` // RequestTypeAccess or RequestTypeMember
+	Group           string    `gorm:"type:varchar(50);index"` // The group the request is made to
+	Requester       string    `gorm:"type:varchar(50)"`       // The account that made the request
+	Command         string    `gorm:"type:varchar(50)"`       // The command replicated once the request is approved
+	Data            string    `gorm:"type:text"`              // The JSON encoded replication data of the command
+	Reason          string    `gorm:"type:text"`              // Why the requester needs it
+	Status          string    `gorm:"type:varchar(10);index"` // RequestStatusPending, RequestStatusApproved or RequestStatusDenied
+	CreationDate    time.Time `gorm:"type:datetime"`
+	DecidedBy       string    `gorm:"type:varchar(50)"` // The account that approved or denied the request
+	DecisionDate    time.Time `gorm:"type:datetime"`
+	DecisionComment string    `gorm:"type:text"`
+}
+
+// BeforeCreate will set a UUID if not present
+func (r *Request) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.UniqID == "" {
+		r.UniqID = uuid.New().String()
+	}
+	return nil
+}
+
+// NewRequest builds a new pending request
+func NewRequest(requestType, group, requester, command, reason string, data ReplicationData) (r *Request, err error) {
+
+	dataStr, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	r = &Request{
+		UniqID:       uuid.New().String(),
+		Type:         requestType,
+		Group:        group,
+		Requester:    requester,
+		Command:      command,
+		Data:         string(dataStr),
+		Reason:       reason,
+		Status:       RequestStatusPending,
+		CreationDate: time.Now().UTC(),
+	}
+
+	return
+}
+
+// GetRequest returns a request from the provided database
+func GetRequest(db *gorm.DB, uniqID string) (r *Request, err error) {
+
+	var requests []*Request
+	err = db.Where("uniq_id = ?", uniqID).Limit(1).Find(&requests).Error
+	if err != nil {
+		return
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("request %s not found", uniqID)
+	}
+
+	return requests[0], nil
+}
+
+// GetRequests returns the requests of a group with the provided status, oldest first.
+// An empty status returns the requests whatever their status.
+func GetRequests(db *gorm.DB, group, status string, requestTypes ...string) (requests []*Request, err error) {
+
+	tx := db.Where("`group` = ?", group)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if len(requestTypes) > 0 {
+		tx = tx.Where("type IN ?", requestTypes)
+	}
+
+	err = tx.Order("creation_date ASC").Find(&requests).Error
+
+	return
+}
+
+// Save saves the request in the provided database
+func (r *Request) Save(db *gorm.DB) (err error) {
+	return db.Save(r).Error
+}
+
+// GetData returns the replication data of the command to replicate once approved
+func (r *Request) GetData() (data ReplicationData, err error) {
+	err = json.Unmarshal([]byte(r.Data), &data)
+	return
+}
+
+// GetCommand returns the command to run once the request is approved, which only depends on its type:
+// a request storing another command was not made by sb, and is rejected
+func (r *Request) GetCommand() (command string, err error) {
+
+	command, ok := requestCommands[r.Type]
+	if !ok {
+		return "", fmt.Errorf("request %s has an unknown type %s", r.UniqID, r.Type)
+	}
+	if r.Command != command {
+		return "", fmt.Errorf("request %s of type %s can't run command %s", r.UniqID, r.Type, r.Command)
+	}
+
+	return
+}
+
+// IsPending returns true if no decision was taken on the request yet
+func (r *Request) IsPending() bool {
+	return r.Status == RequestStatusPending
+}
+
+// Decide records the decision taken on the request
+func (r *Request) Decide(db *gorm.DB, status, decidedBy, comment string, date time.Time) (err error) {
+
+	if status != RequestStatusApproved && status != RequestStatusDenied {
+		return fmt.Errorf("status should be from the list: %s, %s", RequestStatusApproved, RequestStatusDenied)
+	}
+
+	r.Status = status
+	r.DecidedBy = decidedBy
+	r.DecisionComment = comment
+	r.DecisionDate = date.UTC()
+
+	return r.Save(db)
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequests(t *testing.T) {
+
+	db, err := GetRequestsGormDB(filepath.Join(t.TempDir(), "requests.db"))
+	require.NoError(t, err)
+
+	accessRequest, err := NewRequest(RequestTypeAccess, "developers", "alice", "group access add", "deploy the release", ReplicationData{
+		"group": "developers",
+		"host":  "127.0.0.1",
+		"user":  "root",
+		"port":  "22",
+	})
+	require.NoError(t, err)
+	require.True(t, accessRequest.IsPending())
+	require.NoError(t, accessRequest.Save(db))
+
+	memberRequest, err := NewRequest(RequestTypeMember, "developers", "bob", "group member add", "", ReplicationData{
+		"group":   "developers",
+		"account": "bob",
+	})
+	require.NoError(t, err)
+	require.NoError(t, memberRequest.Save(db))
+
+	otherRequest, err := NewRequest(RequestTypeMember, "sysadmins", "bob", "group member add", "", ReplicationData{})
+	require.NoError(t, err)
+	require.NoError(t, otherRequest.Save(db))
+
+	requests, err := GetRequests(db, "developers", RequestStatusPending)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+
+	requests, err = GetRequests(db, "developers", RequestStatusPending, RequestTypeMember)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	require.Equal(t, memberRequest.UniqID, requests[0].UniqID)
+
+	request, err := GetRequest(db, accessRequest.UniqID)
+	require.NoError(t, err)
+	data, err := request.GetData()
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", data["host"])
+
+	command, err := request.GetCommand()
+	require.NoError(t, err)
+	require.Equal(t, "group access add", command)
+
+	// The command is derived from the type of the request
+	for _, r := range []*Request{
+		{Type: RequestTypeAccess, Command: "group member add"},
+		{Type: RequestTypeMember, Command: "account delete"},
+		{Type: "other", Command: "group member add"},
+	} {
+		_, err = r.GetCommand()
+		require.Error(t, err)
+	}
+
+	_, err = GetRequest(db, "unknown")
+	require.Error(t, err)
+
+	require.Error(t, request.Decide(db, RequestStatusPending, "carol", "", time.Now()))
+	require.NoError(t, request.Decide(db, RequestStatusApproved, "carol", "ok for this week", time.Now()))
+
+	request, err = GetRequest(db, accessRequest.UniqID)
+	require.NoError(t, err)
+	require.False(t, request.IsPending())
+	require.Equal(t, "carol", request.DecidedBy)
+
+	requests, err = GetRequests(db, "developers", RequestStatusPending)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+
+	requests, err = GetRequests(db, "developers", "")
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+}
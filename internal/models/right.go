@@ -9,6 +9,7 @@ const (
 	GroupACLKeeper
 	GroupGateKeeper
 	GroupOwner
+	Auditor
 	SBOwner
 	Private
 )
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// IndexedSession describes a session whose recording was indexed for the sessions search
+type IndexedSession struct {
+	SessionID string    `gorm:"PRIMARY_KEY"`            // The session ID (corresponding to the ttyrec filename)
+	Account   string    `gorm:"type:varchar(50);index"` // The local account the session belongs to
+	StartDate time.Time `gorm:"type:datetime;index"`    // Session start time
+	IndexedAt time.Time `gorm:"type:datetime"`          // When the recording was indexed
+	Lines     int       `gorm:"type:integer"`           // The number of indexed lines
+}
+
+// SessionLine is a line of text displayed during a session
+type SessionLine struct {
+	ID        uint   `gorm:"primaryKey"`
+	SessionID string `gorm:"type:varchar(36);index"` // The session the line was displayed in
+	Elapsed   int64  `gorm:"type:integer"`           // When the line was displayed, in milliseconds since the session start
+	Text      string `gorm:"type:text"`              // The text of the line, without the terminal escape sequences
+}
+
+// GetSessionsIndexGormDB returns a DB handler
+func GetSessionsIndexGormDB(database string) (db *gorm.DB, err error) {
+
+	// We open the DB
+	db, err = gorm.Open(sqlite.Open(database), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to connect to sessions index database %s", database)
+		return
+	}
+
+	// Migrate the schema (this will create table or alter table if needed)
+	db.AutoMigrate(&IndexedSession{}, &SessionLine{})
+
+	return
+}
+
+// IndexSession replaces the indexed lines of a session
+func IndexSession(db *gorm.DB, session *IndexedSession, lines []*SessionLine) (err error) {
+
+	session.IndexedAt = time.Now()
+	session.Lines = len(lines)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+
+		err := tx.Where("session_id = ?", session.SessionID).Delete(&SessionLine{}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			line.SessionID = session.SessionID
+		}
+		if len(lines) > 0 {
+			err = tx.CreateInBatches(lines, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Save(session).Error
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to index session %s", session.SessionID)
+	}
+
+	return
+}
+
+// GetIndexedSessions returns the sessions of the list that are indexed, by ID
+func GetIndexedSessions(db *gorm.DB, sessionIDs []string) (indexed map[string]*IndexedSession, err error) {
+
+	indexed = make(map[string]*IndexedSession)
+
+	// SQLite limits the number of variables of a query
+	for start := 0; start < len(sessionIDs); start += 500 {
+		end := start + 500
+		if end > len(sessionIDs) {
+			end = len(sessionIDs)
+		}
+
+		var sessions []*IndexedSession
+		err = db.Where("session_id IN ?", sessionIDs[start:end]).Find(&sessions).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get the indexed sessions")
+		}
+		for _, session := range sessions {
+			indexed[session.SessionID] = session
+		}
+	}
+
+	return
+}
+
+// GetSessionLines returns the indexed lines of a session, in the order they were displayed
+func GetSessionLines(db *gorm.DB, sessionID string) (lines []*SessionLine, err error) {
+
+	err = db.Where("session_id = ?", sessionID).Order("elapsed, id").Find(&lines).Error
+	if err != nil {
+		err = errors.Wrapf(err, "unable to get the indexed lines of session %s", sessionID)
+	}
+
+	return
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionsIndex(t *testing.T) {
+
+	db, err := GetSessionsIndexGormDB(filepath.Join(t.TempDir(), "sessions-index.db"))
+	require.NoError(t, err)
+
+	session := &IndexedSession{SessionID: "session-1", Account: "alice", StartDate: time.Now()}
+	require.NoError(t, IndexSession(db, session, []*SessionLine{
+		{Elapsed: 2000, Text: "rm -rf /tmp/build"},
+		{Elapsed: 1000, Text: "cd /tmp"},
+	}))
+	require.NoError(t, IndexSession(db, &IndexedSession{SessionID: "session-2", Account: "bob"}, nil))
+
+	indexed, err := GetIndexedSessions(db, []string{"session-1", "session-2", "session-3"})
+	require.NoError(t, err)
+	require.Len(t, indexed, 2)
+	require.Equal(t, 2, indexed["session-1"].Lines)
+	require.Equal(t, 0, indexed["session-2"].Lines)
+
+	lines, err := GetSessionLines(db, "session-1")
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	require.Equal(t, "cd /tmp", lines[0].Text)
+	require.Equal(t, "rm -rf /tmp/build", lines[1].Text)
+
+	// Indexing a session again replaces its lines
+	require.NoError(t, IndexSession(db, session, []*SessionLine{{Elapsed: 0, Text: "exit"}}))
+	lines, err = GetSessionLines(db, "session-1")
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	require.Equal(t, "exit", lines[0].Text)
+}
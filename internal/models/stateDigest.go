@@ -0,0 +1,273 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/config"
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// StateDigest summarizes the replicated state of an instance: the hash of the state of each item
+// (an account, its accesses, its keys, its TOTP, a group, its members, ...), by item
+type StateDigest map[string]string
+
+// The ways an item can differ between two instances
+const (
+	DifferenceMissing   = "missing"   // The item is on the peer only
+	DifferenceUnknown   = "unknown"   // The item is on this instance only
+	DifferenceDifferent = "different" // The item is on both instances, with a different state
+)
+
+// StateDifference describes an item whose state differs between two instances
+type StateDifference struct {
+	Item       string `json:"item" yaml:"item"`
+	Difference string `json:"difference" yaml:"difference"`
+}
+
+// StateVerification is the last comparison of the state of this instance with the state of a peer
+type StateVerification struct {
+	Peer        string    `gorm:"PRIMARY_KEY"`
+	Round       string    `gorm:"type:varchar(36)"` // The verification round the digest of the peer was sent for
+	ComparedAt  time.Time `gorm:"type:datetime"`
+	Items       int       `gorm:"type:integer"` // The number of items compared
+	Differences string    `gorm:"type:text"`    // The JSON list of the differences
+}
+
+// ComputeStateDigest computes the digest of the replicated state of this instance
+func ComputeStateDigest() (digest StateDigest, err error) {
+
+	digest = make(StateDigest)
+
+	users, err := GetAllSBUsers()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list the accounts")
+	}
+
+	for _, username := range users {
+		user, errLoad := LoadUser(username)
+		if errLoad != nil {
+			return nil, errors.Wrapf(errLoad, "unable to load account %s", username)
+		}
+		if err = digestUser(digest, user); err != nil {
+			return nil, errors.Wrapf(err, "unable to digest account %s", username)
+		}
+	}
+
+	groups, err := GetAllSBGroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list the groups")
+	}
+
+	for name, group := range groups {
+		if err = digestGroup(digest, group); err != nil {
+			return nil, errors.Wrapf(err, "unable to digest group %s", name)
+		}
+	}
+
+	return
+}
+
+func digestUser(digest StateDigest, user *User) (err error) {
+
+	item := fmt.Sprintf("account %s", user.User.Username)
+	digest[item] = hashLines(nil)
+
+	accesses, err := user.GetSelfAccesses()
+	if err != nil {
+		return
+	}
+	digest[item+" accesses"] = hashAccesses(accesses.Accesses)
+
+	for _, keyType := range []string{"ingress", "egress"} {
+		keys, errKeys := user.listPubKeys(keyType)
+		if errKeys != nil && !os.IsNotExist(errKeys) {
+			return errKeys
+		}
+		digest[fmt.Sprintf("%s %s keys", item, keyType)] = hashKeys(keys)
+	}
+
+	enabled, secret, emergencyPasswords := user.GetTOTP()
+	totp := []string{fmt.Sprintf("enabled=%t", enabled)}
+	if enabled {
+		totp = append(totp, secret)
+		totp = append(totp, emergencyPasswords...)
+	}
+	digest[item+" totp"] = hashLines(totp)
+
+	return
+}
+
+func digestGroup(digest StateDigest, group *Group) (err error) {
+
+	item := fmt.Sprintf("group %s", group.Name)
+	digest[item] = hashLines(nil)
+
+	members := make([]string, 0)
+	for _, memberType := range []string{"owner", "gate-keeper", "acl-keeper", "member"} {
+		accounts, errMembers := group.GetMembers(memberType)
+		if errMembers != nil {
+			return errMembers
+		}
+		for _, account := range accounts {
+			if account != "" {
+				members = append(members, fmt.Sprintf("%s=%s", memberType, account))
+			}
+		}
+	}
+	digest[item+" members"] = hashLines(members)
+
+	accesses, err := group.GetAccesses()
+	if err != nil {
+		return
+	}
+	digest[item+" accesses"] = hashAccesses(accesses.Accesses)
+
+	keys, err := group.listPubKeys("egress")
+	if err != nil {
+		return
+	}
+	digest[item+" egress keys"] = hashKeys(keys)
+
+	return
+}
+
+// Diff returns the items whose state differs from the digest of a peer, sorted
+func (d StateDigest) Diff(peer StateDigest) (differences []*StateDifference) {
+
+	differences = make([]*StateDifference, 0)
+
+	for item, hash := range d {
+		peerHash, ok := peer[item]
+		switch {
+		case !ok:
+			differences = append(differences, &StateDifference{Item: item, Difference: DifferenceUnknown})
+		case peerHash != hash:
+			differences = append(differences, &StateDifference{Item: item, Difference: DifferenceDifferent})
+		}
+	}
+	for item := range peer {
+		if _, ok := d[item]; !ok {
+			differences = append(differences, &StateDifference{Item: item, Difference: DifferenceMissing})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Item < differences[j].Item
+	})
+
+	return
+}
+
+// SaveStateVerification records the comparison of the state of this instance with the digest of a peer
+func SaveStateVerification(db *gorm.DB, peer, round string, local, peerDigest StateDigest) (verification *StateVerification, err error) {
+
+	differences, err := json.Marshal(local.Diff(peerDigest))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal the differences")
+	}
+
+	verification = &StateVerification{
+		Peer:        peer,
+		Round:       round,
+		ComparedAt:  time.Now(),
+		Items:       len(local),
+		Differences: string(differences),
+	}
+
+	err = db.Save(verification).Error
+	if err != nil {
+		err = errors.Wrapf(err, "unable to save the verification of peer %s", peer)
+	}
+
+	return
+}
+
+// PushStateDigest computes the digest of the state of this instance, and pushes it to the other instances,
+// which compare it with their own state
+func PushStateDigest(round string) (err error) {
+
+	digest, err := ComputeStateDigest()
+	if err != nil {
+		return
+	}
+
+	digestJSON, err := json.Marshal(digest)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the state digest")
+	}
+
+	repl, err := NewReplicationEntry("state digest", ReplicationData{
+		"round":  round,
+		"digest": string(digestJSON),
+	})
+	if err != nil {
+		return
+	}
+
+	dbHandler, err := GetReplicationGormDB(config.GetReplicationDatabasePath())
+	if err != nil {
+		return
+	}
+
+	return repl.Save(dbHandler)
+}
+
+// GetStateVerifications returns the last comparison with each peer, sorted by peer
+func GetStateVerifications(db *gorm.DB) (verifications []*StateVerification, err error) {
+
+	err = db.Order("peer").Find(&verifications).Error
+	if err != nil {
+		err = errors.Wrap(err, "unable to get the state verifications")
+	}
+
+	return
+}
+
+// GetDifferences returns the differences found by the verification
+func (v *StateVerification) GetDifferences() (differences []*StateDifference, err error) {
+	err = json.Unmarshal([]byte(v.Differences), &differences)
+	return
+}
+
+func hashAccesses(accesses []*Access) string {
+
+	lines := make([]string, 0, len(accesses))
+	for _, a := range accesses {
+		lines = append(lines, strings.Join([]string{
+			a.Host, a.Prefix, a.Alias, a.User, fmt.Sprint(a.Port), a.Selector,
+			a.ExpiresAt.UTC().Format(time.RFC3339), fmt.Sprint(a.ForwardingAllowed),
+		}, "|"))
+	}
+
+	return hashLines(lines)
+}
+
+func hashKeys(keys []helpers.PublicKey) string {
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key.String())
+	}
+
+	return hashLines(lines)
+}
+
+// hashLines hashes lines regardless of their order
+func hashLines(lines []string) string {
+
+	sorted := append([]string{}, lines...)
+	sort.Strings(sorted)
+
+	hash := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateDigest(t *testing.T) {
+
+	require.Equal(t, hashLines([]string{"a", "b"}), hashLines([]string{"b", "a"}))
+	require.NotEqual(t, hashLines([]string{"a", "b"}), hashLines([]string{"a"}))
+
+	local := StateDigest{
+		"account alice":          "1",
+		"account alice accesses": "2",
+		"group ops":              "3",
+	}
+	peer := StateDigest{
+		"account alice":          "1",
+		"account alice accesses": "4",
+		"account bob":            "5",
+	}
+
+	require.Empty(t, local.Diff(local))
+	require.Equal(t, []*StateDifference{
+		{Item: "account alice accesses", Difference: DifferenceDifferent},
+		{Item: "account bob", Difference: DifferenceMissing},
+		{Item: "group ops", Difference: DifferenceUnknown},
+	}, local.Diff(peer))
+
+	db, err := GetReplicationGormDB(filepath.Join(t.TempDir(), "replication.db"))
+	require.NoError(t, err)
+
+	_, err = SaveStateVerification(db, "sb2", "round-1", local, peer)
+	require.NoError(t, err)
+	_, err = SaveStateVerification(db, "sb2", "round-2", local, local)
+	require.NoError(t, err)
+	_, err = SaveStateVerification(db, "sb1", "round-2", local, peer)
+	require.NoError(t, err)
+
+	// Only the last comparison with each peer is kept
+	verifications, err := GetStateVerifications(db)
+	require.NoError(t, err)
+	require.Len(t, verifications, 2)
+	require.Equal(t, "sb1", verifications[0].Peer)
+	require.Equal(t, "sb2", verifications[1].Peer)
+	require.Equal(t, "round-2", verifications[1].Round)
+	require.Equal(t, 3, verifications[1].Items)
+
+	differences, err := verifications[1].GetDifferences()
+	require.NoError(t, err)
+	require.Empty(t, differences)
+
+	differences, err = verifications[0].GetDifferences()
+	require.NoError(t, err)
+	require.Len(t, differences, 3)
+}
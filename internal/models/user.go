@@ -9,6 +9,7 @@ import (
 	osuser "os/user"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/inpher/sb/internal/config"
 	"github.com/inpher/sb/internal/helpers"
@@ -92,13 +93,15 @@ func LoadUser(username string) (bu *User, err error) {
 }
 
 // AddAccess adds an access to the group
-func (bu *User) AddAccess(host, user, port, alias, comment string, db ...*gorm.DB) (ba *Access, err error) {
+func (bu *User) AddAccess(host, user, port, alias, comment string, expiresAt time.Time, forwardingAllowed bool, db ...*gorm.DB) (ba *Access, err error) {
 	ba, err = BuildSBAccess(host, user, port, alias, true)
 	if err != nil {
 		return
 	}
 
 	ba.Comment = comment
+	ba.ExpiresAt = expiresAt.UTC()
+	ba.ForwardingAllowed = forwardingAllowed
 
 	var dbHandler *gorm.DB
 	if len(db) > 0 {
@@ -447,15 +450,32 @@ func (bu *User) HasAccess(ba *Access, db ...*gorm.DB) (accessInfo *Info, err err
 		Accesses:      make([]*Access, 0),
 	}
 
+	// The labels of the host in the inventory, only loaded if an access has a label selector
+	var hostLabels map[string]string
+
 	for _, userAccessesByKeyPairs := range userAccesses {
 
 		found := false
 
 		for _, a := range userAccessesByKeyPairs.Accesses {
 
-			// If ba object doesn't have a net.IP key, we do a host comparaison
-			if ba.IP == nil {
+			if a.Selector != "" {
+
+				if hostLabels == nil {
+					hostLabels, err = GetInventoryLabels(ba.Host)
+					if err != nil {
+						return
+					}
+				}
+
+				// The host must carry all the labels of the selector
+				if !MatchesSelector(a.Selector, hostLabels) {
+					continue
+				}
+
+			} else if ba.IP == nil {
 
+				// If ba object doesn't have a net.IP key, we do a host comparaison
 				// If host or alias doesn't match (or alias is empty), we don't go further
 				if a.Host != ba.Host && a.Alias != ba.Host {
 					continue
@@ -486,6 +506,11 @@ func (bu *User) HasAccess(ba *Access, db ...*gorm.DB) (accessInfo *Info, err err
 				continue
 			}
 
+			// Expired accesses are kept until the daemon purges them, but they don't grant anything
+			if a.IsExpired() {
+				continue
+			}
+
 			// Hey! We have a match!
 			found = true
 			accessInfo.Accesses = append(accessInfo.Accesses, a)
@@ -515,6 +540,11 @@ func (bu *User) IsACLKeeperOfGroup(groupName string) bool {
 	return true
 }
 
+// IsAuditor checks if the user can audit the other accounts' sessions (members of the auditors group and sb owners)
+func (bu *User) IsAuditor() bool {
+	return bu.IsMemberOfGroup("auditors") || bu.IsOwnerOfGroup("owners")
+}
+
 // IsGateKeeperOfGroup checks if the user is member of the group passed as parameter
 func (bu *User) IsGateKeeperOfGroup(groupName string) bool {
 	group, ok := bu.Groups[groupName]
@@ -554,6 +584,22 @@ func (bu *User) OverrideDatabaseAccessFilePath(path string) error {
 	return nil
 }
 
+// PurgeExpiredAccesses deletes the expired accesses from the personal access database
+func (bu *User) PurgeExpiredAccesses(db ...*gorm.DB) (accesses []*Access, err error) {
+
+	var dbHandler *gorm.DB
+	if len(db) > 0 {
+		dbHandler = db[0]
+	} else {
+		dbHandler, err = GetAccessGormDB(bu.getDatabaseAccessFilePath())
+		if err != nil {
+			return
+		}
+	}
+
+	return DeleteExpiredAccesses(dbHandler)
+}
+
 // RemoveTOTPSecret disables TOTP on the account
 func (bu *User) RemoveTOTPSecret() (err error) {
 	return os.Remove(bu.GetTOTPFilepath())
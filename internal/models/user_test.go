@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/inpher/sb/internal/helpers"
 
@@ -264,13 +265,16 @@ func TestLastSSHSessions(t *testing.T) {
 	_, filename, _, _ := runtime.Caller(0)
 	homeDir := filepath.Dir(filename)
 
+	// The logs database is migrated when opened: the test works on a copy
+	logsDatabase := copyTestAsset(t, fmt.Sprintf("%s/test_assets/user/logs.db", homeDir))
+
 	user := &User{
 		User: &osuser.User{
 			Uid:      "1000",
 			Gid:      "1000",
 			Username: "testuser",
 			Name:     "Test User",
-			HomeDir:  fmt.Sprintf("%s/test_assets/user", homeDir),
+			HomeDir:  filepath.Dir(logsDatabase),
 		},
 		Groups: map[string]*Group{},
 	}
@@ -302,10 +306,10 @@ func TestUserAccesses(t *testing.T) {
 	// Add with classic DB
 	user.OverrideDatabaseAccessFilePath(":memory:")
 
-	_, err := user.AddAccess("test.com", "root", "22", "test", "Added for tests")
+	_, err := user.AddAccess("test.com", "root", "22", "test", "Added for tests", time.Time{}, false)
 	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
 
-	_, err = user.AddAccess("NOT_A_VALID_HOST", "root", "22", "test", "Added for tests")
+	_, err = user.AddAccess("NOT_A_VALID_HOST", "root", "22", "test", "Added for tests", time.Time{}, false)
 	require.Error(t, err, fmt.Errorf("host is neither an IP, a prefix or a resolvable host"), "An error should have occurred when calling AddAccess")
 
 	accesses, err := user.GetAccesses()
@@ -319,7 +323,7 @@ func TestUserAccesses(t *testing.T) {
 	db, err := GetAccessGormDB(":memory:")
 	require.NoError(t, err, "An unexpected error occurred when getting the database handler")
 
-	ba, err := user.AddAccess("test.com", "root", "22", "test", "Added for tests", db)
+	ba, err := user.AddAccess("test.com", "root", "22", "test", "Added for tests", time.Time{}, false, db)
 	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
 	// We reset the data we don't store in database for comparaison later
 	ba.IP = nil
@@ -366,13 +370,13 @@ func TestHasAccesses(t *testing.T) {
 	db, err := GetAccessGormDB(":memory:")
 	require.NoError(t, err, "An unexpected error occurred when getting the database handler")
 
-	ba, err := user.AddAccess("test.com", "root", "22", "test", "Added for tests", db)
+	ba, err := user.AddAccess("test.com", "root", "22", "test", "Added for tests", time.Time{}, false, db)
 	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
 
-	baCf, err := user.AddAccess("one.one.one.one", "root", "22", "one", "Added for tests", db)
+	baCf, err := user.AddAccess("one.one.one.one", "root", "22", "one", "Added for tests", time.Time{}, false, db)
 	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
 
-	baCf22022, err := user.AddAccess("one.one.one.one", "test", "22022", "cf", "Added for tests", db)
+	baCf22022, err := user.AddAccess("one.one.one.one", "test", "22022", "cf", "Added for tests", time.Time{}, false, db)
 	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
 
 	unauthorizedAccessHost, _ := BuildSBAccess("meow.com", "test", "22022", "", false)
@@ -417,6 +421,90 @@ func TestHasAccesses(t *testing.T) {
 	require.NoError(t, err, "An unexpected error occurred when calling DeleteAccess")
 }
 
+func TestHasAccessesExpired(t *testing.T) {
+
+	// Guess the working directory
+	_, filename, _, _ := runtime.Caller(0)
+	homeDir := filepath.Dir(filename)
+
+	user := &User{
+		User: &osuser.User{
+			Uid:      "1000",
+			Gid:      "1000",
+			Username: "testuser",
+			Name:     "Test User",
+			HomeDir:  fmt.Sprintf("%s/test_assets/user", homeDir),
+		},
+		Groups: map[string]*Group{},
+	}
+
+	// Working with a gorm.DB handler
+	db, err := GetAccessGormDB(":memory:")
+	require.NoError(t, err, "An unexpected error occurred when getting the database handler")
+
+	baValid, err := user.AddAccess("127.0.0.1", "root", "22", "", "Added for tests", time.Now().Add(time.Hour), false, db)
+	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
+
+	baExpired, err := user.AddAccess("127.0.0.2", "root", "22", "", "Added for tests", time.Now().Add(-time.Hour), false, db)
+	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
+
+	accessInfo, err := user.HasAccess(baValid, db)
+	require.NoError(t, err, "An unexpected error occurred when calling HasAccess")
+	require.Equal(t, true, accessInfo.Authorized, "Access should be granted until it expires")
+
+	accessInfo, err = user.HasAccess(baExpired, db)
+	require.NoError(t, err, "An unexpected error occurred when calling HasAccess")
+	require.Equal(t, false, accessInfo.Authorized, "Access should not be granted once expired")
+
+	purged, err := user.PurgeExpiredAccesses(db)
+	require.NoError(t, err, "An unexpected error occurred when calling PurgeExpiredAccesses")
+	require.Equal(t, 1, len(purged), "Only the expired access should have been purged")
+	require.Equal(t, baExpired.Prefix, purged[0].Prefix, "The wrong access was purged")
+
+	accesses, err := user.GetAccesses(db)
+	require.NoError(t, err, "An unexpected error occurred when calling GetAccesses")
+	require.Equal(t, 1, len(accesses[0].Accesses), "There should only be one access left")
+	require.Equal(t, baValid.Prefix, accesses[0].Accesses[0].Prefix, "The valid access should have been kept")
+}
+
+func TestHasAccessesForwarding(t *testing.T) {
+
+	// Guess the working directory
+	_, filename, _, _ := runtime.Caller(0)
+	homeDir := filepath.Dir(filename)
+
+	user := &User{
+		User: &osuser.User{
+			Uid:      "1000",
+			Gid:      "1000",
+			Username: "testuser",
+			Name:     "Test User",
+			HomeDir:  fmt.Sprintf("%s/test_assets/user", homeDir),
+		},
+		Groups: map[string]*Group{},
+	}
+
+	// Working with a gorm.DB handler
+	db, err := GetAccessGormDB(":memory:")
+	require.NoError(t, err, "An unexpected error occurred when getting the database handler")
+
+	baForwarding, err := user.AddAccess("127.0.0.1", "root", "22", "", "Added for tests", time.Time{}, true, db)
+	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
+
+	baShell, err := user.AddAccess("127.0.0.2", "root", "22", "", "Added for tests", time.Time{}, false, db)
+	require.NoError(t, err, "An unexpected error occurred when calling AddAccess")
+
+	accessInfo, err := user.HasAccess(baForwarding, db)
+	require.NoError(t, err, "An unexpected error occurred when calling HasAccess")
+	require.Equal(t, 1, len(accessInfo.Accesses), "Access should be granted")
+	require.Equal(t, true, accessInfo.Accesses[0].ForwardingAllowed, "Access should allow port forwarding")
+
+	accessInfo, err = user.HasAccess(baShell, db)
+	require.NoError(t, err, "An unexpected error occurred when calling HasAccess")
+	require.Equal(t, 1, len(accessInfo.Accesses), "Access should be granted")
+	require.Equal(t, false, accessInfo.Accesses[0].ForwardingAllowed, "Access should not allow port forwarding")
+}
+
 func TestGetSSHKeyPairsInvalidPath(t *testing.T) {
 
 	user := &User{
@@ -0,0 +1,144 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/inpher/sb/internal/types"
+	"github.com/pkg/errors"
+)
+
+// The events that are not commands
+const (
+	EventDenied       = "denied"        // A command or a connection was denied by sb
+	EventSessionStart = "session start" // An SSH session to a distant host starts
+)
+
+// Events lists the events the webhooks can subscribe to: the security-relevant commands, once they succeeded,
+// and the events above
+var Events = []string{
+	"account create", "account delete",
+	"group create", "group delete",
+	"group owner add", "group owner remove",
+	"group gate-keeper add", "group gate-keeper remove",
+	"group acl-keeper add", "group acl-keeper remove",
+	"group member add", "group member remove",
+	"group access add", "group access remove",
+	"group request approve",
+	"group egress-key generate",
+	"self access add", "self access remove",
+	"self ingress-key add", "self ingress-key delete",
+	"self egress-key generate",
+	"self totp enable", "self totp disable", "self totp emergency-codes generate",
+	"host add", "host remove", "host import",
+	"admin session kill",
+	EventDenied, EventSessionStart,
+}
+
+// SignatureHeader is the header of the HMAC-SHA256 signature of the body, when the webhook has a secret
+const SignatureHeader = "X-SB-Signature"
+
+const (
+	requestTimeout = 10 * time.Second
+
+	// The delays between the attempts double, from firstRetryDelay to maxRetryDelay
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = time.Hour
+)
+
+// IsNotifiable returns whether the webhooks can subscribe to an event
+func IsNotifiable(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSubscribed returns whether a webhook subscribed to an event ("*" subscribes to all of them)
+func IsSubscribed(webhook *types.WebhookConfig, event string) bool {
+	for _, e := range webhook.Events {
+		if e == event || (e == "*" && IsNotifiable(event)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryDelay returns the delay before the next attempt to deliver a notification, after the failed ones
+func RetryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// Send delivers an event to a webhook
+func Send(webhook *types.WebhookConfig, event *types.NotificationEvent) (err error) {
+
+	body, err := buildBody(webhook.Format, event)
+	if err != nil {
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "invalid webhook %s", webhook.Name)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-SB-Event", event.Event)
+	if webhook.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "unable to reach webhook %s", webhook.Name)
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", webhook.Name, response.Status)
+	}
+
+	return
+}
+
+// Sign returns the signature of a body, as sent in the SignatureHeader header
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// buildBody returns the body of the request for the format of the webhook: the event itself, or a Slack message
+func buildBody(format string, event *types.NotificationEvent) (body []byte, err error) {
+
+	switch format {
+	case "", "json":
+		body, err = json.Marshal(event)
+	case "slack":
+		body, err = json.Marshal(map[string]string{"text": event.Text})
+	default:
+		return nil, fmt.Errorf("webhook format %s is not implemented", format)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "unable to marshal notification")
+	}
+
+	return
+}
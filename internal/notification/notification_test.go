@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/inpher/sb/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSubscribed(t *testing.T) {
+
+	webhook := &types.WebhookConfig{Events: []string{"group owner add", EventDenied}}
+	require.True(t, IsSubscribed(webhook, "group owner add"))
+	require.True(t, IsSubscribed(webhook, EventDenied))
+	require.False(t, IsSubscribed(webhook, "group owner remove"))
+
+	webhook.Events = []string{"*"}
+	require.True(t, IsSubscribed(webhook, "self totp disable"))
+	require.False(t, IsSubscribed(webhook, "self accesses list"))
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, 30*time.Second, RetryDelay(1))
+	require.Equal(t, 2*time.Minute, RetryDelay(3))
+	require.Equal(t, time.Hour, RetryDelay(20))
+}
+
+func TestSend(t *testing.T) {
+
+	var body []byte
+	var headers http.Header
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	event := &types.NotificationEvent{
+		Event:   "group owner add",
+		Date:    time.Date(2023, 4, 5, 23, 30, 0, 0, time.UTC),
+		Account: "t1000",
+		Text:    "t1000 ran group owner add --account john --group owners on sb-host1",
+	}
+
+	webhook := &types.WebhookConfig{Name: "siem", URL: server.URL, Secret: "changeme"}
+	require.NoError(t, Send(webhook, event))
+	require.Equal(t, `{"event":"group owner add","date":"2023-04-05T23:30:00Z","instance":"","id":"","account":"t1000","text":"t1000 ran group owner add --account john --group owners on sb-host1"}`, string(body))
+	require.Equal(t, Sign("changeme", body), headers.Get(SignatureHeader))
+	require.Equal(t, "group owner add", headers.Get("X-SB-Event"))
+
+	webhook = &types.WebhookConfig{Name: "slack", URL: server.URL, Format: "slack"}
+	require.NoError(t, Send(webhook, event))
+	require.Equal(t, `{"text":"t1000 ran group owner add --account john --group owners on sb-host1"}`, string(body))
+	require.Empty(t, headers.Get(SignatureHeader))
+
+	status = http.StatusInternalServerError
+	require.Error(t, Send(webhook, event))
+}
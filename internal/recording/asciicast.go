@@ -0,0 +1,169 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"maze.io/x/ttyrec"
+)
+
+// The terminal size of the recordings made before it was logged
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// AsciicastHeader is the first line of an asciicast v2 file (https://docs.asciinema.org/manual/asciicast/v2/)
+type AsciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// WriteAsciicast converts the output and the input (which may be nil) recordings of a session to an asciicast v2 file.
+// The input frames become "i" events, as recorded: masked secrets included.
+func WriteAsciicast(w io.Writer, header AsciicastHeader, output, input io.Reader) (err error) {
+
+	header.Version = 2
+	if header.Width <= 0 || header.Height <= 0 {
+		header.Width, header.Height = DefaultWidth, DefaultHeight
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode the asciicast header")
+	}
+	if _, err = fmt.Fprintf(w, "%s\n", headerJSON); err != nil {
+		return errors.Wrap(err, "unable to write the asciicast")
+	}
+
+	// A multi-byte character might be split between frames: the incomplete end of a frame waits for the next one
+	var start *ttyrec.TimeVal
+	pending := map[bool][]byte{}
+
+	return walkFrames(output, input, func(frame *ttyrec.Frame, isInput bool) error {
+
+		if start == nil {
+			start = &frame.Time
+		}
+
+		data := append(pending[isInput], frame.Data...)
+		data, pending[isInput] = splitIncompleteUTF8(data)
+		if len(data) == 0 {
+			return nil
+		}
+
+		eventType := "o"
+		if isInput {
+			eventType = "i"
+		}
+
+		event, err := json.Marshal([]interface{}{frame.Time.Sub(*start).Seconds(), eventType, string(data)})
+		if err != nil {
+			return errors.Wrap(err, "unable to encode an asciicast event")
+		}
+		if _, err = fmt.Fprintf(w, "%s\n", event); err != nil {
+			return errors.Wrap(err, "unable to write the asciicast")
+		}
+
+		return nil
+	})
+}
+
+// splitIncompleteUTF8 splits the trailing incomplete UTF-8 sequence of p, if any, from the complete part
+func splitIncompleteUTF8(p []byte) (complete, rest []byte) {
+
+	// A UTF-8 sequence is at most 4 bytes long: only the last 3 bytes can start an incomplete one
+	for i := len(p) - 1; i >= 0 && i >= len(p)-3; i-- {
+		if !utf8.RuneStart(p[i]) {
+			continue
+		}
+		if !utf8.FullRune(p[i:]) {
+			return p[:i], append([]byte(nil), p[i:]...)
+		}
+		break
+	}
+
+	return p, nil
+}
+
+// ReadAsciicastHeader reads and checks the header of an asciicast v2 file
+func ReadAsciicastHeader(r *bufio.Reader) (header *AsciicastHeader, err error) {
+
+	line, err := r.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "unable to read the asciicast header")
+	}
+
+	header = new(AsciicastHeader)
+	if json.Unmarshal(line, header) != nil || header.Version != 2 {
+		return nil, fmt.Errorf("not an asciicast v2 file")
+	}
+
+	return header, nil
+}
+
+// ConvertAsciicast converts an asciicast v2 file to a ttyrec recording, so that it can be replayed like the sessions.
+// The "i" events are only kept if showInput is set, rendered like the merged input recordings.
+func ConvertAsciicast(r io.Reader, w io.Writer, showInput bool) (err error) {
+
+	br := bufio.NewReader(r)
+	_, err = ReadAsciicastHeader(br)
+	if err != nil {
+		return
+	}
+
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 2; scanner.Scan(); line++ {
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event []interface{}
+		err = json.Unmarshal(scanner.Bytes(), &event)
+		if err != nil || len(event) != 3 {
+			return fmt.Errorf("invalid asciicast event on line %d", line)
+		}
+		seconds, okTime := event[0].(float64)
+		eventType, okType := event[1].(string)
+		data, okData := event[2].(string)
+		if !okTime || !okType || !okData {
+			return fmt.Errorf("invalid asciicast event on line %d", line)
+		}
+
+		var frameData []byte
+		switch {
+		case eventType == "o":
+			frameData = []byte(data)
+		case eventType == "i" && showInput:
+			frameData = RenderInput([]byte(data))
+		default:
+			// The resizes and markers have no ttyrec equivalent
+			continue
+		}
+
+		header := ttyrec.Header{Len: uint32(len(frameData))}
+		header.Time.Set(time.Duration(seconds * float64(time.Second)))
+		if _, err = header.WriteTo(w); err != nil {
+			return errors.Wrap(err, "unable to write the recording")
+		}
+		if _, err = w.Write(frameData); err != nil {
+			return errors.Wrap(err, "unable to write the recording")
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return errors.Wrap(err, "unable to read the asciicast")
+	}
+
+	return
+}
@@ -0,0 +1,53 @@
+package recording
+
+import (
+	"io"
+	"time"
+
+	"maze.io/x/ttyrec"
+)
+
+// Encoder writes ttyrec frames stamped with the wall clock, like the original ttyrec does (the encoder of
+// maze.io/x/ttyrec starts its clock on the first frame): the streams of a same session stay aligned.
+type Encoder struct {
+	w   io.Writer
+	now func() time.Time
+}
+
+// NewEncoder returns an Encoder writing its frames to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:   w,
+		now: time.Now,
+	}
+}
+
+// Write writes p as a single frame
+func (e *Encoder) Write(p []byte) (int, error) {
+	return e.WriteAt(p, e.now())
+}
+
+// WriteAt writes p as a single frame stamped with t, for the frames recorded after the time they belong to
+func (e *Encoder) WriteAt(p []byte, t time.Time) (int, error) {
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	header := ttyrec.Header{
+		Time: timeVal(t),
+		Len:  uint32(len(p)),
+	}
+	if _, err := header.WriteTo(e.w); err != nil {
+		return 0, err
+	}
+
+	return e.w.Write(p)
+}
+
+func timeVal(t time.Time) ttyrec.TimeVal {
+	return ttyrec.TimeVal{
+		Seconds:      int32(t.Unix()),
+		MicroSeconds: int32(t.Nanosecond() / 1000),
+	}
+}
@@ -0,0 +1,68 @@
+package recording
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// headerSize is the size of a ttyrec frame header: seconds, microseconds and length, as little-endian uint32
+const headerSize = 12
+
+// Follow writes the frames of a recording still being written to w, as they are appended to it.
+// The frames already recorded are written at once. When no complete frame is available, Follow
+// returns if stop returns true, and polls the recording again after poll otherwise.
+func Follow(r io.ReadSeeker, w io.Writer, stop func() bool, poll time.Duration) (err error) {
+
+	offset, err := r.Seek(0, io.SeekStart)
+	if err != nil {
+		return errors.Wrap(err, "unable to seek in the recording")
+	}
+
+	header := make([]byte, headerSize)
+	for {
+
+		frame, errFrame := readFrameData(r, header)
+		if errFrame == io.EOF {
+			// The frame is not fully written yet: it will be read again from its start
+			if _, err = r.Seek(offset, io.SeekStart); err != nil {
+				return errors.Wrap(err, "unable to seek in the recording")
+			}
+			if stop() {
+				return nil
+			}
+			time.Sleep(poll)
+			continue
+		}
+		if errFrame != nil {
+			return errFrame
+		}
+
+		if _, err = w.Write(frame); err != nil {
+			return errors.Wrap(err, "error writing frame")
+		}
+		offset += int64(headerSize + len(frame))
+	}
+}
+
+// readFrameData reads the next frame of the recording, returning its data.
+// It returns io.EOF if the frame is missing or incomplete.
+func readFrameData(r io.Reader, header []byte) (data []byte, err error) {
+
+	_, err = io.ReadFull(r, header)
+	if err == nil {
+		data = make([]byte, binary.LittleEndian.Uint32(header[8:]))
+		_, err = io.ReadFull(r, data)
+	}
+
+	switch err {
+	case nil:
+		return data, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		return nil, io.EOF
+	default:
+		return nil, errors.Wrap(err, "unable to read the recording")
+	}
+}
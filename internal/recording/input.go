@@ -0,0 +1,178 @@
+package recording
+
+import (
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// maxPendingKeystrokes is how many keystrokes may wait for their echo: beyond, the oldest ones are masked
+const maxPendingKeystrokes = 4096
+
+// MaskByte replaces the masked keystrokes in the input recordings
+const MaskByte = '*'
+
+// escapeSequence matches the ANSI escape sequences, which don't show on the terminal
+var escapeSequence = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|[()][0-9A-Za-z]|[=>78DEHMNOZc])`)
+
+// The states of a recorded keystroke
+const (
+	keystrokePending = iota // Waiting for its echo
+	keystrokeClear          // Echoed by the distant terminal, or not a character: recorded as is
+	keystrokeMasked         // Not echoed: recorded as MaskByte
+)
+
+// keystroke is a byte typed by the user, waiting to be recorded
+type keystroke struct {
+	b     byte
+	frame int // The keystrokes written at once are recorded in the same frame
+	time  time.Time
+	state int
+}
+
+// InputRecorder records the keystrokes of a session in a ttyrec stream of its own, aligned on the clock of the output
+// recording. The distant terminal doesn't tell whether its echo is disabled: the output is observed instead, and the
+// characters it doesn't echo (a password, a passphrase...) are masked. So the keystrokes are only recorded once
+// their echo is received, or once it's clear they won't be echoed: a line ends, or something else is displayed.
+type InputRecorder struct {
+	mu         sync.Mutex
+	encoder    *Encoder
+	keystrokes []*keystroke
+	frames     int
+}
+
+// NewInputRecorder returns an InputRecorder writing its frames to w
+func NewInputRecorder(w io.Writer) *InputRecorder {
+	return &InputRecorder{
+		encoder: NewEncoder(w),
+	}
+}
+
+// Write records the keystrokes of p, masked if they are not echoed.
+// The recording is best effort: it never fails the session.
+func (r *InputRecorder) Write(p []byte) (int, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The keys sending escape sequences (arrows, function keys...) are not characters the terminal echoes
+	escapes := make([]bool, len(p))
+	for _, sequence := range escapeSequence.FindAllIndex(p, -1) {
+		for i := sequence[0]; i < sequence[1]; i++ {
+			escapes[i] = true
+		}
+	}
+
+	now := r.encoder.now()
+	for i, b := range p {
+		k := &keystroke{b: b, frame: r.frames, time: now, state: keystrokeClear}
+		switch {
+		case b == '\r' || b == '\n' || b == 0x03 || b == 0x04:
+			// The line ends, or is cancelled: what wasn't echoed until then won't be
+			r.maskPending()
+		case b >= 0x20 && b != 0x7f && !escapes[i]:
+			k.state = keystrokePending
+		}
+		r.keystrokes = append(r.keystrokes, k)
+	}
+	r.frames++
+
+	if len(r.keystrokes) > maxPendingKeystrokes {
+		r.maskPending()
+	}
+	r.flush()
+
+	return len(p), nil
+}
+
+// Output returns a writer the output of the session is copied to, so that the echo of the keystrokes is detected
+func (r *InputRecorder) Output() io.Writer {
+	return outputObserver{r}
+}
+
+// Close records the keystrokes still waiting for their echo, masked
+func (r *InputRecorder) Close() error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maskPending()
+	r.flush()
+
+	return nil
+}
+
+// echo matches the characters displayed by the distant terminal with the keystrokes waiting for their echo.
+// Anything else displayed means the keystrokes waiting weren't echoed.
+func (r *InputRecorder) echo(p []byte) {
+
+	next := 0
+	for _, c := range escapeSequence.ReplaceAll(p, nil) {
+		if c < 0x20 || c == 0x7f {
+			continue
+		}
+		for next < len(r.keystrokes) && r.keystrokes[next].state != keystrokePending {
+			next++
+		}
+		if next == len(r.keystrokes) {
+			return
+		}
+		if r.keystrokes[next].b != c {
+			r.maskPending()
+			return
+		}
+		r.keystrokes[next].state = keystrokeClear
+	}
+}
+
+// maskPending masks the keystrokes waiting for their echo
+func (r *InputRecorder) maskPending() {
+	for _, k := range r.keystrokes {
+		if k.state == keystrokePending {
+			k.state = keystrokeMasked
+		}
+	}
+}
+
+// flush records the frames whose keystrokes are all decided, in order
+func (r *InputRecorder) flush() {
+
+	for len(r.keystrokes) > 0 {
+
+		frame := r.keystrokes[0].frame
+		end := 0
+		for end < len(r.keystrokes) && r.keystrokes[end].frame == frame {
+			if r.keystrokes[end].state == keystrokePending {
+				return
+			}
+			end++
+		}
+
+		data := make([]byte, end)
+		for i, k := range r.keystrokes[:end] {
+			data[i] = k.b
+			if k.state == keystrokeMasked {
+				data[i] = MaskByte
+			}
+		}
+		r.encoder.WriteAt(data, r.keystrokes[0].time)
+
+		r.keystrokes = r.keystrokes[end:]
+	}
+}
+
+type outputObserver struct {
+	r *InputRecorder
+}
+
+func (o outputObserver) Write(p []byte) (int, error) {
+
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+
+	o.r.echo(p)
+	o.r.flush()
+
+	return len(p), nil
+}
@@ -0,0 +1,108 @@
+package recording
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"maze.io/x/ttyrec"
+)
+
+// Merge writes a single ttyrec stream to w, where the frames of the input recording are inserted among the ones of
+// the output recording, in time order, and rendered in reverse video so that they stand out
+func Merge(output, input io.Reader, w io.Writer) (err error) {
+
+	return walkFrames(output, input, func(frame *ttyrec.Frame, isInput bool) (err error) {
+
+		data := frame.Data
+		if isInput {
+			data = RenderInput(data)
+		}
+
+		header := ttyrec.Header{Time: frame.Time, Len: uint32(len(data))}
+		if _, err = header.WriteTo(w); err != nil {
+			return errors.Wrap(err, "unable to write the merged recording")
+		}
+		if _, err = w.Write(data); err != nil {
+			return errors.Wrap(err, "unable to write the merged recording")
+		}
+
+		return
+	})
+}
+
+// walkFrames calls fn on the frames of the output and the input recordings (which may be nil), in time order
+func walkFrames(output, input io.Reader, fn func(frame *ttyrec.Frame, isInput bool) error) (err error) {
+
+	outputDecoder := ttyrec.NewDecoder(output)
+	outputFrame, err := nextFrame(outputDecoder)
+	if err != nil {
+		return
+	}
+
+	var inputDecoder *ttyrec.Decoder
+	var inputFrame *ttyrec.Frame
+	if input != nil {
+		inputDecoder = ttyrec.NewDecoder(input)
+		inputFrame, err = nextFrame(inputDecoder)
+		if err != nil {
+			return
+		}
+	}
+
+	for outputFrame != nil || inputFrame != nil {
+
+		if inputFrame == nil || (outputFrame != nil && outputFrame.Time.Sub(inputFrame.Time) <= 0) {
+			err = fn(outputFrame, false)
+			if err == nil {
+				outputFrame, err = nextFrame(outputDecoder)
+			}
+		} else {
+			err = fn(inputFrame, true)
+			if err == nil {
+				inputFrame, err = nextFrame(inputDecoder)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// nextFrame returns the next frame of the stream, or nil at its end (a session interrupted abruptly might
+// have left a truncated frame behind)
+func nextFrame(d *ttyrec.Decoder) (*ttyrec.Frame, error) {
+	frame, err := d.DecodeFrame()
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode the recording")
+	}
+	return frame, nil
+}
+
+// RenderInput returns the keystrokes as they should be displayed among the output: in reverse video, with the
+// control characters made visible
+func RenderInput(keystrokes []byte) []byte {
+
+	rendered := []byte("\x1b[7m")
+	for _, b := range keystrokes {
+		switch {
+		case b == '\r' || b == '\n':
+			rendered = append(rendered, "⏎"...)
+		case b == 0x7f:
+			rendered = append(rendered, "⌫"...)
+		case b == 0x1b:
+			rendered = append(rendered, "^["...)
+		case b < 0x20:
+			rendered = append(rendered, fmt.Sprintf("^%c", b+'@')...)
+		default:
+			rendered = append(rendered, b)
+		}
+	}
+
+	return append(rendered, "\x1b[27m"...)
+}
@@ -0,0 +1,143 @@
+package recording
+
+import (
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"maze.io/x/ttyrec"
+)
+
+// The keys controlling a replay
+const (
+	KeyPause       = ' '  // Pause or resume the replay
+	KeyStep        = '.'  // Show the next frame, while paused
+	KeyFaster      = '+'  // Double the speed
+	KeySlower      = '-'  // Halve the speed
+	KeyFastForward = 'f'  // Skip FastForwardStep of the recording
+	KeyQuit        = 'q'  // Stop the replay
+	KeyInterrupt   = 0x03 // Ctrl-C: stop the replay too, the terminal being in raw mode
+)
+
+// FastForwardStep is how much of the recording KeyFastForward skips
+const FastForwardStep = 10 * time.Second
+
+// PlayerOptions describes how a recording is replayed
+type PlayerOptions struct {
+	Speed   float64       // The speed factor (1 if not set)
+	MaxIdle time.Duration // The delays between frames are capped to it (no cap if not set)
+	From    time.Duration // The frames before this offset are displayed at once
+	To      time.Duration // The replay stops at this offset (the end of the recording if not set)
+}
+
+// Player replays a recording, interactively if it is given controls
+type Player struct {
+	options  PlayerOptions
+	controls <-chan byte
+	after    func(time.Duration) <-chan time.Time
+
+	paused      bool
+	skipToFrame time.Duration
+}
+
+// NewPlayer returns a Player. The controls channel (which may be nil) delivers the keys pressed by the user.
+func NewPlayer(options PlayerOptions, controls <-chan byte) *Player {
+
+	if options.Speed <= 0 {
+		options.Speed = 1
+	}
+
+	return &Player{
+		options:  options,
+		controls: controls,
+		after:    time.After,
+	}
+}
+
+// Play writes the frames of the recording to w, respecting the delays between them
+func (p *Player) Play(r io.Reader, w io.Writer) (err error) {
+
+	d := ttyrec.NewDecoder(r)
+	frames, stop := d.DecodeStream()
+	defer stop()
+
+	var first, previous *ttyrec.Frame
+	for frame := range frames {
+
+		if first == nil {
+			first, previous = frame, frame
+		}
+
+		offset := frame.Time.Sub(first.Time)
+		if p.options.To > 0 && offset > p.options.To {
+			return
+		}
+
+		// The frames before the start are still displayed, to get the screen right
+		if offset > p.options.From && offset > p.skipToFrame {
+			quit := p.wait(frame.Time.Sub(previous.Time), offset)
+			if quit {
+				return
+			}
+		}
+
+		if _, err = w.Write(frame.Data); err != nil {
+			return errors.Wrap(err, "error writing frame")
+		}
+		previous = frame
+	}
+
+	return
+}
+
+// wait waits for the delay before the frame at offset, handling the controls in the meantime.
+// It returns whether the user asked to quit.
+func (p *Player) wait(delay, offset time.Duration) (quit bool) {
+
+	if p.options.MaxIdle > 0 && delay > p.options.MaxIdle {
+		delay = p.options.MaxIdle
+	}
+	remaining := time.Duration(float64(delay) / p.options.Speed)
+
+	var timer <-chan time.Time
+	var deadline time.Time
+	if !p.paused {
+		timer, deadline = p.after(remaining), time.Now().Add(remaining)
+	}
+
+	for {
+		select {
+		case <-timer:
+			return false
+		case key, ok := <-p.controls:
+			if !ok {
+				p.controls = nil
+				continue
+			}
+			switch key {
+			case KeyPause:
+				p.paused = !p.paused
+				if p.paused {
+					remaining, timer = time.Until(deadline), nil
+				} else {
+					timer, deadline = p.after(remaining), time.Now().Add(remaining)
+				}
+			case KeyStep:
+				if p.paused {
+					return false
+				}
+			case KeyFaster, KeySlower:
+				if key == KeyFaster {
+					p.options.Speed *= 2
+				} else {
+					p.options.Speed /= 2
+				}
+			case KeyFastForward:
+				p.skipToFrame = offset + FastForwardStep
+				return false
+			case KeyQuit, KeyInterrupt:
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package recording
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"maze.io/x/ttyrec"
+)
+
+func decodeFrames(t *testing.T, r io.Reader) (frames []*ttyrec.Frame) {
+
+	d := ttyrec.NewDecoder(r)
+	for {
+		frame, err := d.DecodeFrame()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("unable to decode the recording: %s", err)
+		}
+		frames = append(frames, frame)
+	}
+}
+
+func TestInputRecorderMasking(t *testing.T) {
+
+	var buffer bytes.Buffer
+	recorder := NewInputRecorder(&buffer)
+	output := recorder.Output()
+
+	// The keystrokes are echoed by the distant terminal, but the secrets and the commands of full-screen programs
+	events := []struct {
+		input  string
+		output string
+	}{
+		{"", "user@host:~$ "},
+		{"sudo -i", "sudo -i"},
+		{"\r", "\r\n[sudo] password for user: "},
+		{"hunter2", ""},
+		{"\r", "\r\nroot@host:~# "},
+		{"l", "l"},
+		{"s\x1b[A", "s"},
+		{"\r", "\r\nEnter passphrase for key '/root/.ssh/id_ed25519': \x1b[0m"},
+		{"s3cr3t\x7f\r", "\r\nroot@host:~# "},
+		{"vi", "vi"},
+		{"\r", "\x1b[?1049h\x1b[H\x1b[2J~\r\n~"},
+		{"dd", "\x1b[H~"},
+		{"123", ""},
+	}
+	expected := []string{"sudo -i", "\r", "*******", "\r", "l", "s\x1b[A", "\r", "******\x7f\r", "vi", "\r", "**", "***"}
+
+	for _, event := range events {
+		io.WriteString(recorder, event.input)
+		io.WriteString(output, event.output)
+	}
+	recorder.Close()
+
+	frames := decodeFrames(t, &buffer)
+	if len(frames) != len(expected) {
+		t.Fatalf("recorded %d frames, expected %d", len(frames), len(expected))
+	}
+	for i := range expected {
+		if string(frames[i].Data) != expected[i] {
+			t.Errorf("frame %d was recorded as %q, expected %q", i, frames[i].Data, expected[i])
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+
+	start := time.Now()
+	clock := func(offset time.Duration) func() time.Time {
+		return func() time.Time { return start.Add(offset) }
+	}
+
+	var output, input, merged bytes.Buffer
+	outputEncoder := NewEncoder(&output)
+	inputEncoder := NewEncoder(&input)
+
+	outputEncoder.now = clock(0)
+	outputEncoder.Write([]byte("$ "))
+	inputEncoder.now = clock(time.Second)
+	inputEncoder.Write([]byte("ls\r"))
+	outputEncoder.now = clock(2 * time.Second)
+	outputEncoder.Write([]byte("ls\r\nfile\r\n$ "))
+	inputEncoder.now = clock(3 * time.Second)
+	inputEncoder.Write([]byte{0x04})
+
+	err := Merge(&output, &input, &merged)
+	if err != nil {
+		t.Fatalf("Merge() error: %s", err)
+	}
+
+	expected := []string{"$ ", "\x1b[7mls⏎\x1b[27m", "ls\r\nfile\r\n$ ", "\x1b[7m^D\x1b[27m"}
+	frames := decodeFrames(t, &merged)
+	if len(frames) != len(expected) {
+		t.Fatalf("merged %d frames, expected %d", len(frames), len(expected))
+	}
+	for i, frame := range frames {
+		if string(frame.Data) != expected[i] {
+			t.Errorf("frame %d is %q, expected %q", i, frame.Data, expected[i])
+		}
+		if delay := frame.Time.Sub(frames[0].Time); delay != time.Duration(i)*time.Second {
+			t.Errorf("frame %d is at %s, expected %s", i, delay, time.Duration(i)*time.Second)
+		}
+	}
+}
+
+func TestAsciicast(t *testing.T) {
+
+	start := time.Now()
+	clock := func(offset time.Duration) func() time.Time {
+		return func() time.Time { return start.Add(offset) }
+	}
+
+	var output, input, cast bytes.Buffer
+	outputEncoder := NewEncoder(&output)
+	inputEncoder := NewEncoder(&input)
+
+	// "é" is split between two frames
+	outputEncoder.now = clock(0)
+	outputEncoder.Write([]byte("caf\xc3"))
+	outputEncoder.now = clock(500 * time.Millisecond)
+	outputEncoder.Write([]byte("\xa9\r\n"))
+	inputEncoder.now = clock(time.Second)
+	inputEncoder.Write([]byte("q"))
+
+	err := WriteAsciicast(&cast, AsciicastHeader{Timestamp: 1700000000, Title: "root@host:22"}, &output, &input)
+	if err != nil {
+		t.Fatalf("WriteAsciicast() error: %s", err)
+	}
+
+	expected := `{"version":2,"width":80,"height":24,"timestamp":1700000000,"title":"root@host:22"}
+[0,"o","caf"]
+[0.5,"o","é\r\n"]
+[1,"i","q"]
+`
+	if cast.String() != expected {
+		t.Errorf("WriteAsciicast() =\n%s\nexpected:\n%s", cast.String(), expected)
+	}
+
+	// Back to ttyrec, with and without the input
+	for _, showInput := range []bool{false, true} {
+
+		var converted bytes.Buffer
+		err = ConvertAsciicast(bytes.NewReader(cast.Bytes()), &converted, showInput)
+		if err != nil {
+			t.Fatalf("ConvertAsciicast() error: %s", err)
+		}
+
+		frames := decodeFrames(t, &converted)
+		expectedFrames := []string{"caf", "é\r\n"}
+		if showInput {
+			expectedFrames = append(expectedFrames, "\x1b[7mq\x1b[27m")
+		}
+		if len(frames) != len(expectedFrames) {
+			t.Fatalf("converted %d frames, expected %d", len(frames), len(expectedFrames))
+		}
+		for i, frame := range frames {
+			if string(frame.Data) != expectedFrames[i] {
+				t.Errorf("frame %d is %q, expected %q", i, frame.Data, expectedFrames[i])
+			}
+		}
+		if showInput && frames[2].Time.Sub(frames[0].Time) != time.Second {
+			t.Errorf("the input frame is at %s, expected 1s", frames[2].Time.Sub(frames[0].Time))
+		}
+	}
+
+	var converted bytes.Buffer
+	if ConvertAsciicast(bytes.NewReader(output.Bytes()), &converted, false) == nil {
+		t.Errorf("ConvertAsciicast() should refuse a ttyrec recording")
+	}
+}
+
+// newRecording returns a recording with a frame at each offset (in seconds), showing the offset
+func newRecording(offsets ...int) []byte {
+
+	start := time.Now()
+	var buffer bytes.Buffer
+	encoder := NewEncoder(&buffer)
+	for _, offset := range offsets {
+		encoder.now = func() time.Time { return start.Add(time.Duration(offset) * time.Second) }
+		fmt.Fprintf(encoder, "%d ", offset)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestPlayerOptions(t *testing.T) {
+
+	var delays []time.Duration
+	player := NewPlayer(PlayerOptions{Speed: 2, MaxIdle: 4 * time.Second, From: time.Second, To: 15 * time.Second}, nil)
+	player.after = func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+		fired := make(chan time.Time, 1)
+		fired <- time.Now()
+		return fired
+	}
+
+	var output bytes.Buffer
+	err := player.Play(bytes.NewReader(newRecording(0, 1, 5, 12, 20)), &output)
+	if err != nil {
+		t.Fatalf("Play() error: %s", err)
+	}
+
+	if output.String() != "0 1 5 12 " {
+		t.Errorf("Play() displayed %q, expected \"0 1 5 12 \"", output.String())
+	}
+	if len(delays) != 2 || delays[0] != 2*time.Second || delays[1] != 2*time.Second {
+		t.Errorf("Play() waited %v, expected [2s 2s]", delays)
+	}
+}
+
+func TestPlayerControls(t *testing.T) {
+
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"q", "0 "},
+		{"f" + "q", "0 1 5 "},
+		{" " + "." + "+" + "." + "q", "0 1 5 "},
+		{" " + "." + " " + "\x03", "0 1 "},
+	}
+
+	for _, test := range tests {
+
+		controls := make(chan byte)
+		player := NewPlayer(PlayerOptions{}, controls)
+		// The frames are only displayed on the controls
+		player.after = func(time.Duration) <-chan time.Time { return nil }
+
+		var output bytes.Buffer
+		done := make(chan error)
+		go func() {
+			done <- player.Play(bytes.NewReader(newRecording(0, 1, 5, 12, 20)), &output)
+		}()
+		for _, key := range []byte(test.keys) {
+			controls <- key
+		}
+
+		if err := <-done; err != nil {
+			t.Fatalf("Play() error: %s", err)
+		}
+		if output.String() != test.expected {
+			t.Errorf("with keys %q, Play() displayed %q, expected %q", test.keys, output.String(), test.expected)
+		}
+	}
+}
+
+func TestExtractLines(t *testing.T) {
+
+	start := time.Now()
+	var buffer bytes.Buffer
+	encoder := NewEncoder(&buffer)
+
+	frames := []struct {
+		offset time.Duration
+		data   string
+	}{
+		{0, "\x1b]0;root@host: ~\x07\x1b[01;32mroot@host\x1b[00m:~# "},
+		{time.Second, "rm -r"},
+		{2 * time.Second, "f /tmp/x\r\n"},
+		{3 * time.Second, "\x1b[01;32mroot@host\x1b[00m:~# ls\tfoo\b\bbar\r\n\r\n"},
+		{4 * time.Second, "logout"},
+	}
+	for _, frame := range frames {
+		encoder.now = func() time.Time { return start.Add(frame.offset) }
+		encoder.Write([]byte(frame.data))
+	}
+
+	lines, err := ExtractLines(&buffer)
+	if err != nil {
+		t.Fatalf("ExtractLines() error: %s", err)
+	}
+
+	expected := []Line{
+		{0, "root@host:~# rm -rf /tmp/x"},
+		{3 * time.Second, "root@host:~# ls fbar"},
+		{4 * time.Second, "logout"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("ExtractLines() = %v, expected %v", lines, expected)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d is %v, expected %v", i, lines[i], expected[i])
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+
+	recording := newRecording(0, 1, 2)
+	f, err := os.Create(filepath.Join(t.TempDir(), "live.ttyrec"))
+	if err != nil {
+		t.Fatalf("unable to create the recording: %s", err)
+	}
+	defer f.Close()
+
+	// The second frame is still being written when the recording is followed
+	written := len(recording)/3 + headerSize + 1
+	f.Write(recording[:written])
+
+	var output bytes.Buffer
+	polls := 0
+	stop := func() bool {
+		polls++
+		if polls == 1 {
+			if output.String() != "0 " {
+				t.Errorf("Follow() displayed %q before the end of the second frame, expected \"0 \"", output.String())
+			}
+			f.WriteAt(recording[written:], int64(written))
+		}
+		return polls > 1
+	}
+
+	err = Follow(f, &output, stop, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Follow() error: %s", err)
+	}
+	if output.String() != "0 1 2 " {
+		t.Errorf("Follow() displayed %q, expected \"0 1 2 \"", output.String())
+	}
+}
@@ -0,0 +1,95 @@
+package recording
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"maze.io/x/ttyrec"
+)
+
+// maxLineSize caps the lines of text: full-screen programs redraw the screen without ever ending a line
+const maxLineSize = 16 * 1024
+
+// Line is a line of text displayed during a session
+type Line struct {
+	Offset time.Duration // When the line started to be displayed, since the beginning of the recording
+	Text   string
+}
+
+// ExtractLines returns the lines of text displayed by a recording, without the terminal escape sequences
+// and control characters
+func ExtractLines(r io.Reader) (lines []Line, err error) {
+
+	var raw []byte
+	var offset, lineStart time.Duration
+
+	flush := func() {
+		if text := cleanLine(raw); text != "" {
+			lines = append(lines, Line{Offset: lineStart, Text: text})
+		}
+		raw = raw[:0]
+	}
+
+	var first *ttyrec.TimeVal
+	err = walkFrames(r, nil, func(frame *ttyrec.Frame, _ bool) error {
+
+		if first == nil {
+			first = &frame.Time
+		}
+		offset = frame.Time.Sub(*first)
+
+		data := frame.Data
+		for len(data) > 0 {
+			if len(raw) == 0 {
+				lineStart = offset
+			}
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				raw = append(raw, data...)
+				if len(raw) > maxLineSize {
+					flush()
+				}
+				break
+			}
+			raw = append(raw, data[:i]...)
+			flush()
+			data = data[i+1:]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	flush()
+
+	return
+}
+
+// cleanLine returns the text of a raw line, as it was displayed
+func cleanLine(raw []byte) string {
+
+	raw = escapeSequence.ReplaceAll(raw, nil)
+
+	var text []rune
+	for len(raw) > 0 {
+		r, size := utf8.DecodeRune(raw)
+		raw = raw[size:]
+		switch {
+		case r == '\b':
+			if len(text) > 0 {
+				text = text[:len(text)-1]
+			}
+		case r == '\t':
+			text = append(text, ' ')
+		case r == utf8.RuneError || r < 0x20 || r == 0x7f:
+		default:
+			text = append(text, r)
+		}
+	}
+
+	return strings.TrimSpace(string(text))
+}
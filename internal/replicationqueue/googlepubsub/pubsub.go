@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/inpher/sb/internal/models"
@@ -11,6 +12,12 @@ import (
 	"github.com/spf13/viper"
 )
 
+// retryPolicy delivers the failed messages again after a delay growing exponentially, like the other queues
+var retryPolicy = &pubsub.RetryPolicy{
+	MinimumBackoff: models.FirstReplicationRetryDelay,
+	MaximumBackoff: models.MaxReplicationRetryDelay,
+}
+
 type ReplicationQueuePubSub struct {
 	project        string
 	topicName      string
@@ -78,19 +85,24 @@ func (rq *ReplicationQueuePubSub) PushToQueue(entry *models.Replication) (err er
 
 func (rq *ReplicationQueuePubSub) ConsumeQueue(callbackFn func(entry *models.Replication) error) (err error) {
 
+	// The callbacks run concurrently: they don't share any variable
 	return rq.subscription.Receive(context.Background(), func(ctx context.Context, msg *pubsub.Message) {
 
 		entry := models.Replication{}
 
-		// Unmarshal the event
-		err = json.Unmarshal(msg.Data, &entry)
-		if err != nil {
+		// Unmarshal the event: if it can't be decoded now, it never will, so we acknowledge it
+		errUnmarshal := json.Unmarshal(msg.Data, &entry)
+		if errUnmarshal != nil {
+			fmt.Fprintf(os.Stderr, "Dropping Google PubSub message %s: %s\n", msg.ID, errUnmarshal)
+			msg.Ack()
 			return
 		}
 
 		// Call the callback function
-		err = callbackFn(&entry)
-		if err != nil {
+		// If it fails, the message is delivered again once the delay of the retry policy of the subscription is elapsed
+		errCallback := callbackFn(&entry)
+		if errCallback != nil {
+			msg.Nack()
 			return
 		}
 
@@ -148,11 +160,19 @@ func (rq *ReplicationQueuePubSub) getSubscription(createIfNotExists bool) (subsc
 		subscription, err = rq.client.CreateSubscription(ctx, rq.subscriberName, pubsub.SubscriptionConfig{
 			Topic:                 rq.topic,
 			EnableMessageOrdering: true,
+			RetryPolicy:           retryPolicy,
 		})
 		if err != nil {
 			err = errors.Wrap(err, "unable to create Google PubSub subscription")
-			return
 		}
+		return
 	}
+
+	// The subscriptions created by a former version of sb deliver the failed messages again immediately
+	_, err = subscription.Update(ctx, pubsub.SubscriptionConfigToUpdate{RetryPolicy: retryPolicy})
+	if err != nil {
+		err = errors.Wrap(err, "unable to set the retry policy of the Google PubSub subscription")
+	}
+
 	return
 }
@@ -0,0 +1,219 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/models"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// durableNameReplacer replaces the characters that are not allowed in a JetStream consumer name
+var durableNameReplacer = strings.NewReplacer(".", "-", "*", "-", ">", "-", " ", "-")
+
+type ReplicationQueueNATS struct {
+	streamName  string
+	subject     string
+	durableName string
+	ackWait     time.Duration
+	conn        *nats.Conn
+	js          jetstream.JetStream
+	stream      jetstream.Stream
+	consumer    jetstream.Consumer
+}
+
+func NewReplicationQueueNATS(options *viper.Viper, hostname string) (rq *ReplicationQueueNATS, err error) {
+
+	if options == nil {
+		err = fmt.Errorf("replication.queue.nats options can't be empty")
+		return
+	}
+
+	url := options.GetString("url")
+	streamName := options.GetString("stream")
+	subject := options.GetString("subject")
+	if url == "" || streamName == "" || subject == "" {
+		err = fmt.Errorf("nats.url, nats.stream and nats.subject options can't be empty")
+		return
+	}
+
+	ackWait := options.GetDuration("ack-wait")
+	if ackWait <= 0 {
+		ackWait = 30 * time.Second
+	}
+
+	natsOptions := []nats.Option{
+		nats.Name(fmt.Sprintf("sb-%s", hostname)),
+		nats.MaxReconnects(-1),
+	}
+	if credentials := options.GetString("credentials"); credentials != "" {
+		natsOptions = append(natsOptions, nats.UserCredentials(credentials))
+	}
+
+	conn, err := nats.Connect(url, natsOptions...)
+	if err != nil {
+		err = errors.Wrap(err, "unable to connect to NATS")
+		return
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		err = errors.Wrap(err, "unable to initialize NATS JetStream")
+		return
+	}
+
+	// Each instance registers its own durable consumer, so that every instance receives every entry
+	rq = &ReplicationQueueNATS{
+		streamName:  streamName,
+		subject:     subject,
+		durableName: durableNameReplacer.Replace(fmt.Sprintf("%s-%s", streamName, hostname)),
+		ackWait:     ackWait,
+		conn:        conn,
+		js:          js,
+	}
+
+	// Get the stream where to push (and create if it doesn't exist yet)
+	rq.stream, err = rq.getStream(true)
+	if err != nil {
+		return
+	}
+
+	rq.consumer, err = rq.getConsumer(true)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (rq *ReplicationQueueNATS) PushToQueue(entry *models.Replication) (err error) {
+
+	if rq.js == nil {
+		return fmt.Errorf("replication queue NATS not initialized")
+	}
+
+	entryStr, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "unable to json.Marshal entry")
+	}
+
+	// The entry's unique ID is used as message ID, so that JetStream drops the duplicates of a retried publication
+	_, err = rq.js.Publish(context.Background(), rq.subject, entryStr, jetstream.WithMsgID(entry.UniqID))
+	if err != nil {
+		return errors.Wrap(err, "unable to publish entry to NATS JetStream")
+	}
+
+	return
+}
+
+func (rq *ReplicationQueueNATS) ConsumeQueue(callbackFn func(entry *models.Replication) error) (err error) {
+
+	if rq.consumer == nil {
+		return fmt.Errorf("replication queue NATS not initialized")
+	}
+
+	messages, err := rq.consumer.Messages()
+	if err != nil {
+		return errors.Wrap(err, "unable to consume NATS JetStream messages")
+	}
+	defer messages.Stop()
+
+	for {
+		msg, err := messages.Next()
+		if err != nil {
+			return errors.Wrap(err, "unable to get next NATS JetStream message")
+		}
+
+		entry := models.Replication{}
+
+		// Unmarshal the event: if it can't be decoded now, it never will, so we stop its redelivery
+		err = json.Unmarshal(msg.Data(), &entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Dropping NATS JetStream message: %s\n", err)
+			msg.Term()
+			continue
+		}
+
+		// Call the callback function
+		// If it fails, the message is delivered again once the delay the callback asked for is elapsed
+		err = callbackFn(&entry)
+		if err != nil {
+			msg.NakWithDelay(models.GetReplicationRetryDelay(err))
+			continue
+		}
+
+		// Let's acknowledge the message
+		msg.Ack()
+	}
+}
+
+func (rq *ReplicationQueueNATS) getStream(createIfNotExists bool) (stream jetstream.Stream, err error) {
+
+	ctx := context.Background()
+
+	stream, err = rq.js.Stream(ctx, rq.streamName)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		err = errors.Wrap(err, "unable to check if NATS JetStream stream exists")
+		return
+	}
+
+	if !createIfNotExists {
+		err = fmt.Errorf("this NATS JetStream stream does not exist")
+		return
+	}
+
+	stream, err = rq.js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     rq.streamName,
+		Subjects: []string{rq.subject},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "unable to create NATS JetStream stream")
+		return
+	}
+
+	return
+}
+
+func (rq *ReplicationQueueNATS) getConsumer(createIfNotExists bool) (consumer jetstream.Consumer, err error) {
+
+	ctx := context.Background()
+
+	consumer, err = rq.stream.Consumer(ctx, rq.durableName)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		err = errors.Wrap(err, "unable to check if NATS JetStream consumer exists")
+		return
+	}
+
+	if !createIfNotExists {
+		err = fmt.Errorf("this NATS JetStream consumer does not exist")
+		return
+	}
+
+	// Like a new Google PubSub subscription, a new consumer only receives the entries pushed after its creation
+	consumer, err = rq.stream.CreateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       rq.durableName,
+		FilterSubject: rq.subject,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       rq.ackWait,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "unable to create NATS JetStream consumer")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,173 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/inpher/sb/internal/models"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func runServer(t *testing.T) *server.Server {
+
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+
+	go s.Start()
+	require.True(t, s.ReadyForConnections(5*time.Second))
+	t.Cleanup(s.Shutdown)
+
+	return s
+}
+
+func getOptions(s *server.Server) *viper.Viper {
+
+	options := viper.New()
+	options.Set("url", s.ClientURL())
+	options.Set("stream", "sb")
+	options.Set("subject", "sb.replication")
+	options.Set("ack-wait", "500ms")
+
+	return options
+}
+
+func TestReplicationQueueNATS(t *testing.T) {
+
+	s := runServer(t)
+
+	_, err := NewReplicationQueueNATS(viper.New(), "sb-1")
+	require.Error(t, err)
+
+	rq1, err := NewReplicationQueueNATS(getOptions(s), "sb-1.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "sb-sb-1-example-com", rq1.durableName)
+
+	rq2, err := NewReplicationQueueNATS(getOptions(s), "sb-2.example.com")
+	require.NoError(t, err)
+
+	received1 := make(chan *models.Replication, 10)
+	go rq1.ConsumeQueue(func(entry *models.Replication) error {
+		received1 <- entry
+		return nil
+	})
+
+	// The first delivery fails: the entry must be delivered again as it was not acknowledged
+	received2 := make(chan *models.Replication, 10)
+	attempts := 0
+	go rq2.ConsumeQueue(func(entry *models.Replication) error {
+		attempts++
+		if attempts == 1 {
+			return fmt.Errorf("callback failed")
+		}
+		received2 <- entry
+		return nil
+	})
+
+	entry := &models.Replication{
+		UniqID:       "1234",
+		CreationDate: time.Now().UTC().Truncate(time.Second),
+		Instance:     "sb-1.example.com",
+		Action:       "self accesses add",
+	}
+	require.NoError(t, rq1.PushToQueue(entry))
+
+	for _, received := range []chan *models.Replication{received1, received2} {
+		select {
+		case e := <-received:
+			require.Equal(t, entry.UniqID, e.UniqID)
+			require.Equal(t, entry.Action, e.Action)
+			require.True(t, entry.CreationDate.Equal(e.CreationDate))
+		case <-time.After(5 * time.Second):
+			t.Fatal("replication entry not received")
+		}
+	}
+	require.Equal(t, 2, attempts)
+
+	// Once acknowledged, an entry is not delivered again
+	select {
+	case <-received1:
+		t.Fatal("replication entry received twice")
+	case <-time.After(time.Second):
+	}
+}
+
+func TestReplicationQueueNATSUndecodableMessage(t *testing.T) {
+
+	s := runServer(t)
+
+	rq, err := NewReplicationQueueNATS(getOptions(s), "sb-1.example.com")
+	require.NoError(t, err)
+
+	received := make(chan *models.Replication, 10)
+	go rq.ConsumeQueue(func(entry *models.Replication) error {
+		received <- entry
+		return nil
+	})
+
+	// A message that can't be decoded is terminated, instead of being delivered again after every ack-wait
+	_, err = rq.js.Publish(context.Background(), rq.subject, []byte("not a replication entry"))
+	require.NoError(t, err)
+	require.NoError(t, rq.PushToQueue(&models.Replication{UniqID: "1234", Action: "group create"}))
+
+	select {
+	case e := <-received:
+		require.Equal(t, "1234", e.UniqID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("replication entry not received")
+	}
+
+	time.Sleep(2 * time.Second)
+	info, err := rq.consumer.Info(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, info.NumAckPending)
+	require.Zero(t, info.NumRedelivered)
+}
+
+func TestReplicationQueueNATSRetryDelay(t *testing.T) {
+
+	s := runServer(t)
+
+	// The entry is delivered again after the delay asked for by the callback, well before ack-wait
+	options := getOptions(s)
+	options.Set("ack-wait", "30s")
+	rq, err := NewReplicationQueueNATS(options, "sb-1.example.com")
+	require.NoError(t, err)
+
+	deliveries := make(chan time.Time, 10)
+	attempts := 0
+	go rq.ConsumeQueue(func(entry *models.Replication) error {
+		deliveries <- time.Now()
+		attempts++
+		if attempts == 1 {
+			return &models.ReplicationRetryError{Err: fmt.Errorf("callback failed"), Delay: 2 * time.Second}
+		}
+		return nil
+	})
+
+	require.NoError(t, rq.PushToQueue(&models.Replication{UniqID: "1234", Action: "group create"}))
+
+	var first time.Time
+	select {
+	case first = <-deliveries:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replication entry not received")
+	}
+
+	select {
+	case second := <-deliveries:
+		require.GreaterOrEqual(t, second.Sub(first), 2*time.Second)
+	case <-time.After(10 * time.Second):
+		t.Fatal("replication entry not delivered again")
+	}
+}
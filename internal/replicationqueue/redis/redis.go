@@ -0,0 +1,245 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/inpher/sb/internal/models"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+// readCount is the maximum number of entries read from the stream at once
+const readCount = 100
+
+type ReplicationQueueRedis struct {
+	stream    string
+	group     string
+	consumer  string
+	maxLength int64
+	client    *redis.Client
+	retryAt   map[string]time.Time // When the pending entries that failed can be delivered again
+}
+
+func NewReplicationQueueRedis(options *viper.Viper, hostname string) (rq *ReplicationQueueRedis, err error) {
+
+	if options == nil {
+		err = fmt.Errorf("replication.queue.redis options can't be empty")
+		return
+	}
+
+	address := options.GetString("address")
+	stream := options.GetString("stream")
+	if address == "" || stream == "" {
+		err = fmt.Errorf("redis.address and redis.stream options can't be empty")
+		return
+	}
+
+	redisOptions := &redis.Options{
+		Addr:     address,
+		Username: options.GetString("username"),
+		Password: options.GetString("password"),
+		DB:       options.GetInt("db"),
+	}
+	if options.GetBool("tls") {
+		redisOptions.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	// Each instance registers its own consumer group, so that every instance receives every entry
+	rq = &ReplicationQueueRedis{
+		stream:    stream,
+		group:     fmt.Sprintf("%s-%s", stream, hostname),
+		consumer:  hostname,
+		maxLength: options.GetInt64("max-length"),
+		client:    redis.NewClient(redisOptions),
+		retryAt:   make(map[string]time.Time),
+	}
+
+	err = rq.client.Ping(context.Background()).Err()
+	if err != nil {
+		err = errors.Wrap(err, "unable to connect to Redis")
+		return
+	}
+
+	// Create the stream and the consumer group if they don't exist yet
+	err = rq.getConsumerGroup(true)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (rq *ReplicationQueueRedis) PushToQueue(entry *models.Replication) (err error) {
+
+	if rq.client == nil {
+		return fmt.Errorf("replication queue Redis not initialized")
+	}
+
+	entryStr, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "unable to json.Marshal entry")
+	}
+
+	args := &redis.XAddArgs{
+		Stream: rq.stream,
+		Values: map[string]interface{}{"entry": string(entryStr)},
+	}
+
+	// Trimming is approximate to keep it cheap on Redis side
+	if rq.maxLength > 0 {
+		args.MaxLen = rq.maxLength
+		args.Approx = true
+	}
+
+	err = rq.client.XAdd(context.Background(), args).Err()
+	if err != nil {
+		return errors.Wrap(err, "unable to publish entry to Redis stream")
+	}
+
+	return
+}
+
+func (rq *ReplicationQueueRedis) ConsumeQueue(callbackFn func(entry *models.Replication) error) (err error) {
+
+	ctx := context.Background()
+
+	for {
+
+		// We start by reading the entries that were delivered to us but never acknowledged
+		// (callback failed, or the daemon stopped before acknowledging), then we wait for new ones
+		err = rq.consumePending(ctx, callbackFn)
+		if err != nil {
+			return
+		}
+
+		streams, errRead := rq.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    rq.group,
+			Consumer: rq.consumer,
+			Streams:  []string{rq.stream, ">"},
+			Count:    readCount,
+			Block:    5 * time.Second,
+		}).Result()
+		if errRead == redis.Nil {
+			continue
+		}
+		if errRead != nil {
+			return errors.Wrap(errRead, "unable to read from Redis stream")
+		}
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				rq.handleMessage(ctx, msg, callbackFn)
+			}
+		}
+	}
+}
+
+// consumePending reads the entries delivered to us but never acknowledged, page by page until none are left
+func (rq *ReplicationQueueRedis) consumePending(ctx context.Context, callbackFn func(entry *models.Replication) error) (err error) {
+
+	lastID := "0"
+
+	for {
+		streams, errRead := rq.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    rq.group,
+			Consumer: rq.consumer,
+			Streams:  []string{rq.stream, lastID},
+			Count:    readCount,
+			Block:    -1,
+		}).Result()
+		if errRead == redis.Nil {
+			return nil
+		}
+		if errRead != nil {
+			return errors.Wrap(errRead, "unable to read pending entries from Redis stream")
+		}
+
+		read := 0
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				lastID = msg.ID
+				read++
+
+				// An entry that failed is only delivered again once the delay the callback asked for is elapsed
+				if time.Now().Before(rq.retryAt[msg.ID]) {
+					continue
+				}
+				rq.handleMessage(ctx, msg, callbackFn)
+			}
+		}
+		if read == 0 {
+			return nil
+		}
+	}
+}
+
+func (rq *ReplicationQueueRedis) handleMessage(ctx context.Context, msg redis.XMessage, callbackFn func(entry *models.Replication) error) {
+
+	entry := models.Replication{}
+
+	data, ok := msg.Values["entry"].(string)
+	if !ok {
+		// This message is not ours and will never be, let's acknowledge it
+		rq.client.XAck(ctx, rq.stream, rq.group, msg.ID)
+		return
+	}
+
+	// Unmarshal the event: if it can't be decoded now, it never will, so we acknowledge it
+	// instead of leaving it in the pending entries forever
+	err := json.Unmarshal([]byte(data), &entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Dropping Redis stream entry %s: %s\n", msg.ID, err)
+		rq.client.XAck(ctx, rq.stream, rq.group, msg.ID)
+		return
+	}
+
+	// Call the callback function
+	// If it fails, the entry stays pending and will be delivered again by a read of the pending entries,
+	// once the delay the callback asked for is elapsed
+	err = callbackFn(&entry)
+	if err != nil {
+		rq.retryAt[msg.ID] = time.Now().Add(models.GetReplicationRetryDelay(err))
+		return
+	}
+
+	// Let's acknowledge the message
+	rq.client.XAck(ctx, rq.stream, rq.group, msg.ID)
+	delete(rq.retryAt, msg.ID)
+}
+
+func (rq *ReplicationQueueRedis) getConsumerGroup(createIfNotExists bool) (err error) {
+
+	ctx := context.Background()
+
+	groups, err := rq.client.XInfoGroups(ctx, rq.stream).Result()
+	if err != nil && !strings.Contains(err.Error(), "no such key") {
+		return errors.Wrap(err, "unable to check if Redis consumer group exists")
+	}
+
+	for _, group := range groups {
+		if group.Name == rq.group {
+			return nil
+		}
+	}
+
+	if !createIfNotExists {
+		return fmt.Errorf("this Redis consumer group does not exist")
+	}
+
+	// Like a new Google PubSub subscription, a new consumer group only receives the entries pushed after its creation
+	err = rq.client.XGroupCreateMkStream(ctx, rq.stream, rq.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return errors.Wrap(err, "unable to create Redis consumer group")
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inpher/sb/internal/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// getOptions returns the options of a new stream on the Redis server of SB_TEST_REDIS_ADDRESS (127.0.0.1:6379 by
+// default), and skips the test if no server is available
+func getOptions(t *testing.T) *viper.Viper {
+
+	address := os.Getenv("SB_TEST_REDIS_ADDRESS")
+	if address == "" {
+		address = "127.0.0.1:6379"
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: address})
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("no Redis server available on %s: %s", address, err)
+	}
+
+	stream := fmt.Sprintf("sb-test-%s", uuid.New().String())
+	t.Cleanup(func() { client.Del(context.Background(), stream) })
+
+	options := viper.New()
+	options.Set("address", address)
+	options.Set("stream", stream)
+
+	return options
+}
+
+// pendingEntries returns the number of entries delivered to the consumer group and not acknowledged yet
+func pendingEntries(t *testing.T, rq *ReplicationQueueRedis) int64 {
+	pending, err := rq.client.XPending(context.Background(), rq.stream, rq.group).Result()
+	require.NoError(t, err)
+	return pending.Count
+}
+
+func TestReplicationQueueRedis(t *testing.T) {
+
+	options := getOptions(t)
+
+	_, err := NewReplicationQueueRedis(viper.New(), "sb-1")
+	require.Error(t, err)
+
+	rq1, err := NewReplicationQueueRedis(options, "sb-1.example.com")
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%s-sb-1.example.com", options.GetString("stream")), rq1.group)
+
+	rq2, err := NewReplicationQueueRedis(options, "sb-2.example.com")
+	require.NoError(t, err)
+
+	received1 := make(chan *models.Replication, 10)
+	go rq1.ConsumeQueue(func(entry *models.Replication) error {
+		received1 <- entry
+		return nil
+	})
+
+	// The first delivery fails: the entry must be delivered again as it was not acknowledged
+	received2 := make(chan *models.Replication, 10)
+	var attempts int32
+	go rq2.ConsumeQueue(func(entry *models.Replication) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return fmt.Errorf("callback failed")
+		}
+		received2 <- entry
+		return nil
+	})
+
+	entry := &models.Replication{
+		UniqID:       "1234",
+		CreationDate: time.Now().UTC().Truncate(time.Second),
+		Instance:     "sb-1.example.com",
+		Action:       "self accesses add",
+	}
+	require.NoError(t, rq1.PushToQueue(entry))
+
+	for _, received := range []chan *models.Replication{received1, received2} {
+		select {
+		case e := <-received:
+			require.Equal(t, entry.UniqID, e.UniqID)
+			require.Equal(t, entry.Action, e.Action)
+			require.True(t, entry.CreationDate.Equal(e.CreationDate))
+		case <-time.After(10 * time.Second):
+			t.Fatal("replication entry not received")
+		}
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	// Once acknowledged, an entry is not delivered again
+	select {
+	case <-received1:
+		t.Fatal("replication entry received twice")
+	case <-time.After(time.Second):
+	}
+
+	// An entry that can't be decoded is acknowledged, instead of staying pending forever
+	require.NoError(t, rq1.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: rq1.stream,
+		Values: map[string]interface{}{"entry": "not a replication entry"},
+	}).Err())
+	require.Eventually(t, func() bool {
+		return pendingEntries(t, rq1) == 0 && pendingEntries(t, rq2) == 0
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestReplicationQueueRedisPendingBacklog(t *testing.T) {
+
+	options := getOptions(t)
+
+	rq, err := NewReplicationQueueRedis(options, "sb-1.example.com")
+	require.NoError(t, err)
+
+	// More entries than read at once are left pending, then all delivered again
+	entries := 2*readCount + 10
+	for i := 0; i < entries; i++ {
+		require.NoError(t, rq.PushToQueue(&models.Replication{UniqID: fmt.Sprintf("%d", i), Action: "group create"}))
+	}
+
+	var failing int32 = 1
+	received := make(chan string, 2*entries)
+	go rq.ConsumeQueue(func(entry *models.Replication) error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return fmt.Errorf("callback failed")
+		}
+		received <- entry.UniqID
+		return nil
+	})
+
+	require.Eventually(t, func() bool {
+		return pendingEntries(t, rq) == int64(entries)
+	}, 10*time.Second, 100*time.Millisecond)
+
+	atomic.StoreInt32(&failing, 0)
+
+	seen := make(map[string]bool)
+	for len(seen) < entries {
+		select {
+		case id := <-received:
+			seen[id] = true
+		case <-time.After(10 * time.Second):
+			t.Fatalf("only %d of the %d pending entries were delivered again", len(seen), entries)
+		}
+	}
+	require.Zero(t, pendingEntries(t, rq))
+}
+
+func TestReplicationQueueRedisRetryDelay(t *testing.T) {
+
+	options := getOptions(t)
+
+	rq, err := NewReplicationQueueRedis(options, "sb-1.example.com")
+	require.NoError(t, err)
+
+	// A pending entry is only delivered again once the delay asked for by the callback is elapsed
+	deliveries := make(chan time.Time, 10)
+	var attempts int32
+	go rq.ConsumeQueue(func(entry *models.Replication) error {
+		deliveries <- time.Now()
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return &models.ReplicationRetryError{Err: fmt.Errorf("callback failed"), Delay: 7 * time.Second}
+		}
+		return nil
+	})
+
+	require.NoError(t, rq.PushToQueue(&models.Replication{UniqID: "1234", Action: "group create"}))
+
+	var first time.Time
+	select {
+	case first = <-deliveries:
+	case <-time.After(10 * time.Second):
+		t.Fatal("replication entry not received")
+	}
+
+	select {
+	case second := <-deliveries:
+		require.GreaterOrEqual(t, second.Sub(first), 7*time.Second)
+	case <-time.After(20 * time.Second):
+		t.Fatal("replication entry not delivered again")
+	}
+	require.Eventually(t, func() bool {
+		return pendingEntries(t, rq) == 0
+	}, 10*time.Second, 100*time.Millisecond)
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/inpher/sb/internal/models"
 	"github.com/inpher/sb/internal/replicationqueue/googlepubsub"
+	"github.com/inpher/sb/internal/replicationqueue/nats"
+	"github.com/inpher/sb/internal/replicationqueue/redis"
 	"github.com/inpher/sb/internal/types"
 	"github.com/pkg/errors"
 )
@@ -24,6 +26,10 @@ func GetReplicationQueue(config *types.ReplicationQueueConfig, hostname string)
 	switch config.QueueType {
 	case "googlepubsub":
 		rq, err = googlepubsub.NewReplicationQueuePubSub(config.QueueOptions, hostname)
+	case "nats":
+		rq, err = nats.NewReplicationQueueNATS(config.QueueOptions, hostname)
+	case "redis":
+		rq, err = redis.NewReplicationQueueRedis(config.QueueOptions, hostname)
 	default:
 		err = fmt.Errorf("storage type %s is not implemented", config.QueueType)
 	}
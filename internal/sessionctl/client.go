@@ -0,0 +1,59 @@
+package sessionctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The actions of the requests
+const (
+	ActionTerminate = "terminate"
+)
+
+// Request asks the daemon to act on a running session
+type Request struct {
+	Action    string `json:"action"`
+	SessionID string `json:"session_id"`
+}
+
+// Response holds the error of the request, if any
+type Response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// TerminateSession asks the daemon listening on the unix socket to terminate a running session
+func TerminateSession(socketPath, sessionID string) (err error) {
+	return send(socketPath, &Request{Action: ActionTerminate, SessionID: sessionID})
+}
+
+func send(socketPath string, request *Request) (err error) {
+
+	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		return errors.Wrapf(err, "unable to connect to the daemon on %s", socketPath)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	err = json.NewEncoder(conn).Encode(request)
+	if err != nil {
+		return errors.Wrap(err, "unable to send the request")
+	}
+
+	var response Response
+	err = json.NewDecoder(conn).Decode(&response)
+	if err != nil {
+		return errors.Wrap(err, "unable to read the daemon response")
+	}
+
+	if response.Error != "" {
+		return fmt.Errorf("the daemon refused the request: %s", response.Error)
+	}
+
+	return
+}
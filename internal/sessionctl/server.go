@@ -0,0 +1,109 @@
+package sessionctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/pkg/errors"
+)
+
+// TerminateFunc terminates a running session, if the system user with the specified uid is allowed to
+type TerminateFunc func(uid uint32, sessionID string) error
+
+// Server terminates the running sessions on request of the accounts connecting on its unix socket.
+// The callers are identified by the kernel (SO_PEERCRED), so the socket can be world-writable
+// while only the daemon (running as root) can signal the processes of the sessions.
+type Server struct {
+	listener  *net.UnixListener
+	terminate TerminateFunc
+}
+
+// NewServer starts listening on the unix socket
+func NewServer(socketPath string, terminate TerminateFunc) (s *Server, err error) {
+
+	// A previous instance might have left its socket behind
+	err = os.Remove(socketPath)
+	if err != nil && !os.IsNotExist(err) {
+		err = errors.Wrapf(err, "unable to remove stale socket %s", socketPath)
+		return
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		err = errors.Wrapf(err, "unable to listen on %s", socketPath)
+		return
+	}
+
+	err = os.Chmod(socketPath, 0666)
+	if err != nil {
+		listener.Close()
+		err = errors.Wrapf(err, "unable to chmod socket %s", socketPath)
+		return
+	}
+
+	s = &Server{
+		listener:  listener,
+		terminate: terminate,
+	}
+
+	return
+}
+
+// Serve handles the incoming connections until the listener is closed
+func (s *Server) Serve() (err error) {
+
+	for {
+		conn, err := s.listener.AcceptUnix()
+		if err != nil {
+			return errors.Wrap(err, "unable to accept connection")
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// Close stops listening
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) handle(conn *net.UnixConn) {
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var response Response
+
+	err := s.process(conn)
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	json.NewEncoder(conn).Encode(&response)
+}
+
+func (s *Server) process(conn *net.UnixConn) (err error) {
+
+	uid, err := helpers.GetPeerUID(conn)
+	if err != nil {
+		return
+	}
+
+	var request Request
+	err = json.NewDecoder(conn).Decode(&request)
+	if err != nil {
+		return fmt.Errorf("invalid request")
+	}
+
+	switch request.Action {
+	case ActionTerminate:
+		return s.terminate(uid, request.SessionID)
+	}
+
+	return fmt.Errorf("unknown action %s", request.Action)
+}
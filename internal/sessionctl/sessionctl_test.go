@@ -0,0 +1,51 @@
+package sessionctl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+
+	socketPath := filepath.Join(t.TempDir(), "sessions.sock")
+
+	var terminated []string
+	server, err := NewServer(socketPath, func(uid uint32, sessionID string) error {
+		if uid != uint32(os.Getuid()) {
+			return fmt.Errorf("unexpected uid %d", uid)
+		}
+		if sessionID != "session" {
+			return fmt.Errorf("session %s not found", sessionID)
+		}
+		terminated = append(terminated, sessionID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewServer() error: %s", err)
+	}
+	defer server.Close()
+	go server.Serve()
+
+	err = TerminateSession(socketPath, "session")
+	if err != nil {
+		t.Fatalf("TerminateSession() error: %s", err)
+	}
+	if len(terminated) != 1 {
+		t.Errorf("the session wasn't terminated")
+	}
+
+	err = TerminateSession(socketPath, "other")
+	if err == nil {
+		t.Errorf("TerminateSession() should fail for an unknown session")
+	}
+
+	err = send(socketPath, &Request{Action: "kill", SessionID: "session"})
+	if err == nil {
+		t.Errorf("send() should fail for an unknown action")
+	}
+	if len(terminated) != 1 {
+		t.Errorf("the session was terminated again")
+	}
+}
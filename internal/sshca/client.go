@@ -0,0 +1,56 @@
+package sshca
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// RequestCertificate asks the CA listening on the unix socket to sign a certificate for the request
+func RequestCertificate(socketPath string, request *SignRequest) (cert *ssh.Certificate, err error) {
+
+	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to connect to the CA on %s", socketPath)
+		return
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	err = json.NewEncoder(conn).Encode(request)
+	if err != nil {
+		err = errors.Wrap(err, "unable to send the signing request")
+		return
+	}
+
+	var response SignResponse
+	err = json.NewDecoder(conn).Decode(&response)
+	if err != nil {
+		err = errors.Wrap(err, "unable to read the CA response")
+		return
+	}
+
+	if response.Error != "" {
+		err = fmt.Errorf("the CA refused to sign the certificate: %s", response.Error)
+		return
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(response.Certificate))
+	if err != nil {
+		err = errors.Wrap(err, "unable to parse the certificate")
+		return
+	}
+
+	cert, ok := publicKey.(*ssh.Certificate)
+	if !ok {
+		err = fmt.Errorf("the CA didn't return a certificate")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,143 @@
+package sshca
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/inpher/sb/internal/helpers"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthorizeFunc checks that the system user with the specified uid can get a certificate for the request,
+// and returns the key ID and principals of the certificate to sign, and whether it permits port forwarding
+type AuthorizeFunc func(uid uint32, request *SignRequest) (keyID string, principals []string, portForwarding bool, err error)
+
+// Server signs certificates for the ttyrec sessions connecting on its unix socket.
+// The callers are identified by the kernel (SO_PEERCRED), so the socket can be world-writable
+// while the CA private key stays readable by root only.
+type Server struct {
+	listener        *net.UnixListener
+	signer          ssh.Signer
+	validity        time.Duration
+	sourceAddresses []string
+	agentForwarding bool
+	authorize       AuthorizeFunc
+}
+
+// NewServer loads the CA private key and starts listening on the unix socket. The certificates permit agent
+// forwarding when agentForwarding is true, as the sessions then forward the agent of the accounts.
+func NewServer(socketPath, privateKeyPath string, validity time.Duration, sourceAddresses []string, agentForwarding bool, authorize AuthorizeFunc) (s *Server, err error) {
+
+	signer, err := LoadSigner(privateKeyPath)
+	if err != nil {
+		return
+	}
+
+	// A previous instance might have left its socket behind
+	err = os.Remove(socketPath)
+	if err != nil && !os.IsNotExist(err) {
+		err = errors.Wrapf(err, "unable to remove stale socket %s", socketPath)
+		return
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		err = errors.Wrapf(err, "unable to listen on %s", socketPath)
+		return
+	}
+
+	err = os.Chmod(socketPath, 0666)
+	if err != nil {
+		listener.Close()
+		err = errors.Wrapf(err, "unable to chmod socket %s", socketPath)
+		return
+	}
+
+	s = &Server{
+		listener:        listener,
+		signer:          signer,
+		validity:        validity,
+		sourceAddresses: sourceAddresses,
+		agentForwarding: agentForwarding,
+		authorize:       authorize,
+	}
+
+	return
+}
+
+// PublicKey returns the CA public key, in the authorized_keys format
+func (s *Server) PublicKey() string {
+	return string(ssh.MarshalAuthorizedKey(s.signer.PublicKey()))
+}
+
+// Serve handles the incoming connections until the listener is closed
+func (s *Server) Serve() (err error) {
+
+	for {
+		conn, err := s.listener.AcceptUnix()
+		if err != nil {
+			return errors.Wrap(err, "unable to accept connection")
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// Close stops listening
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) handle(conn *net.UnixConn) {
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var response SignResponse
+
+	cert, err := s.sign(conn)
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Certificate = string(ssh.MarshalAuthorizedKey(cert))
+	}
+
+	json.NewEncoder(conn).Encode(&response)
+}
+
+func (s *Server) sign(conn *net.UnixConn) (cert *ssh.Certificate, err error) {
+
+	uid, err := helpers.GetPeerUID(conn)
+	if err != nil {
+		return
+	}
+
+	var request SignRequest
+	err = json.NewDecoder(conn).Decode(&request)
+	if err != nil {
+		err = fmt.Errorf("invalid request")
+		return
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(request.PublicKey))
+	if err != nil {
+		err = fmt.Errorf("invalid public key")
+		return
+	}
+	if _, ok := publicKey.(*ssh.Certificate); ok {
+		err = fmt.Errorf("invalid public key")
+		return
+	}
+
+	keyID, principals, portForwarding, err := s.authorize(uid, &request)
+	if err != nil {
+		return
+	}
+
+	return SignUserCertificate(s.signer, publicKey, keyID, principals, portForwarding, s.agentForwarding, s.sourceAddresses, s.validity, time.Now())
+}
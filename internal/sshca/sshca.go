@@ -0,0 +1,125 @@
+package sshca
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// clockSkew is subtracted from the certificates' start of validity to cope with clocks drifting between sb and the distant hosts
+const clockSkew = time.Minute
+
+// SignRequest describes a certificate signing request sent to the CA by a ttyrec session
+type SignRequest struct {
+	PublicKey string `json:"public_key"`
+	Access    string `json:"access"`
+	SessionID string `json:"session_id"`
+}
+
+// SignResponse describes the answer of the CA to a SignRequest
+type SignResponse struct {
+	Certificate string `json:"certificate,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// LoadSigner loads the CA private key from an OpenSSH (or PEM) unencrypted private key file
+func LoadSigner(privateKeyPath string) (signer ssh.Signer, err error) {
+
+	pk, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to read CA private key %s", privateKeyPath)
+		return
+	}
+
+	signer, err = ssh.ParsePrivateKey(pk)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to parse CA private key %s", privateKeyPath)
+		return
+	}
+
+	return
+}
+
+// Principals returns the principals of a certificate granting an access with the specified user to the specified host,
+// through the specified groups: USER, and one USER@GROUP principal per group.
+// With hostBound, the host is part of every principal (USER@HOST and USER@HOST@GROUP), so that the certificate is
+// useless on the other hosts trusting the CA.
+func Principals(accessUser, host string, groups []string, hostBound bool) (principals []string) {
+
+	principal := accessUser
+	if hostBound {
+		principal = fmt.Sprintf("%s@%s", accessUser, host)
+	}
+	principals = []string{principal}
+
+	seen := map[string]bool{principal: true}
+	for _, group := range groups {
+		principal := fmt.Sprintf("%s@%s", principals[0], group)
+		if seen[principal] {
+			continue
+		}
+		seen[principal] = true
+		principals = append(principals, principal)
+	}
+
+	return
+}
+
+// SignUserCertificate signs a user certificate for the public key, valid from now (minus a small clock skew) and for the specified duration.
+// Port forwarding is only permitted when portForwarding is true, and agent forwarding when agentForwarding is.
+func SignUserCertificate(signer ssh.Signer, publicKey ssh.PublicKey, keyID string, principals []string, portForwarding, agentForwarding bool, sourceAddresses []string, validity time.Duration, now time.Time) (cert *ssh.Certificate, err error) {
+
+	if len(principals) == 0 {
+		err = fmt.Errorf("a certificate requires at least one principal")
+		return
+	}
+
+	serial := make([]byte, 8)
+	_, err = rand.Read(serial)
+	if err != nil {
+		err = errors.Wrap(err, "unable to generate certificate serial")
+		return
+	}
+
+	cert = &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          binary.BigEndian.Uint64(serial),
+		CertType:        ssh.UserCert,
+		KeyId:           keyID,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(now.Add(-clockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(validity).Unix()),
+		Permissions: ssh.Permissions{
+			CriticalOptions: map[string]string{},
+			Extensions: map[string]string{
+				"permit-pty":     "",
+				"permit-user-rc": "",
+			},
+		},
+	}
+	if portForwarding {
+		cert.Permissions.Extensions["permit-port-forwarding"] = ""
+	}
+	if agentForwarding {
+		cert.Permissions.Extensions["permit-agent-forwarding"] = ""
+	}
+
+	// Restricting the source addresses ensures the certificate is useless outside of sb
+	if len(sourceAddresses) > 0 {
+		cert.Permissions.CriticalOptions["source-address"] = strings.Join(sourceAddresses, ",")
+	}
+
+	err = cert.SignCert(rand.Reader, signer)
+	if err != nil {
+		err = errors.Wrap(err, "unable to sign certificate")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,182 @@
+package sshca
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func generateKey(t *testing.T) (ssh.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("unable to convert key: %s", err)
+	}
+	return sshPub, priv
+}
+
+func writeCAKey(t *testing.T, priv ed25519.PrivateKey) string {
+	block, err := ssh.MarshalPrivateKey(priv, "sb-ca")
+	if err != nil {
+		t.Fatalf("unable to marshal CA key: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "egress_ca")
+	err = os.WriteFile(path, pem.EncodeToMemory(block), 0600)
+	if err != nil {
+		t.Fatalf("unable to write CA key: %s", err)
+	}
+	return path
+}
+
+func TestPrincipals(t *testing.T) {
+
+	principals := Principals("root", "db1.example.com", []string{"developers", "ops", "developers"}, false)
+	expected := []string{"root", "root@developers", "root@ops"}
+	if !reflect.DeepEqual(principals, expected) {
+		t.Errorf("Principals() = %v, expected %v", principals, expected)
+	}
+
+	principals = Principals("root", "db1.example.com", []string{"developers", "ops", "developers"}, true)
+	expected = []string{"root@db1.example.com", "root@db1.example.com@developers", "root@db1.example.com@ops"}
+	if !reflect.DeepEqual(principals, expected) {
+		t.Errorf("Principals() = %v, expected %v", principals, expected)
+	}
+
+	principals = Principals("admin", "10.0.0.1", nil, true)
+	if !reflect.DeepEqual(principals, []string{"admin@10.0.0.1"}) {
+		t.Errorf("Principals() = %v, expected [admin@10.0.0.1]", principals)
+	}
+}
+
+func TestSignUserCertificate(t *testing.T) {
+
+	caPub, caPriv := generateKey(t)
+	signer, err := ssh.NewSignerFromKey(caPriv)
+	if err != nil {
+		t.Fatalf("unable to create signer: %s", err)
+	}
+	userPub, _ := generateKey(t)
+
+	now := time.Now()
+	cert, err := SignUserCertificate(signer, userPub, "sb:alice:session", []string{"root@db1", "root@db1@developers"}, false, false, []string{"10.0.0.1/32"}, 5*time.Minute, now)
+	if err != nil {
+		t.Fatalf("SignUserCertificate() error: %s", err)
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(caPub.Marshal())
+		},
+		Clock: func() time.Time { return now },
+	}
+
+	err = checker.CheckCert("root@db1@developers", cert)
+	if err != nil {
+		t.Errorf("certificate should be valid for principal root@db1@developers: %s", err)
+	}
+	for _, principal := range []string{"root", "root@db2", "admin@db1"} {
+		err = checker.CheckCert(principal, cert)
+		if err == nil {
+			t.Errorf("certificate shouldn't be valid for principal %s", principal)
+		}
+	}
+
+	checker.Clock = func() time.Time { return now.Add(6 * time.Minute) }
+	err = checker.CheckCert("root@db1", cert)
+	if err == nil {
+		t.Errorf("certificate shouldn't be valid once expired")
+	}
+
+	if cert.CriticalOptions["source-address"] != "10.0.0.1/32" {
+		t.Errorf("unexpected source-address critical option: %q", cert.CriticalOptions["source-address"])
+	}
+
+	for _, extension := range []string{"permit-port-forwarding", "permit-agent-forwarding"} {
+		if _, ok := cert.Extensions[extension]; ok {
+			t.Errorf("certificate shouldn't have the %s extension", extension)
+		}
+	}
+
+	cert, err = SignUserCertificate(signer, userPub, "sb:alice:session", []string{"root@db1"}, true, false, nil, 5*time.Minute, now)
+	if err != nil {
+		t.Fatalf("SignUserCertificate() error: %s", err)
+	}
+	if _, ok := cert.Extensions["permit-port-forwarding"]; !ok {
+		t.Errorf("certificate should permit port forwarding")
+	}
+	if _, ok := cert.Extensions["permit-agent-forwarding"]; ok {
+		t.Errorf("certificate shouldn't permit agent forwarding")
+	}
+
+	cert, err = SignUserCertificate(signer, userPub, "sb:alice:session", []string{"root@db1"}, false, true, nil, 5*time.Minute, now)
+	if err != nil {
+		t.Fatalf("SignUserCertificate() error: %s", err)
+	}
+	if _, ok := cert.Extensions["permit-agent-forwarding"]; !ok {
+		t.Errorf("certificate should permit agent forwarding")
+	}
+
+	_, err = SignUserCertificate(signer, userPub, "sb:alice:session", nil, false, false, nil, 5*time.Minute, now)
+	if err == nil {
+		t.Errorf("SignUserCertificate() should fail without principals")
+	}
+}
+
+func TestServer(t *testing.T) {
+
+	_, caPriv := generateKey(t)
+	caKeyPath := writeCAKey(t, caPriv)
+	socketPath := filepath.Join(t.TempDir(), "ca.sock")
+
+	server, err := NewServer(socketPath, caKeyPath, time.Minute, nil, false, func(uid uint32, request *SignRequest) (string, []string, bool, error) {
+		if uid != uint32(os.Getuid()) {
+			return "", nil, false, fmt.Errorf("unexpected uid %d", uid)
+		}
+		if request.Access != "root@10.0.0.1:22" {
+			return "", nil, false, fmt.Errorf("access denied")
+		}
+		return "sb:test:" + request.SessionID, []string{"root@10.0.0.1"}, false, nil
+	})
+	if err != nil {
+		t.Fatalf("NewServer() error: %s", err)
+	}
+	defer server.Close()
+	go server.Serve()
+
+	userPub, _ := generateKey(t)
+
+	cert, err := RequestCertificate(socketPath, &SignRequest{
+		PublicKey: string(ssh.MarshalAuthorizedKey(userPub)),
+		Access:    "root@10.0.0.1:22",
+		SessionID: "session",
+	})
+	if err != nil {
+		t.Fatalf("RequestCertificate() error: %s", err)
+	}
+	if cert.KeyId != "sb:test:session" {
+		t.Errorf("unexpected key ID: %s", cert.KeyId)
+	}
+	if string(cert.Key.Marshal()) != string(userPub.Marshal()) {
+		t.Errorf("certificate doesn't certify the requested key")
+	}
+
+	_, err = RequestCertificate(socketPath, &SignRequest{
+		PublicKey: string(ssh.MarshalAuthorizedKey(userPub)),
+		Access:    "root@10.0.0.2:22",
+		SessionID: "session",
+	})
+	if err == nil {
+		t.Errorf("RequestCertificate() should fail for an unauthorized access")
+	}
+}
@@ -0,0 +1,373 @@
+package sshclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/inpher/sb/internal/helpers"
+	pkgerrors "github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+// ErrUnknownHostKey is returned when the distant host is not in the known_hosts file and the user didn't trust it
+var ErrUnknownHostKey = errors.New("the host key of the distant host is unknown")
+
+// HostKeyPrompt asks whether an unknown host key should be trusted
+type HostKeyPrompt func(hostname string, key ssh.PublicKey) bool
+
+// Config describes how to connect to a distant host
+type Config struct {
+	Host           string
+	Port           int
+	User           string
+	Signers        []ssh.Signer
+	KnownHostsFile string
+	HostKeyPrompt  HostKeyPrompt // If nil, unknown host keys are refused
+	ForwardAgent   bool          // Forward the agent of the account (SSH_AUTH_SOCK) to the distant host
+	Env            map[string]string
+	Timeout        time.Duration
+}
+
+// Client is an SSH connection to a distant host
+type Client struct {
+	*ssh.Client
+	config *Config
+}
+
+// LoadSigners loads the unencrypted private keys from the files. The keys that can't be loaded are returned as errors, the other ones being still usable.
+func LoadSigners(keyFiles []string) (signers []ssh.Signer, errs []error) {
+
+	for _, keyFile := range keyFiles {
+
+		pk, err := os.ReadFile(keyFile)
+		if err != nil {
+			errs = append(errs, pkgerrors.Wrapf(err, "unable to read private key %s", keyFile))
+			continue
+		}
+
+		signer, err := ssh.ParsePrivateKey(pk)
+		if err != nil {
+			errs = append(errs, pkgerrors.Wrapf(err, "unable to parse private key %s", keyFile))
+			continue
+		}
+
+		signers = append(signers, signer)
+	}
+
+	return
+}
+
+// Dial connects and authenticates to the distant host
+func Dial(config *Config) (c *Client, err error) {
+
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+
+	hostKeyCallback, hostKeyAlgorithms, err := newHostKeyCallback(config.KnownHostsFile, address, config.HostKeyPrompt)
+	if err != nil {
+		return
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	client, err := ssh.Dial("tcp", address, &ssh.ClientConfig{
+		User:              config.User,
+		Auth:              []ssh.AuthMethod{ssh.PublicKeys(config.Signers...)},
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: hostKeyAlgorithms,
+		Timeout:           timeout,
+	})
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "unable to connect to %s@%s", config.User, address)
+		return
+	}
+
+	c = &Client{
+		Client: client,
+		config: config,
+	}
+
+	if config.ForwardAgent && os.Getenv("SSH_AUTH_SOCK") != "" {
+		err = agent.ForwardToRemote(client, os.Getenv("SSH_AUTH_SOCK"))
+		if err != nil {
+			client.Close()
+			err = pkgerrors.Wrap(err, "unable to forward the agent")
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// RunInteractive runs the command (or a shell if the command is empty) on the distant host.
+// If stdin is a terminal, a PTY is requested, the local terminal is switched to raw mode and its
+// resizes are propagated. The output of the session is written to stdout and stderr (which are the
+// same stream with a PTY). If keystrokes is not nil, what is read from stdin is copied to it.
+func (c *Client) RunInteractive(command string, stdin *os.File, keystrokes, stdout, stderr io.Writer) (err error) {
+
+	session, err := c.newSession()
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	// The session copies stdin to the distant host until it's canceled, once the session is over:
+	// a copy outliving the session would swallow the next input of the process
+	input, err := helpers.NewCancelableReader(stdin)
+	if err != nil {
+		return pkgerrors.Wrap(err, "unable to read the input")
+	}
+	defer input.Cancel()
+
+	session.Stdin = input
+	if keystrokes != nil {
+		session.Stdin = io.TeeReader(input, keystrokes)
+	}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	fd := int(stdin.Fd())
+	if term.IsTerminal(fd) {
+
+		width, height, errSize := term.GetSize(fd)
+		if errSize != nil {
+			width, height = 80, 24
+		}
+
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+
+		err = session.RequestPty(termType, height, width, ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		})
+		if err != nil {
+			return pkgerrors.Wrap(err, "unable to request a PTY")
+		}
+
+		state, errRaw := term.MakeRaw(fd)
+		if errRaw != nil {
+			return pkgerrors.Wrap(errRaw, "unable to set the terminal in raw mode")
+		}
+		defer term.Restore(fd, state)
+
+		// We propagate the window resizes to the distant host
+		resized := make(chan os.Signal, 1)
+		signal.Notify(resized, syscall.SIGWINCH)
+		defer signal.Stop(resized)
+		go func() {
+			for range resized {
+				width, height, errSize := term.GetSize(fd)
+				if errSize == nil {
+					session.WindowChange(height, width)
+				}
+			}
+		}()
+	}
+
+	if command == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(command)
+	}
+	if err != nil {
+		return pkgerrors.Wrap(err, "unable to start the session")
+	}
+
+	return session.Wait()
+}
+
+// Run runs the command on the distant host, without PTY nor agent forwarding
+func (c *Client) Run(command string, stdin io.Reader, stdout, stderr io.Writer) (err error) {
+
+	session, err := c.Client.NewSession()
+	if err != nil {
+		return pkgerrors.Wrap(err, "unable to open a session")
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	return session.Run(command)
+}
+
+// Forward accepts the connections of the listener and forwards them to the remote address, as seen from the distant host
+func (c *Client) Forward(listener net.Listener, remote string) error {
+
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func(local net.Conn) {
+			defer local.Close()
+
+			distant, err := c.Dial("tcp", remote)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to reach %s: %s\n", remote, err)
+				return
+			}
+			defer distant.Close()
+
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				io.Copy(distant, local)
+				distant.Close()
+				wg.Done()
+			}()
+			go func() {
+				io.Copy(local, distant)
+				local.Close()
+				wg.Done()
+			}()
+			wg.Wait()
+		}(local)
+	}
+}
+
+func (c *Client) newSession() (session *ssh.Session, err error) {
+
+	session, err = c.Client.NewSession()
+	if err != nil {
+		err = pkgerrors.Wrap(err, "unable to open a session")
+		return
+	}
+
+	// The distant host might refuse agent forwarding, or some variables (AcceptEnv): like ssh, we carry on
+	if c.config.ForwardAgent && os.Getenv("SSH_AUTH_SOCK") != "" {
+		agent.RequestAgentForwarding(session)
+	}
+	for name, value := range c.config.Env {
+		session.Setenv(name, value)
+	}
+
+	return
+}
+
+// ExitStatus returns the exit status of a finished session (0 if err is nil, -1 if the session didn't exit properly)
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus()
+	}
+	return -1
+}
+
+// newHostKeyCallback checks the host keys against the known_hosts file, and asks the prompt for the unknown ones.
+// It also returns the algorithms of the known keys of the host, so that the server presents one of them.
+func newHostKeyCallback(knownHostsFile, address string, prompt HostKeyPrompt) (callback ssh.HostKeyCallback, algorithms []string, err error) {
+
+	// knownhosts fails on a missing file: we start with an empty one, like ssh does
+	f, err := os.OpenFile(knownHostsFile, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "unable to open %s", knownHostsFile)
+		return
+	}
+	f.Close()
+
+	known, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "unable to parse %s", knownHostsFile)
+		return
+	}
+
+	algorithms = knownAlgorithms(known, address)
+
+	callback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+
+		err := known(hostname, remote, key)
+
+		var keyErr *knownhosts.KeyError
+		if err == nil || !errors.As(err, &keyErr) {
+			return err
+		}
+
+		// The host is known, with a different key
+		if len(keyErr.Want) > 0 {
+			return fmt.Errorf("the host key of %s changed (%s): someone could be eavesdropping, or the key was changed (see the self hostkey forget command)", hostname, ssh.FingerprintSHA256(key))
+		}
+
+		if prompt == nil || !prompt(hostname, key) {
+			return ErrUnknownHostKey
+		}
+
+		return appendKnownHost(knownHostsFile, hostname, remote, key)
+	}
+
+	return
+}
+
+// knownAlgorithms returns the algorithms of the keys known for the address
+func knownAlgorithms(known ssh.HostKeyCallback, address string) (algorithms []string) {
+
+	// A key that can't be known makes the callback list the known ones
+	placeholder, err := ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	if err != nil {
+		return
+	}
+
+	err = known(address, &net.TCPAddr{IP: net.IPv4zero}, placeholder)
+
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return
+	}
+
+	for _, want := range keyErr.Want {
+		switch want.Key.Type() {
+		case ssh.KeyAlgoRSA:
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algorithms = append(algorithms, want.Key.Type())
+		}
+	}
+
+	return
+}
+
+func appendKnownHost(knownHostsFile, hostname string, remote net.Addr, key ssh.PublicKey) (err error) {
+
+	f, err := os.OpenFile(knownHostsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return pkgerrors.Wrapf(err, "unable to open %s", knownHostsFile)
+	}
+	defer f.Close()
+
+	addresses := []string{knownhosts.Normalize(hostname)}
+	if remoteAddress := knownhosts.Normalize(remote.String()); remoteAddress != addresses[0] {
+		addresses = append(addresses, remoteAddress)
+	}
+
+	line := bytes.TrimSpace([]byte(knownhosts.Line(addresses, key)))
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return pkgerrors.Wrapf(err, "unable to write %s", knownHostsFile)
+	}
+
+	return
+}
@@ -0,0 +1,357 @@
+package sshclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is a minimal SSH server: "exit N" commands exit with status N, the other ones are echoed back,
+// and direct-tcpip channels are connected to the requested address
+type testServer struct {
+	listener net.Listener
+	hostKey  ssh.Signer
+	port     int
+}
+
+func newTestServer(t *testing.T, authorizedKey ssh.PublicKey) *testServer {
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate host key: %s", err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("unable to create host key signer: %s", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unauthorized key")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testServer{
+		listener: listener,
+		hostKey:  hostKey,
+		port:     listener.Addr().(*net.TCPAddr).Port,
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn, config)
+		}
+	}()
+
+	return s
+}
+
+func (s *testServer) handle(conn net.Conn, config *ssh.ServerConfig) {
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			go s.handleSession(channel, requests)
+		case "direct-tcpip":
+			var payload struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}
+			ssh.Unmarshal(newChannel.ExtraData(), &payload)
+			target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+			if err != nil {
+				newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				target.Close()
+				continue
+			}
+			go ssh.DiscardRequests(requests)
+			go func() {
+				io.Copy(target, channel)
+				target.Close()
+			}()
+			go func() {
+				io.Copy(channel, target)
+				channel.Close()
+			}()
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+		}
+	}
+}
+
+func (s *testServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+
+	defer channel.Close()
+
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+
+		var payload struct{ Command string }
+		ssh.Unmarshal(req.Payload, &payload)
+
+		status := 0
+		if strings.HasPrefix(payload.Command, "exit ") {
+			status, _ = strconv.Atoi(strings.TrimPrefix(payload.Command, "exit "))
+		} else {
+			io.WriteString(channel, payload.Command)
+		}
+
+		exitStatus := make([]byte, 4)
+		binary.BigEndian.PutUint32(exitStatus, uint32(status))
+		channel.SendRequest("exit-status", false, exitStatus)
+		return
+	}
+}
+
+func newClientKey(t *testing.T) (ssh.Signer, string) {
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate client key: %s", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("unable to create client signer: %s", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(priv, "test")
+	if err != nil {
+		t.Fatalf("unable to marshal client key: %s", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600)
+	if err != nil {
+		t.Fatalf("unable to write client key: %s", err)
+	}
+
+	return signer, keyFile
+}
+
+func TestLoadSigners(t *testing.T) {
+
+	signer, keyFile := newClientKey(t)
+
+	signers, errs := LoadSigners([]string{keyFile, filepath.Join(t.TempDir(), "missing")})
+	if len(signers) != 1 || len(errs) != 1 {
+		t.Fatalf("LoadSigners() returned %d signers and %d errors, expected 1 and 1", len(signers), len(errs))
+	}
+	if !bytes.Equal(signers[0].PublicKey().Marshal(), signer.PublicKey().Marshal()) {
+		t.Errorf("LoadSigners() loaded the wrong key")
+	}
+}
+
+func TestKnownHosts(t *testing.T) {
+
+	signer, _ := newClientKey(t)
+	server := newTestServer(t, signer.PublicKey())
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+
+	config := &Config{
+		Host:           "127.0.0.1",
+		Port:           server.port,
+		User:           "root",
+		Signers:        []ssh.Signer{signer},
+		KnownHostsFile: knownHostsFile,
+	}
+
+	// Without prompt, unknown hosts are refused
+	_, err := Dial(config)
+	if err == nil || !strings.Contains(err.Error(), ErrUnknownHostKey.Error()) {
+		t.Fatalf("Dial() should refuse an unknown host, got: %v", err)
+	}
+
+	// Trusting the host saves its key
+	prompted := 0
+	config.HostKeyPrompt = func(hostname string, key ssh.PublicKey) bool {
+		prompted++
+		return true
+	}
+	client, err := Dial(config)
+	if err != nil {
+		t.Fatalf("Dial() error: %s", err)
+	}
+	client.Close()
+
+	// The host is now known: no more prompt
+	client, err = Dial(config)
+	if err != nil {
+		t.Fatalf("Dial() error: %s", err)
+	}
+	client.Close()
+	if prompted != 1 {
+		t.Errorf("the prompt was called %d times, expected 1", prompted)
+	}
+
+	// Another server on the same address is refused
+	other := newTestServer(t, signer.PublicKey())
+	content, _ := os.ReadFile(knownHostsFile)
+	content = bytes.ReplaceAll(content, []byte(strconv.Itoa(server.port)), []byte(strconv.Itoa(other.port)))
+	os.WriteFile(knownHostsFile, content, 0600)
+	config.Port = other.port
+	_, err = Dial(config)
+	if err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Fatalf("Dial() should refuse a changed host key, got: %v", err)
+	}
+}
+
+func TestRunAndForward(t *testing.T) {
+
+	signer, _ := newClientKey(t)
+	server := newTestServer(t, signer.PublicKey())
+
+	client, err := Dial(&Config{
+		Host:           "127.0.0.1",
+		Port:           server.port,
+		User:           "root",
+		Signers:        []ssh.Signer{signer},
+		KnownHostsFile: filepath.Join(t.TempDir(), "known_hosts"),
+		HostKeyPrompt:  func(string, ssh.PublicKey) bool { return true },
+	})
+	if err != nil {
+		t.Fatalf("Dial() error: %s", err)
+	}
+	defer client.Close()
+
+	var stdout bytes.Buffer
+	err = client.Run("hello", nil, &stdout, io.Discard)
+	if err != nil || stdout.String() != "hello" {
+		t.Errorf("Run() = %q, %v; expected \"hello\", nil", stdout.String(), err)
+	}
+
+	err = client.Run("exit 3", nil, io.Discard, io.Discard)
+	if ExitStatus(err) != 3 {
+		t.Errorf("ExitStatus() = %d, expected 3 (%v)", ExitStatus(err), err)
+	}
+	if ExitStatus(nil) != 0 {
+		t.Errorf("ExitStatus(nil) should be 0")
+	}
+
+	// A service reachable from the distant host
+	service, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %s", err)
+	}
+	defer service.Close()
+	go func() {
+		conn, err := service.Accept()
+		if err != nil {
+			return
+		}
+		io.WriteString(conn, "pong")
+		conn.Close()
+	}()
+
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "forward.sock"))
+	if err != nil {
+		t.Fatalf("unable to listen: %s", err)
+	}
+	defer listener.Close()
+	go client.Forward(listener, service.Addr().String())
+
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to connect to the forwarding: %s", err)
+	}
+	defer conn.Close()
+	response, _ := io.ReadAll(conn)
+	if string(response) != "pong" {
+		t.Errorf("forwarded response = %q, expected \"pong\"", response)
+	}
+}
+
+func TestRunInteractiveInput(t *testing.T) {
+
+	signer, _ := newClientKey(t)
+	server := newTestServer(t, signer.PublicKey())
+
+	client, err := Dial(&Config{
+		Host:           "127.0.0.1",
+		Port:           server.port,
+		User:           "root",
+		Signers:        []ssh.Signer{signer},
+		KnownHostsFile: filepath.Join(t.TempDir(), "known_hosts"),
+		HostKeyPrompt:  func(string, ssh.PublicKey) bool { return true },
+	})
+	if err != nil {
+		t.Fatalf("Dial() error: %s", err)
+	}
+	defer client.Close()
+
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("unable to create a pipe: %s", err)
+	}
+	defer stdin.Close()
+	defer input.Close()
+
+	var stdout bytes.Buffer
+	err = client.RunInteractive("hello", stdin, nil, &stdout, io.Discard)
+	if err != nil || stdout.String() != "hello" {
+		t.Errorf("RunInteractive() = %q, %v; expected \"hello\", nil", stdout.String(), err)
+	}
+
+	// Once the session is over, the input is left to the next reader
+	io.WriteString(input, "next")
+	read := make(chan string)
+	go func() {
+		next := make([]byte, 4)
+		io.ReadFull(stdin, next)
+		read <- string(next)
+	}()
+	select {
+	case next := <-read:
+		if next != "next" {
+			t.Errorf("input after the session = %q, expected \"next\"", next)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("the input after the session was swallowed")
+	}
+}
@@ -0,0 +1,123 @@
+package azureblob
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+type StorageAzureBlob struct {
+	container   string
+	prefix      string
+	endpointURL string
+	context     context.Context
+	client      *azblob.Client
+}
+
+func NewStorageAzureBlob(options *viper.Viper) (rs *StorageAzureBlob, err error) {
+
+	if options == nil {
+		err = fmt.Errorf("ttyrecsoffloading.storage.azureblob options can't be empty")
+		return
+	}
+
+	accountName := options.GetString("account-name")
+	accountKey := options.GetString("account-key")
+	container := options.GetString("container")
+	prefix := options.GetString("prefix")
+	endpointURL := options.GetString("endpoint-url")
+
+	if container == "" {
+		err = fmt.Errorf("azureblob.container option can't be empty")
+		return
+	}
+	if accountName == "" && endpointURL == "" {
+		err = fmt.Errorf("azureblob.account-name and azureblob.endpoint-url options can't be both empty")
+		return
+	}
+
+	// The endpoint URL can be overridden, for instance to target an Azurite emulator
+	if endpointURL == "" {
+		endpointURL = fmt.Sprintf("https://%s.blob.core.windows.net/", accountName)
+	}
+
+	rs = &StorageAzureBlob{
+		container:   container,
+		prefix:      prefix,
+		endpointURL: endpointURL,
+		context:     context.Background(),
+	}
+
+	// With no account key, credentials are taken from the environment (environment variables, managed identity, ...)
+	if accountKey != "" {
+		credential, errCredential := azblob.NewSharedKeyCredential(accountName, accountKey)
+		if errCredential != nil {
+			err = errors.Wrap(errCredential, "unable to build Azure shared key credential")
+			return
+		}
+		rs.client, err = azblob.NewClientWithSharedKeyCredential(endpointURL, credential, nil)
+	} else {
+		credential, errCredential := azidentity.NewDefaultAzureCredential(nil)
+		if errCredential != nil {
+			err = errors.Wrap(errCredential, "unable to get Azure credentials from the environment")
+			return
+		}
+		rs.client, err = azblob.NewClient(endpointURL, credential, nil)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "unable to create Azure Blob Storage client")
+		return
+	}
+
+	return
+}
+
+func (r *StorageAzureBlob) GetFromStorage(blob, outputFilePath string) (err error) {
+	if r.client == nil {
+		return fmt.Errorf("storage Azure Blob hasn't been initialized")
+	}
+
+	file, err := os.OpenFile(outputFilePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, err = r.client.DownloadFile(r.context, r.container, path.Join(r.prefix, blob), file, nil)
+	if err != nil {
+		return errors.Wrapf(err, "unable to download blob %s from Azure Blob Storage", path.Join(r.prefix, blob))
+	}
+	err = file.Sync()
+	if err != nil {
+		return errors.Wrap(err, "unable to sync the output file after downloading from Azure Blob Storage")
+	}
+
+	return
+}
+
+func (r *StorageAzureBlob) PushToStorage(blob, inputFilePath string) (err error) {
+	if r.client == nil {
+		return fmt.Errorf("storage Azure Blob hasn't been initialized")
+	}
+
+	// Open the encrypted file
+	file, err := os.Open(inputFilePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	// Upload the file to Azure Blob Storage
+	_, err = r.client.UploadFile(r.context, r.container, path.Join(r.prefix, blob), file, nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to upload file to Azure Blob Storage")
+	}
+
+	return
+}
@@ -0,0 +1,87 @@
+package azureblob
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// The well-known account of the Azurite emulator
+const (
+	azuriteAccountName = "devstoreaccount1"
+	azuriteAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+func TestStorageAzureBlobOptions(t *testing.T) {
+
+	_, err := NewStorageAzureBlob(nil)
+	require.Error(t, err)
+
+	options := viper.New()
+	options.Set("account-name", azuriteAccountName)
+	_, err = NewStorageAzureBlob(options)
+	require.Error(t, err)
+
+	options = viper.New()
+	options.Set("container", "ttyrecs")
+	_, err = NewStorageAzureBlob(options)
+	require.Error(t, err)
+
+	options.Set("account-name", azuriteAccountName)
+	options.Set("account-key", azuriteAccountKey)
+	rs, err := NewStorageAzureBlob(options)
+	require.NoError(t, err)
+	require.Equal(t, "https://devstoreaccount1.blob.core.windows.net/", rs.endpointURL)
+}
+
+// TestStorageAzureBlob runs against the endpoint of SB_TEST_AZUREBLOB_ENDPOINT_URL (for instance an Azurite
+// emulator on http://127.0.0.1:10000/devstoreaccount1), and is skipped when none is set
+func TestStorageAzureBlob(t *testing.T) {
+
+	endpointURL := os.Getenv("SB_TEST_AZUREBLOB_ENDPOINT_URL")
+	if endpointURL == "" {
+		t.Skip("SB_TEST_AZUREBLOB_ENDPOINT_URL is not set")
+	}
+
+	accountName, accountKey := os.Getenv("SB_TEST_AZUREBLOB_ACCOUNT_NAME"), os.Getenv("SB_TEST_AZUREBLOB_ACCOUNT_KEY")
+	if accountName == "" {
+		accountName, accountKey = azuriteAccountName, azuriteAccountKey
+	}
+
+	options := viper.New()
+	options.Set("account-name", accountName)
+	options.Set("account-key", accountKey)
+	options.Set("endpoint-url", endpointURL)
+	options.Set("container", "sb-test-"+uuid.New().String())
+	options.Set("prefix", "ttyrecs")
+
+	rs, err := NewStorageAzureBlob(options)
+	require.NoError(t, err)
+
+	_, err = rs.client.CreateContainer(rs.context, rs.container, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { rs.client.DeleteContainer(rs.context, rs.container, nil) })
+
+	workDirectory := t.TempDir()
+	inputFilePath := filepath.Join(workDirectory, "1234.ttyrec.bin")
+	require.NoError(t, os.WriteFile(inputFilePath, []byte("encrypted ttyrec"), 0600))
+
+	require.NoError(t, rs.PushToStorage("1234.ttyrec.bin", inputFilePath))
+
+	outputFilePath := filepath.Join(workDirectory, "output.bin")
+	require.NoError(t, rs.GetFromStorage("1234.ttyrec.bin", outputFilePath))
+
+	content, err := os.ReadFile(outputFilePath)
+	require.NoError(t, err)
+	require.Equal(t, "encrypted ttyrec", string(content))
+
+	_, err = rs.client.DeleteBlob(rs.context, rs.container, path.Join(rs.prefix, "1234.ttyrec.bin"), nil)
+	require.NoError(t, err)
+
+	require.Error(t, rs.GetFromStorage("1234.ttyrec.bin", outputFilePath))
+}
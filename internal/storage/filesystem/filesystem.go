@@ -0,0 +1,208 @@
+package filesystem
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Placeholders that can be used in the path-layout option
+const (
+	placeholderYear  = "{year}"
+	placeholderMonth = "{month}"
+	placeholderDay   = "{day}"
+	placeholderUser  = "{user}"
+)
+
+var placeholders = []string{placeholderYear, placeholderMonth, placeholderDay, placeholderUser}
+
+type StorageFilesystem struct {
+	directory  string
+	pathLayout string
+}
+
+func NewStorageFilesystem(options *viper.Viper) (rs *StorageFilesystem, err error) {
+
+	if options == nil {
+		err = fmt.Errorf("ttyrecsoffloading.storage.filesystem options can't be empty")
+		return
+	}
+
+	directory := options.GetString("directory")
+	pathLayout := strings.Trim(options.GetString("path-layout"), "/")
+
+	if directory == "" {
+		err = fmt.Errorf("filesystem.directory option can't be empty")
+		return
+	}
+
+	info, err := os.Stat(directory)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to stat directory %s", directory)
+		return
+	}
+	if !info.IsDir() {
+		err = fmt.Errorf("%s is not a directory", directory)
+		return
+	}
+
+	rs = &StorageFilesystem{
+		directory:  directory,
+		pathLayout: pathLayout,
+	}
+
+	return
+}
+
+func (r *StorageFilesystem) GetFromStorage(key, outputFilePath string) (err error) {
+	if r.directory == "" {
+		return fmt.Errorf("storage filesystem hasn't been initialized")
+	}
+
+	path, err := r.findObject(key)
+	if err != nil {
+		return
+	}
+
+	input, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open file %s", path)
+	}
+	defer input.Close()
+
+	output, err := os.OpenFile(outputFilePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	defer output.Close()
+
+	_, err = io.Copy(output, input)
+	if err != nil {
+		return errors.Wrapf(err, "unable to copy file %s from filesystem storage", path)
+	}
+	err = output.Sync()
+	if err != nil {
+		return errors.Wrap(err, "unable to sync the output file after copying from filesystem storage")
+	}
+
+	return
+}
+
+func (r *StorageFilesystem) PushToStorage(key, inputFilePath string) (err error) {
+	if r.directory == "" {
+		return fmt.Errorf("storage filesystem hasn't been initialized")
+	}
+
+	input, err := os.Open(inputFilePath)
+	if err != nil {
+		return
+	}
+	defer input.Close()
+
+	directory := filepath.Join(r.directory, r.expandLayout(inputFilePath))
+	err = os.MkdirAll(directory, 0750)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create directory %s", directory)
+	}
+
+	// We write to a temporary file in the destination directory, and rename it once complete,
+	// so that a partially written object is never visible (rename is atomic on a same filesystem)
+	tmp, err := os.CreateTemp(directory, fmt.Sprintf(".%s.tmp-*", key))
+	if err != nil {
+		return errors.Wrapf(err, "unable to create temporary file in %s", directory)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	_, err = io.Copy(tmp, input)
+	if err != nil {
+		return errors.Wrap(err, "unable to copy file to filesystem storage")
+	}
+	err = tmp.Sync()
+	if err != nil {
+		return errors.Wrap(err, "unable to sync the file copied to filesystem storage")
+	}
+	err = tmp.Close()
+	if err != nil {
+		return
+	}
+
+	err = os.Rename(tmp.Name(), filepath.Join(directory, key))
+	if err != nil {
+		return errors.Wrap(err, "unable to move file to its final location in filesystem storage")
+	}
+
+	return
+}
+
+// expandLayout returns the relative directory where to store a file, built from the path-layout option.
+// The date is the file's last modification date (in UTC), i.e. shortly after the end of the recorded session,
+// and the user is the owner of the directory holding the file, i.e. the account's ttyrecs directory.
+func (r *StorageFilesystem) expandLayout(inputFilePath string) string {
+
+	if r.pathLayout == "" {
+		return ""
+	}
+
+	date := time.Now().UTC()
+	if info, err := os.Stat(inputFilePath); err == nil {
+		date = info.ModTime().UTC()
+	}
+
+	return strings.NewReplacer(
+		placeholderYear, date.Format("2006"),
+		placeholderMonth, date.Format("01"),
+		placeholderDay, date.Format("02"),
+		placeholderUser, getDirectoryOwner(filepath.Dir(inputFilePath)),
+	).Replace(r.pathLayout)
+}
+
+// findObject returns the path of a stored file. As the layout placeholders can't be deduced from the key,
+// they are replaced by wildcards and the file is looked up in the matching directories.
+func (r *StorageFilesystem) findObject(key string) (path string, err error) {
+
+	pattern := r.pathLayout
+	for _, placeholder := range placeholders {
+		pattern = strings.ReplaceAll(pattern, placeholder, "*")
+	}
+
+	matches, err := filepath.Glob(filepath.Join(r.directory, pattern, key))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to look up file in filesystem storage")
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("file %s not found in filesystem storage", key)
+	}
+
+	return matches[0], nil
+}
+
+// getDirectoryOwner returns the name of the owner of a directory, "unknown" if it can't be determined
+func getDirectoryOwner(directory string) string {
+
+	info, err := os.Stat(directory)
+	if err != nil {
+		return "unknown"
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "unknown"
+	}
+
+	u, err := user.LookupId(strconv.FormatUint(uint64(stat.Uid), 10))
+	if err != nil {
+		return "unknown"
+	}
+
+	return u.Username
+}
@@ -0,0 +1,64 @@
+package filesystem
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageFilesystem(t *testing.T) {
+
+	options := viper.New()
+	_, err := NewStorageFilesystem(options)
+	require.Error(t, err)
+
+	options.Set("directory", filepath.Join(t.TempDir(), "not-existing"))
+	_, err = NewStorageFilesystem(options)
+	require.Error(t, err)
+
+	for _, layout := range []string{"", "{year}/{month}/{day}/{user}"} {
+
+		archive := t.TempDir()
+		options.Set("directory", archive)
+		options.Set("path-layout", layout)
+
+		rs, err := NewStorageFilesystem(options)
+		require.NoError(t, err)
+
+		workDirectory := t.TempDir()
+		inputFilePath := filepath.Join(workDirectory, "1234.ttyrec.bin")
+		require.NoError(t, os.WriteFile(inputFilePath, []byte("encrypted ttyrec"), 0600))
+
+		date := time.Date(2023, 4, 5, 23, 30, 0, 0, time.UTC)
+		require.NoError(t, os.Chtimes(inputFilePath, date, date))
+
+		require.NoError(t, rs.PushToStorage("1234.ttyrec.bin", inputFilePath))
+
+		expectedPath := filepath.Join(archive, "1234.ttyrec.bin")
+		if layout != "" {
+			u, err := user.Current()
+			require.NoError(t, err)
+			expectedPath = filepath.Join(archive, "2023", "04", "05", u.Username, "1234.ttyrec.bin")
+		}
+		require.FileExists(t, expectedPath)
+
+		// No temporary file should be left behind
+		entries, err := os.ReadDir(filepath.Dir(expectedPath))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		outputFilePath := filepath.Join(workDirectory, "output.bin")
+		require.NoError(t, rs.GetFromStorage("1234.ttyrec.bin", outputFilePath))
+
+		content, err := os.ReadFile(outputFilePath)
+		require.NoError(t, err)
+		require.Equal(t, "encrypted ttyrec", string(content))
+
+		require.Error(t, rs.GetFromStorage("5678.ttyrec.bin", outputFilePath))
+	}
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"fmt"
 
+	"github.com/inpher/sb/internal/storage/azureblob"
+	"github.com/inpher/sb/internal/storage/filesystem"
 	"github.com/inpher/sb/internal/storage/gcs"
 	"github.com/inpher/sb/internal/storage/s3"
 	"github.com/inpher/sb/internal/types"
@@ -22,6 +24,10 @@ func GetStorage(config *types.TTYRecsOffloadingConfig) (rs Storage, err error) {
 	}
 
 	switch config.StorageType {
+	case "azureblob":
+		rs, err = azureblob.NewStorageAzureBlob(config.StorageOptions)
+	case "filesystem":
+		rs, err = filesystem.NewStorageFilesystem(config.StorageOptions)
 	case "gcs":
 		rs, err = gcs.NewStorageGCS(config.StorageOptions)
 	case "s3":
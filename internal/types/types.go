@@ -1,6 +1,10 @@
 package types
 
-import "github.com/spf13/viper"
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 // StandardError is a wrapper around string to handle the plugin's custom errors
 type StandardError string
@@ -40,7 +44,78 @@ type TTYRecsOffloadingConfig struct {
 }
 
 type ReplicationQueueConfig struct {
-	Enabled      bool
-	QueueType    string
-	QueueOptions *viper.Viper
+	Enabled          bool
+	QueueType        string
+	QueueOptions     *viper.Viper
+	VerifyInterval   time.Duration // How often the daemon sends the digest of its state to the other instances (0 to disable)
+	MaxAttempts      int           // The number of attempts to handle an entry before putting it in the dead letters
+	AppliedRetention time.Duration // How long the IDs of the applied entries are kept, to never apply them twice
+}
+
+type MetricsConfig struct {
+	Enabled bool
+	Listen  string
+}
+
+type EgressCAConfig struct {
+	Enabled             bool
+	PrivateKey          string
+	Socket              string
+	Validity            time.Duration
+	SourceAddresses     []string
+	HostBoundPrincipals bool
+}
+
+type AuditSinkConfig struct {
+	SinkType    string
+	SinkOptions *viper.Viper
+}
+
+// AuditEvent describes the creation or the update of a log entry, as emitted to the audit sinks
+type AuditEvent struct {
+	Action    string     `json:"action"`   // create or update
+	Date      time.Time  `json:"date"`     // When the event was emitted
+	Instance  string     `json:"instance"` // The instance that handled the action
+	ID        string     `json:"id"`       // The log entry ID (the session ID, for the SSH sessions)
+	Account   string     `json:"account"`
+	Command   string     `json:"command,omitempty"`
+	Arguments string     `json:"arguments,omitempty"`
+	Allowed   bool       `json:"allowed"`
+	Comment   string     `json:"comment,omitempty"`
+	FromHost  string     `json:"from_host,omitempty"`
+	FromPort  string     `json:"from_port,omitempty"`
+	ToUser    string     `json:"to_user,omitempty"`
+	ToHost    string     `json:"to_host,omitempty"`
+	ToPort    string     `json:"to_port,omitempty"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+}
+
+type NotificationsConfig struct {
+	Enabled     bool
+	MaxAttempts int
+	Webhooks    map[string]*WebhookConfig
+}
+
+type WebhookConfig struct {
+	Name   string   `mapstructure:"name"`
+	URL    string   `mapstructure:"url"`
+	Secret string   `mapstructure:"secret"`
+	Format string   `mapstructure:"format"`
+	Events []string `mapstructure:"events"`
+	Hosts  string   `mapstructure:"hosts"` // A selector of the inventory labels of the distant hosts to be notified of
+}
+
+// NotificationEvent describes a security-relevant event, as sent to the webhooks
+type NotificationEvent struct {
+	Event     string            `json:"event"`    // The command name, "denied" or "session start"
+	Date      time.Time         `json:"date"`     // When the event happened
+	Instance  string            `json:"instance"` // The instance that handled the action
+	ID        string            `json:"id"`       // The log entry ID (the session ID, for the SSH sessions)
+	Account   string            `json:"account"`  // The account that triggered the event
+	FromHost  string            `json:"from_host,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+	Target    string            `json:"target,omitempty"` // The distant host, as user@host:port
+	Text      string            `json:"text"`             // A human readable description of the event
 }